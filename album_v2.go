@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The v2 album API sends model.AlbumV2 as JSON only, clients needing XML, YAML, MessagePack or Protobuf stay on v1.
+
+// negotiateJsonOnly writes a 406 when the client does not accept JSON.
+func negotiateJsonOnly(c *gin.Context, span trace.Span) bool {
+	if c.NegotiateFormat(binding.MIMEJSON) == "" {
+		buildErrorResponse(c, span, problemNotAcceptable, fmt.Sprintf("Accept [%s] not supported, use %s", c.GetHeader("Accept"), binding.MIMEJSON))
+		return true
+	}
+	span.SetAttributes(attribute.Key("album-store.response.format").String(jsonAlbumFormat.name))
+	return false
+}
+
+// GetAlbumsV2 godoc
+// @Summary Get all Albums
+// @Schemes
+// @Description get all the albums in the store, prices in cents with their currency
+// @Tags albums
+// @Produce json
+// @Success 200 {array} model.AlbumV2
+// @Failure 406 {object} model.ServerError
+// @Router /v2/albums [get]
+func getAlbumsV2(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	if negotiateJsonOnly(c, span) {
+		return
+	}
+	albums := findAlbums(c.Request.Context())
+	albumsV2 := make([]model.AlbumV2, len(albums))
+	for index, album := range albums {
+		albumsV2[index] = album.V2()
+	}
+	buildJsonResponse(c, span, http.StatusOK, albumsV2)
+}
+
+// GetAlbumByIdV2 godoc
+// @Summary Get Album by id
+// @Schemes
+// @Description get as single album by id, price in cents with its currency
+// @Tags albums
+// @Param  id path int true  "album id" minimum(1)
+// @Produce json
+// @Success 200 {object} model.AlbumV2
+// @Failure 400 {object} model.ServerError
+// @Failure 404 {object} model.ServerError
+// @Failure 406 {object} model.ServerError
+// @Router /v2/albums/{id} [get]
+func getAlbumByIDV2(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	if negotiateJsonOnly(c, span) {
+		return
+	}
+	albumID, failed := parseIDParam(c, span, "id", "Album")
+	if failed {
+		return
+	}
+	album, found := findAlbumByID(c.Request.Context(), albumID)
+	if !found {
+		catalog.albumNotFound(c.Request.Context())
+		buildErrorResponse(c, span, problemAlbumNotFound, fmt.Sprintf("Album [%v] not found", albumID))
+		return
+	}
+	buildJsonResponse(c, span, http.StatusOK, album.V2())
+}
+
+// PostAlbumV2 godoc
+// @Summary Create album
+// @Schemes
+// @Description add a new album to the store, price in cents with its currency
+// @Tags albums
+// @Param request body model.AlbumV2 true "album"
+// @Param Idempotency-Key header string false "replay the first response for retries of the same request"
+// @Accept json
+// @Produce json
+// @Success 201 {object} model.AlbumV2
+// @Failure 400 {object} model.ServerError
+// @Failure 406 {object} model.ServerError
+// @Failure 409 {object} model.ServerError
+// @Failure 413 {object} model.ServerError
+// @Failure 415 {object} model.ServerError
+// @Failure 422 {object} model.ServerError
+// @Router /v2/albums [post]
+func postAlbumV2(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	if negotiateJsonOnly(c, span) {
+		return
+	}
+	var albumV2 model.AlbumV2
+	if bindRequestJson(c, span, &albumV2, "Album") {
+		return
+	}
+	album := albumV2.V1()
+	addAlbum(c.Request.Context(), album)
+	buildJsonResponse(c, span, http.StatusCreated, album.V2())
+}
@@ -23,6 +23,899 @@ const docTemplate = `{
         "/albums": {
             "get": {
                 "description": "get all the albums in the store",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/x-yaml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "albums"
+                ],
+                "summary": "Get all Albums",
+                "deprecated": true,
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Album"
+                            }
+                        },
+                        "headers": {
+                            "Deprecation": {
+                                "type": "string",
+                                "description": "date v1 was deprecated, @ followed by Unix seconds"
+                            },
+                            "Link": {
+                                "type": "string",
+                                "description": "the resource in v2, rel=successor-version"
+                            },
+                            "Sunset": {
+                                "type": "string",
+                                "description": "date v1 is removed"
+                            }
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "add a new album to the store",
+                "consumes": [
+                    "application/json",
+                    "application/xml",
+                    "application/x-yaml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/x-yaml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "albums"
+                ],
+                "summary": "Create album",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "description": "album",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Album"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "replay the first response for retries of the same request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Album"
+                        },
+                        "headers": {
+                            "Deprecation": {
+                                "type": "string",
+                                "description": "date v1 was deprecated, @ followed by Unix seconds"
+                            },
+                            "Link": {
+                                "type": "string",
+                                "description": "the resource in v2, rel=successor-version"
+                            },
+                            "Sunset": {
+                                "type": "string",
+                                "description": "date v1 is removed"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/albums/{id}": {
+            "get": {
+                "description": "get as single album by id",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/x-yaml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "albums"
+                ],
+                "summary": "Get Album by id",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "album id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Album"
+                        },
+                        "headers": {
+                            "Deprecation": {
+                                "type": "string",
+                                "description": "date v1 was deprecated, @ followed by Unix seconds"
+                            },
+                            "Link": {
+                                "type": "string",
+                                "description": "the resource in v2, rel=successor-version"
+                            },
+                            "Sunset": {
+                                "type": "string",
+                                "description": "date v1 is removed"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/carts": {
+            "post": {
+                "description": "create a new empty shopping cart",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "carts"
+                ],
+                "summary": "Create cart",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Cart"
+                        }
+                    }
+                }
+            }
+        },
+        "/carts/{id}": {
+            "get": {
+                "description": "get a shopping cart with its lines and computed total",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "carts"
+                ],
+                "summary": "Get cart by id",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "cart id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Cart"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/carts/{id}/lines": {
+            "post": {
+                "description": "add an album to a cart, increasing the quantity if the album is already in the cart",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "carts"
+                ],
+                "summary": "Add album to cart",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "cart id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "cart line",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.CartLineRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Cart"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/carts/{id}/lines/{albumId}": {
+            "delete": {
+                "description": "remove an album line from a cart",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "carts"
+                ],
+                "summary": "Remove album from cart",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "cart id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "album id",
+                        "name": "albumId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Cart"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/graphql": {
+            "post": {
+                "description": "run a GraphQL query (album, albums) or mutation (createAlbum) against the store",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "graphql"
+                ],
+                "summary": "GraphQL album queries and mutations",
+                "parameters": [
+                    {
+                        "description": "GraphQL query, operation name and variables",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.graphqlRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "get Prometheus metrics for the service",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "albums"
+                ],
+                "summary": "Prometheus metrics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders": {
+            "post": {
+                "description": "checkout a cart, charging the payment processor and creating a paid order",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Checkout cart",
+                "parameters": [
+                    {
+                        "description": "order",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OrderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}": {
+            "get": {
+                "description": "get an order and its current status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get order by id",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "order id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "move an order through its lifecycle: pending -\u003e paid -\u003e shipped, or cancelled from pending or paid",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Update order status",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "order id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "order status",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OrderStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/schemas/album": {
+            "get": {
+                "description": "get the JSON Schema of an album, derived from the validation rules, to validate an album before sending it",
+                "produces": [
+                    "application/schema+json"
+                ],
+                "tags": [
+                    "albums"
+                ],
+                "summary": "JSON Schema for Album",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/status": {
+            "get": {
+                "description": "get the status of the service",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "albums"
+                ],
+                "summary": "Status of service",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/albums": {
+            "get": {
+                "description": "get all the albums in the store",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/x-yaml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "albums"
+                ],
+                "summary": "Get all Albums",
+                "deprecated": true,
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Album"
+                            }
+                        },
+                        "headers": {
+                            "Deprecation": {
+                                "type": "string",
+                                "description": "date v1 was deprecated, @ followed by Unix seconds"
+                            },
+                            "Link": {
+                                "type": "string",
+                                "description": "the resource in v2, rel=successor-version"
+                            },
+                            "Sunset": {
+                                "type": "string",
+                                "description": "date v1 is removed"
+                            }
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "add a new album to the store",
+                "consumes": [
+                    "application/json",
+                    "application/xml",
+                    "application/x-yaml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/x-yaml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "albums"
+                ],
+                "summary": "Create album",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "description": "album",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Album"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "replay the first response for retries of the same request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Album"
+                        },
+                        "headers": {
+                            "Deprecation": {
+                                "type": "string",
+                                "description": "date v1 was deprecated, @ followed by Unix seconds"
+                            },
+                            "Link": {
+                                "type": "string",
+                                "description": "the resource in v2, rel=successor-version"
+                            },
+                            "Sunset": {
+                                "type": "string",
+                                "description": "date v1 is removed"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/albums/{id}": {
+            "get": {
+                "description": "get as single album by id",
+                "produces": [
+                    "application/json",
+                    "application/xml",
+                    "application/x-yaml",
+                    "application/msgpack",
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "albums"
+                ],
+                "summary": "Get Album by id",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "album id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Album"
+                        },
+                        "headers": {
+                            "Deprecation": {
+                                "type": "string",
+                                "description": "date v1 was deprecated, @ followed by Unix seconds"
+                            },
+                            "Link": {
+                                "type": "string",
+                                "description": "the resource in v2, rel=successor-version"
+                            },
+                            "Sunset": {
+                                "type": "string",
+                                "description": "date v1 is removed"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Problem"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/albums": {
+            "get": {
+                "description": "get all the albums in the store, prices in cents with their currency",
                 "produces": [
                     "application/json"
                 ],
@@ -36,14 +929,20 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/model.Album"
+                                "$ref": "#/definitions/model.AlbumV2"
                             }
                         }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
                     }
                 }
             },
             "post": {
-                "description": "add a new album to the store",
+                "description": "add a new album to the store, price in cents with its currency",
                 "consumes": [
                     "application/json"
                 ],
@@ -61,15 +960,21 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.Album"
+                            "$ref": "#/definitions/model.AlbumV2"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "replay the first response for retries of the same request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/model.Album"
+                            "$ref": "#/definitions/model.AlbumV2"
                         }
                     },
                     "400": {
@@ -77,13 +982,43 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.ServerError"
                         }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
                     }
                 }
             }
         },
-        "/albums/{id}": {
+        "/v2/albums/{id}": {
             "get": {
-                "description": "get as single album by id",
+                "description": "get as single album by id, price in cents with its currency",
                 "produces": [
                     "application/json"
                 ],
@@ -95,9 +1030,9 @@ const docTemplate = `{
                     {
                         "minimum": 1,
                         "type": "integer",
-                        "description": "int valid",
+                        "description": "album id",
                         "name": "id",
-                        "in": "query",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -105,7 +1040,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Album"
+                            "$ref": "#/definitions/model.AlbumV2"
                         }
                     },
                     "400": {
@@ -113,25 +1048,37 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.ServerError"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ServerError"
+                        }
                     }
                 }
             }
         },
-        "/status": {
+        "/v3/api-docs": {
             "get": {
-                "description": "get Prometheus metrics for the service",
+                "description": "get the OpenAPI 3.0 document of the service",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "albums"
+                    "service"
                 ],
-                "summary": "Prometheus metrics",
+                "summary": "OpenAPI document",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "object"
                         }
                     }
                 }
@@ -139,6 +1086,24 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "main.graphqlRequest": {
+            "type": "object",
+            "required": [
+                "query"
+            ],
+            "properties": {
+                "operationName": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
         "model.Album": {
             "type": "object",
             "required": [
@@ -169,6 +1134,43 @@ const docTemplate = `{
                 }
             }
         },
+        "model.AlbumV2": {
+            "type": "object",
+            "required": [
+                "artist",
+                "currency",
+                "priceCents",
+                "title"
+            ],
+            "properties": {
+                "artist": {
+                    "type": "string",
+                    "maxLength": 1000,
+                    "minLength": 2
+                },
+                "currency": {
+                    "type": "string",
+                    "enum": [
+                        "USD"
+                    ]
+                },
+                "id": {
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 1
+                },
+                "priceCents": {
+                    "type": "integer",
+                    "maximum": 1000000,
+                    "minimum": 0
+                },
+                "title": {
+                    "type": "string",
+                    "maxLength": 1000,
+                    "minLength": 2
+                }
+            }
+        },
         "model.BindingErrorMsg": {
             "type": "object",
             "required": [
@@ -184,15 +1186,176 @@ const docTemplate = `{
                 }
             }
         },
-        "model.ServerError": {
+        "model.Cart": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.CartLine"
+                    }
+                },
+                "total": {
+                    "type": "number"
+                }
+            }
+        },
+        "model.CartLine": {
+            "type": "object",
+            "properties": {
+                "albumId": {
+                    "type": "integer"
+                },
+                "lineTotal": {
+                    "type": "number"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.CartLineRequest": {
+            "type": "object",
+            "required": [
+                "albumId",
+                "quantity"
+            ],
+            "properties": {
+                "albumId": {
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 1
+                },
+                "quantity": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1
+                }
+            }
+        },
+        "model.Order": {
+            "type": "object",
+            "properties": {
+                "cartId": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.CartLine"
+                    }
+                },
+                "paymentId": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.OrderStatus"
+                },
+                "total": {
+                    "type": "number"
+                }
+            }
+        },
+        "model.OrderRequest": {
+            "type": "object",
+            "required": [
+                "cartId"
+            ],
+            "properties": {
+                "cartId": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "model.OrderStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "paid",
+                "shipped",
+                "cancelled"
+            ],
+            "x-enum-varnames": [
+                "OrderPending",
+                "OrderPaid",
+                "OrderShipped",
+                "OrderCancelled"
+            ]
+        },
+        "model.OrderStatusRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "status": {
+                    "enum": [
+                        "shipped",
+                        "cancelled"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.OrderStatus"
+                        }
+                    ]
+                }
+            }
+        },
+        "model.Problem": {
             "type": "object",
             "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
                 "errors": {
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/model.BindingErrorMsg"
                     }
                 },
+                "instance": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "trace_id": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ServerError": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.BindingErrorMsg"
+                    },
+                    "x-nullable": true
+                },
                 "message": {
                     "type": "string"
                 }
@@ -206,7 +1369,7 @@ var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
 	Host:             "localhost:9080",
 	BasePath:         "/",
-	Schemes:          []string{},
+	Schemes:          []string{"http"},
 	Title:            "Album Store API",
 	Description:      "Simple golang album store CRUD application",
 	InfoInstanceName: "swagger",
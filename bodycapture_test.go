@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_bodyCapture_Route_Disabled(t *testing.T) {
+	resetAlbums()
+	telemetry.BodyCapture, _ = telemetry.NewBodyCapturePolicy(1, "/albums=0", 0, "")
+	defer func() { telemetry.BodyCapture = nil }()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	albumBody := `{"id": 10, "title": "The Ozzman Cometh", "artist": "Black Sabbath", "price": 66.60}`
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(albumBody))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusCreated, testRecorder.Code)
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	assert.NotContains(t, attributeMap, "album-store.request.body")
+	assert.NotContains(t, attributeMap, "album-store.response.body")
+	assert.Equal(t, "201", attributeMap["album-store.response.code"].Emit())
+}
+
+func Test_bodyCapture_Redacted_And_Truncated(t *testing.T) {
+	resetAlbums()
+	telemetry.BodyCapture, _ = telemetry.NewBodyCapturePolicy(1, "/albums/:id=1", 40, "$.price")
+	defer func() { telemetry.BodyCapture = nil }()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req, _ := http.NewRequest(http.MethodGet, "/albums/1", nil)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	assert.Equal(t, `{"artist":"John Coltrane","id":1,"price"...[truncated 35 bytes]`, attributeMap["album-store.response.body"].Emit())
+}
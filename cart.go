@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	cartsLock  sync.Mutex
+	carts      = map[int]*model.Cart{}
+	nextCartID = 1
+)
+
+func resetCarts() {
+	cartsLock.Lock()
+	defer cartsLock.Unlock()
+	carts = map[int]*model.Cart{}
+	nextCartID = 1
+}
+
+// roundPrice rounds a monetary amount to whole cents.
+func roundPrice(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func recalculateCart(cart *model.Cart) {
+	total := 0.0
+	for index := range cart.Lines {
+		cart.Lines[index].LineTotal = roundPrice(cart.Lines[index].Price * float64(cart.Lines[index].Quantity))
+		total += cart.Lines[index].LineTotal
+	}
+	cart.Total = roundPrice(total)
+}
+
+// copyCart returns a snapshot of the cart that is safe to use once cartsLock is released.
+func copyCart(cart *model.Cart) model.Cart {
+	lines := make([]model.CartLine, len(cart.Lines))
+	copy(lines, cart.Lines)
+	return model.Cart{ID: cart.ID, Lines: lines, Total: cart.Total}
+}
+
+// CreateCart godoc
+// @Summary Create cart
+// @Schemes
+// @Description create a new empty shopping cart
+// @Tags carts
+// @Produce json
+// @Success 201 {object} model.Cart
+// @Router /carts [post]
+func createCart(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	_, stage := telemetry.StartStage(c.Request.Context(), "store cart")
+	cartsLock.Lock()
+	cart := &model.Cart{ID: nextCartID, Lines: []model.CartLine{}}
+	carts[cart.ID] = cart
+	nextCartID++
+	response := copyCart(cart)
+	cartsLock.Unlock()
+	telemetry.EndStage(stage, false)
+	span.SetAttributes(attribute.Key("album-store.cart.id").Int(response.ID))
+	buildJsonResponse(c, span, http.StatusCreated, response)
+}
+
+// GetCartById godoc
+// @Summary Get cart by id
+// @Schemes
+// @Description get a shopping cart with its lines and computed total
+// @Tags carts
+// @Param  id path int true  "cart id" minimum(1)
+// @Produce json
+// @Success 200 {object} model.Cart
+// @Failure 400 {object} model.ServerError
+// @Failure 404 {object} model.ServerError
+// @Router /carts/{id} [get]
+func getCartByID(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	cartID, failed := parseIDParam(c, span, "id", "Cart")
+	if failed {
+		return
+	}
+	_, stage := telemetry.StartStage(c.Request.Context(), "find cart")
+	cartsLock.Lock()
+	cart, found := carts[cartID]
+	var response model.Cart
+	if found {
+		response = copyCart(cart)
+	}
+	cartsLock.Unlock()
+	telemetry.EndStage(stage, !found)
+	if !found {
+		buildErrorResponse(c, span, problemCartNotFound, fmt.Sprintf("Cart [%v] not found", cartID))
+		return
+	}
+	buildJsonResponse(c, span, http.StatusOK, response)
+}
+
+// AddCartLine godoc
+// @Summary Add album to cart
+// @Schemes
+// @Description add an album to a cart, increasing the quantity if the album is already in the cart
+// @Tags carts
+// @Param  id path int true  "cart id" minimum(1)
+// @Param request body model.CartLineRequest true "cart line"
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Cart
+// @Failure 400 {object} model.ServerError
+// @Failure 404 {object} model.ServerError
+// @Failure 413 {object} model.ServerError
+// @Failure 415 {object} model.ServerError
+// @Router /carts/{id}/lines [post]
+func addCartLine(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	cartID, failed := parseIDParam(c, span, "id", "Cart")
+	if failed {
+		return
+	}
+	var lineRequest model.CartLineRequest
+	if bindRequestJson(c, span, &lineRequest, "CartLine") {
+		return
+	}
+	album, found := findAlbumByID(c.Request.Context(), lineRequest.AlbumID)
+	if !found {
+		buildErrorResponse(c, span, problemAlbumNotFound, fmt.Sprintf("Album [%v] not found", lineRequest.AlbumID))
+		return
+	}
+
+	_, stage := telemetry.StartStage(c.Request.Context(), "update cart")
+	cartsLock.Lock()
+	cart, found := carts[cartID]
+	var response model.Cart
+	if found {
+		addLineToCart(cart, album, lineRequest.Quantity)
+		response = copyCart(cart)
+	}
+	cartsLock.Unlock()
+	telemetry.EndStage(stage, !found)
+	if !found {
+		buildErrorResponse(c, span, problemCartNotFound, fmt.Sprintf("Cart [%v] not found", cartID))
+		return
+	}
+	buildJsonResponse(c, span, http.StatusOK, response)
+}
+
+func addLineToCart(cart *model.Cart, album model.Album, quantity int) {
+	for index := range cart.Lines {
+		if cart.Lines[index].AlbumID == album.ID {
+			cart.Lines[index].Quantity += quantity
+			recalculateCart(cart)
+			return
+		}
+	}
+	cart.Lines = append(cart.Lines, model.CartLine{AlbumID: album.ID, Title: album.Title, Quantity: quantity, Price: album.Price})
+	recalculateCart(cart)
+}
+
+// RemoveCartLine godoc
+// @Summary Remove album from cart
+// @Schemes
+// @Description remove an album line from a cart
+// @Tags carts
+// @Param  id path int true  "cart id" minimum(1)
+// @Param  albumId path int true  "album id" minimum(1)
+// @Produce json
+// @Success 200 {object} model.Cart
+// @Failure 400 {object} model.ServerError
+// @Failure 404 {object} model.ServerError
+// @Router /carts/{id}/lines/{albumId} [delete]
+func removeCartLine(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	cartID, failed := parseIDParam(c, span, "id", "Cart")
+	if failed {
+		return
+	}
+	albumID, failed := parseIDParam(c, span, "albumId", "Album")
+	if failed {
+		return
+	}
+
+	_, stage := telemetry.StartStage(c.Request.Context(), "update cart")
+	cartsLock.Lock()
+	cart, cartFound := carts[cartID]
+	lineFound := false
+	var response model.Cart
+	if cartFound {
+		for index, line := range cart.Lines {
+			if line.AlbumID == albumID {
+				cart.Lines = append(cart.Lines[:index], cart.Lines[index+1:]...)
+				lineFound = true
+				break
+			}
+		}
+		recalculateCart(cart)
+		response = copyCart(cart)
+	}
+	cartsLock.Unlock()
+	telemetry.EndStage(stage, !cartFound || !lineFound)
+
+	if !cartFound {
+		buildErrorResponse(c, span, problemCartNotFound, fmt.Sprintf("Cart [%v] not found", cartID))
+		return
+	}
+	if !lineFound {
+		buildErrorResponse(c, span, problemCartAlbumNotFound, fmt.Sprintf("Album [%v] not in cart [%v]", albumID, cartID))
+		return
+	}
+	buildJsonResponse(c, span, http.StatusOK, response)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func Test_createCart(t *testing.T) {
+	resetCarts()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	var cart model.Cart
+
+	req := newJsonRequest(http.MethodPost, "/carts", nil)
+	router.ServeHTTP(testRecorder, req)
+	if err := json.Unmarshal(testRecorder.Body.Bytes(), &cart); err != nil {
+		assert.Fail(t, "json unmarshal fail", "should be Cart ", testRecorder.Body.String())
+	}
+
+	assert.Equal(t, http.StatusCreated, testRecorder.Code)
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, codes.Ok, finishedSpans[0].Status().Code)
+
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "201", attributeMap["album-store.response.code"].Emit())
+	assert.Equal(t, `{"id":1,"lines":[],"total":0}`, attributeMap["album-store.response.body"].Emit())
+
+	assert.Equal(t, model.Cart{ID: 1, Lines: []model.CartLine{}, Total: 0}, cart)
+}
+
+func Test_addCartLine_ComputesTotals(t *testing.T) {
+	resetAlbums()
+	resetCarts()
+	_, _, router := setupTestRouter(t)
+	router.ServeHTTP(httptest.NewRecorder(), newJsonRequest(http.MethodPost, "/carts", nil))
+
+	var cart model.Cart
+	for _, body := range []string{`{"albumId": 1, "quantity": 2}`, `{"albumId": 2, "quantity": 1}`, `{"albumId": 1, "quantity": 1}`} {
+		testRecorder := httptest.NewRecorder()
+		req := newJsonRequest(http.MethodPost, "/carts/1/lines", strings.NewReader(body))
+		router.ServeHTTP(testRecorder, req)
+		assert.Equal(t, http.StatusOK, testRecorder.Code)
+		if err := json.Unmarshal(testRecorder.Body.Bytes(), &cart); err != nil {
+			assert.Fail(t, "json unmarshal fail", "should be Cart ", testRecorder.Body.String())
+		}
+	}
+
+	assert.Equal(t, 2, len(cart.Lines))
+	assert.Equal(t, 3, cart.Lines[0].Quantity)
+	assert.Equal(t, 170.97, cart.Lines[0].LineTotal)
+	assert.Equal(t, 17.99, cart.Lines[1].LineTotal)
+	assert.Equal(t, 188.96, cart.Total)
+}
+
+func Test_addCartLine_AlbumNotFound(t *testing.T) {
+	resetCarts()
+	_, _, router := setupTestRouter(t)
+	router.ServeHTTP(httptest.NewRecorder(), newJsonRequest(http.MethodPost, "/carts", nil))
+
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	var serverError model.ServerError
+
+	req := newJsonRequest(http.MethodPost, "/carts/1/lines", strings.NewReader(`{"albumId": 666, "quantity": 1}`))
+	router.ServeHTTP(testRecorder, req)
+	if err := json.Unmarshal(testRecorder.Body.Bytes(), &serverError); err != nil {
+		assert.Fail(t, "json unmarshal fail", "should be ServerError ", testRecorder.Body.String())
+	}
+
+	assert.Equal(t, http.StatusNotFound, testRecorder.Code)
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
+	assert.Equal(t, "Album [666] not found", finishedSpans[0].Status().Description)
+
+	assert.Equal(t, "Album [666] not found", serverError.Message)
+}
+
+func Test_addCartLine_BadRequest_Validation(t *testing.T) {
+	resetCarts()
+	_, _, router := setupTestRouter(t)
+	router.ServeHTTP(httptest.NewRecorder(), newJsonRequest(http.MethodPost, "/carts", nil))
+
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	var serverError model.ServerError
+
+	req := newJsonRequest(http.MethodPost, "/carts/1/lines", strings.NewReader(`{"albumId": 1, "quantity": 0}`))
+	router.ServeHTTP(testRecorder, req)
+	if err := json.Unmarshal(testRecorder.Body.Bytes(), &serverError); err != nil {
+		assert.Fail(t, "json unmarshal fail", "should be ServerError ", testRecorder.Body.String())
+	}
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, "CartLine JSON field validation failed", finishedSpans[0].Status().Description)
+
+	assert.Equal(t, 1, len(serverError.BindingErrors))
+	assert.Equal(t, "quantity", serverError.BindingErrors[0].Field)
+	assert.Equal(t, "is required", serverError.BindingErrors[0].Message)
+}
+
+func Test_removeCartLine(t *testing.T) {
+	resetAlbums()
+	resetCarts()
+	_, _, router := setupTestRouter(t)
+	router.ServeHTTP(httptest.NewRecorder(), newJsonRequest(http.MethodPost, "/carts", nil))
+	router.ServeHTTP(httptest.NewRecorder(), newJsonRequest(http.MethodPost, "/carts/1/lines", strings.NewReader(`{"albumId": 1, "quantity": 2}`)))
+	router.ServeHTTP(httptest.NewRecorder(), newJsonRequest(http.MethodPost, "/carts/1/lines", strings.NewReader(`{"albumId": 3, "quantity": 1}`)))
+
+	testRecorder := httptest.NewRecorder()
+	var cart model.Cart
+
+	req := httptest.NewRequest(http.MethodDelete, "/carts/1/lines/1", nil)
+	router.ServeHTTP(testRecorder, req)
+	if err := json.Unmarshal(testRecorder.Body.Bytes(), &cart); err != nil {
+		assert.Fail(t, "json unmarshal fail", "should be Cart ", testRecorder.Body.String())
+	}
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, 1, len(cart.Lines))
+	assert.Equal(t, 3, cart.Lines[0].AlbumID)
+	assert.Equal(t, 39.99, cart.Total)
+
+	testRecorder = httptest.NewRecorder()
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodDelete, "/carts/1/lines/1", nil))
+	assert.Equal(t, http.StatusNotFound, testRecorder.Code)
+}
+
+func Test_getCartById_NotFound(t *testing.T) {
+	resetCarts()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	var serverError model.ServerError
+
+	req := httptest.NewRequest(http.MethodGet, "/carts/42", nil)
+	router.ServeHTTP(testRecorder, req)
+	if err := json.Unmarshal(testRecorder.Body.Bytes(), &serverError); err != nil {
+		assert.Fail(t, "json unmarshal fail", "should be ServerError ", testRecorder.Body.String())
+	}
+
+	assert.Equal(t, http.StatusNotFound, testRecorder.Code)
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
+
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "404", attributeMap["album-store.response.code"].Emit())
+
+	assert.Equal(t, "Cart [42] not found", serverError.Message)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// contract tests walk every operation in api/swagger.json, send a request generated to be valid and requests generated
+// to break one constraint each through setupRouter, and check every response against the documented responses.
+
+// contractRequest is a generated request, valid requests must succeed and invalid ones must be rejected with a 4xx.
+type contractRequest struct {
+	name  string
+	path  string
+	body  interface{}
+	valid bool
+}
+
+// contractExamples replace generated values the schema cannot describe well enough to be valid, keyed by operation and field.
+var contractExamples = map[string]interface{}{
+	"POST /graphql query": "{ albums { id title } }",
+}
+
+func init() {
+	openapi3filter.RegisterBodyDecoder("application/schema+json", openapi3filter.RegisteredBodyDecoder("application/json"))
+}
+
+func loadContractDocument(t *testing.T) *openapi3.T {
+	swaggerJson, err := os.ReadFile("api/swagger.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newOpenAPIDocument(string(swaggerJson))
+}
+
+// resetContractState gives every operation the same data, with cart 1 holding album 1 and order 1 placed from cart 2.
+func resetContractState(router *gin.Engine) {
+	resetAlbums()
+	resetCarts()
+	resetOrders()
+	resetIdempotentResponses()
+	for _, setup := range []struct{ path, body string }{
+		{"/carts", ""}, {"/carts/1/lines", `{"albumId": 1, "quantity": 1}`},
+		{"/carts", ""}, {"/carts/2/lines", `{"albumId": 2, "quantity": 1}`},
+		{"/orders", `{"cartId": 2}`},
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), newJsonRequest(http.MethodPost, setup.path, strings.NewReader(setup.body)))
+	}
+}
+
+func Test_contract_AllOperations(t *testing.T) {
+	document := loadContractDocument(t)
+	_, _, router := setupTestRouter(t)
+
+	for _, operationKey := range sortedOperations(document) {
+		method, path, _ := strings.Cut(operationKey, " ")
+		pathItem := document.Paths[path]
+		operation := pathItem.GetOperation(method)
+		route := &routers.Route{Spec: document, Path: path, PathItem: pathItem, Method: method, Operation: operation}
+		t.Run(operationKey, func(t *testing.T) {
+			for _, request := range contractRequests(operationKey, path, operation) {
+				resetContractState(router)
+				checkContract(t, router, route, request)
+			}
+		})
+	}
+}
+
+func sortedOperations(document *openapi3.T) []string {
+	var operations []string
+	for path, pathItem := range document.Paths {
+		for method := range pathItem.Operations() {
+			operations = append(operations, method+" "+path)
+		}
+	}
+	sort.Strings(operations)
+	return operations
+}
+
+func checkContract(t *testing.T, router *gin.Engine, route *routers.Route, request contractRequest) {
+	var body io.Reader
+	if request.body != nil {
+		bodyJson, _ := json.Marshal(request.body)
+		body = bytes.NewReader(bodyJson)
+	}
+	req := httptest.NewRequest(route.Method, request.path, body)
+	req.Header.Set("Accept", "application/json")
+	if request.body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	testRecorder := httptest.NewRecorder()
+	router.ServeHTTP(testRecorder, req)
+
+	if request.valid {
+		assert.Less(t, testRecorder.Code, 300, "%s should succeed, got %s", request.name, testRecorder.Body.String())
+	} else {
+		assert.True(t, testRecorder.Code >= 400 && testRecorder.Code < 500, "%s should be rejected, got %d", request.name, testRecorder.Code)
+	}
+	err := openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{Request: req, Route: route},
+		Status:                 testRecorder.Code,
+		Header:                 testRecorder.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(testRecorder.Body.Bytes())),
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true, MultiError: true},
+	})
+	assert.NoError(t, err, "%s response %d is not documented: %s", request.name, testRecorder.Code, testRecorder.Body.String())
+}
+
+// contractRequests is a valid request for the operation, then one request per path parameter and body field constraint broken.
+func contractRequests(operationKey string, path string, operation *openapi3.Operation) []contractRequest {
+	pathValues := map[string]interface{}{}
+	for _, parameter := range operation.Parameters {
+		if parameter.Value.In == openapi3.ParameterInPath {
+			pathValues[parameter.Value.Name] = exampleValue(parameter.Value.Schema.Value, operationKey+" "+parameter.Value.Name)
+		}
+	}
+	var bodySchema *openapi3.Schema
+	var validBody interface{}
+	if operation.RequestBody != nil {
+		if mediaType := operation.RequestBody.Value.Content.Get("application/json"); mediaType != nil {
+			bodySchema = mediaType.Schema.Value
+			validBody = exampleValue(bodySchema, operationKey)
+		}
+	}
+	requests := []contractRequest{{name: "valid request", path: expandPath(path, pathValues), body: validBody, valid: true}}
+
+	for _, parameter := range operation.Parameters {
+		if parameter.Value.In != openapi3.ParameterInPath {
+			continue
+		}
+		for _, invalid := range invalidValues(parameter.Value.Schema.Value) {
+			values := copyValues(pathValues)
+			values[parameter.Value.Name] = invalid
+			requests = append(requests, contractRequest{name: fmt.Sprintf("path %s=%v", parameter.Value.Name, invalid), path: expandPath(path, values), body: validBody})
+		}
+	}
+	if bodySchema == nil {
+		return requests
+	}
+	for _, name := range bodySchema.Required {
+		body := copyValues(validBody.(map[string]interface{}))
+		delete(body, name)
+		requests = append(requests, contractRequest{name: "body without " + name, path: expandPath(path, pathValues), body: body})
+	}
+	for name, property := range bodySchema.Properties {
+		for _, invalid := range invalidValues(property.Value) {
+			body := copyValues(validBody.(map[string]interface{}))
+			body[name] = invalid
+			requests = append(requests, contractRequest{name: fmt.Sprintf("body %s=%v", name, invalid), path: expandPath(path, pathValues), body: body})
+		}
+	}
+	return requests
+}
+
+// exampleValue generates the smallest value the schema accepts.
+func exampleValue(schema *openapi3.Schema, key string) interface{} {
+	if example, found := contractExamples[key]; found {
+		return example
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+	switch schema.Type {
+	case openapi3.TypeObject:
+		object := map[string]interface{}{}
+		for name, property := range schema.Properties {
+			if property.Value.Type != openapi3.TypeObject {
+				object[name] = exampleValue(property.Value, key+" "+name)
+			}
+		}
+		return object
+	case openapi3.TypeArray:
+		return []interface{}{exampleValue(schema.Items.Value, key)}
+	case openapi3.TypeInteger, openapi3.TypeNumber:
+		if schema.Min != nil && *schema.Min > 1 {
+			return *schema.Min
+		}
+		return 1
+	case openapi3.TypeBoolean:
+		return true
+	default:
+		return strings.Repeat("a", int(schema.MinLength)+1)
+	}
+}
+
+// invalidValues are values breaking each constraint of the schema, including its type.
+func invalidValues(schema *openapi3.Schema) []interface{} {
+	var values []interface{}
+	switch schema.Type {
+	case openapi3.TypeInteger, openapi3.TypeNumber:
+		values = append(values, "X")
+		if schema.Min != nil {
+			values = append(values, *schema.Min-1)
+		}
+		if schema.Max != nil {
+			values = append(values, *schema.Max+1)
+		}
+	case openapi3.TypeString:
+		values = append(values, 1)
+		if schema.MinLength > 1 {
+			values = append(values, strings.Repeat("a", int(schema.MinLength)-1))
+		}
+		if schema.MaxLength != nil {
+			values = append(values, strings.Repeat("a", int(*schema.MaxLength)+1))
+		}
+		if len(schema.Enum) > 0 {
+			values = append(values, "not-"+fmt.Sprint(schema.Enum[0]))
+		}
+	}
+	return values
+}
+
+func expandPath(path string, values map[string]interface{}) string {
+	for name, value := range values {
+		path = strings.ReplaceAll(path, "{"+name+"}", fmt.Sprint(value))
+	}
+	return path
+}
+
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	copied := map[string]interface{}{}
+	for name, value := range values {
+		copied[name] = value
+	}
+	return copied
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxRequestBodyBytes is the largest request body read, set with MAX_BODY_BYTES.
+var maxRequestBodyBytes int64 = 1 << 20
+
+// limitRequestBody caps every request body at maxRequestBodyBytes, reading past the limit fails with an *http.MaxBytesError.
+func limitRequestBody() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRequestBodyBytes)
+		}
+		c.Next()
+	}
+}
+
+// readRequestBody reads the whole request body, writing a 413 when it is larger than maxRequestBodyBytes.
+func readRequestBody(c *gin.Context, span trace.Span) ([]byte, bool) {
+	byteArray, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			buildErrorResponse(c, span, problemBodyTooLarge, fmt.Sprintf("Request body larger than %d bytes", maxBytesError.Limit))
+			return nil, true
+		}
+		buildErrorResponse(c, span, problemMalformedBody, fmt.Sprintf("Request body could not be read. %s", err))
+		return nil, true
+	}
+	return byteArray, false
+}
+
+// requireJsonContentType writes a 415 unless the request body is sent as application/json.
+func requireJsonContentType(c *gin.Context, span trace.Span) bool {
+	if c.ContentType() != binding.MIMEJSON {
+		buildErrorResponse(c, span, problemUnsupportedMediaType, fmt.Sprintf("Content-Type [%s] not supported, use %s", c.ContentType(), binding.MIMEJSON))
+		return true
+	}
+	return false
+}
+
+// rejectedField is a key of a JSON request body that is not a field of the model or appears more than once.
+type rejectedField struct {
+	name   string
+	reason string
+}
+
+// findRejectedFields lists unknown and duplicate keys of a JSON object, matching names without case as encoding/json does.
+// Only the top level of the object is checked, a body that is not a JSON object is left for binding to reject.
+func findRejectedFields(body []byte, target interface{}) []rejectedField {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+	metadata, registered := metadataFor(target)
+	var rejected []rejectedField
+	seen := map[string]bool{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+		name, _ := token.(string)
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil
+		}
+		switch {
+		case seen[strings.ToLower(name)]:
+			rejected = append(rejected, rejectedField{name: name, reason: "duplicate-field"})
+		case registered && !metadata.hasJsonName(name):
+			rejected = append(rejected, rejectedField{name: name, reason: "unknown-field"})
+		}
+		seen[strings.ToLower(name)] = true
+	}
+	return rejected
+}
+
+// rejectInvalidFields writes a 400 listing the unknown and duplicate keys of a JSON body as binding errors,
+// each rejected key is also a span event carrying the field name.
+func rejectInvalidFields(c *gin.Context, span trace.Span, body []byte, target interface{}, modelName string) bool {
+	rejected := findRejectedFields(body, target)
+	if len(rejected) == 0 {
+		return false
+	}
+	bindingErrorMessages := buildRejectedFieldMessages(rejected, translatorForLanguages(c.GetHeader("Accept-Language")))
+	for _, field := range rejected {
+		span.AddEvent(fmt.Sprintf("Rejected %s [%s]", field.reason, field.name), trace.WithAttributes(
+			attribute.Key("album-store.request.field").String(field.name),
+			attribute.Key("album-store.request.field.reason").String(field.reason),
+		))
+	}
+	errorMessage := fmt.Sprintf("%s JSON has unknown or duplicate fields", modelName)
+	bindingErrorMessage, _ := json.Marshal(bindingErrorMessages)
+	span.SetStatus(codes.Error, errorMessage)
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.request.body", string(body))
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.response.body", fmt.Sprintf(`{"errors":%s}`, bindingErrorMessage))
+	abortWithProblem(c, span, problemInvalidFields, errorMessage, bindingErrorMessages)
+	return true
+}
+
+func buildRejectedFieldMessages(rejected []rejectedField, translator ut.Translator) []*model.BindingErrorMsg {
+	bindingErrorMessages := make([]*model.BindingErrorMsg, len(rejected))
+	for index, field := range rejected {
+		message, _ := translator.T(field.reason)
+		bindingErrorMessages[index] = &model.BindingErrorMsg{Field: field.name, Message: message}
+	}
+	return bindingErrorMessages
+}
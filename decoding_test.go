@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func Test_postAlbum_UnknownField(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`{"id": 10, "title": "Blue Train", "titel": "Blue Train", "artist": "John Coltrane", "price": 56.99}`))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":[{"field":"titel","message":"is not a known field"}],"message":""}`, testRecorder.Body.String())
+	assert.Equal(t, 3, len(listAlbums()))
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
+	assert.Equal(t, "Album JSON has unknown or duplicate fields", finishedSpans[0].Status().Description)
+	assert.Equal(t, 1, len(finishedSpans[0].Events()))
+	assert.Equal(t, "Rejected unknown-field [titel]", finishedSpans[0].Events()[0].Name)
+	eventAttributes := makeKeyMap(finishedSpans[0].Events()[0].Attributes)
+	assert.Equal(t, "titel", eventAttributes["album-store.request.field"].Emit())
+	assert.Equal(t, "unknown-field", eventAttributes["album-store.request.field.reason"].Emit())
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "invalid-fields", attributeMap["album-store.error.code"].Emit())
+}
+
+func Test_postAlbum_DuplicateKey(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`{"id": 10, "title": "Blue Train", "artist": "John Coltrane", "Title": "Giant Steps", "price": 56.99}`))
+	req.Header.Set("Accept-Language", "de")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":[{"field":"Title","message":"ist ein doppelter Schlüssel"}],"message":""}`, testRecorder.Body.String())
+	assert.Equal(t, "Rejected duplicate-field [Title]", requestSpans(spanRecorder)[0].Events()[0].Name)
+	assert.Equal(t, 3, len(listAlbums()))
+}
+
+func Test_postOrder_UnknownField(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPost, "/orders", strings.NewReader(`{"cartId": 1, "coupon": "FREE"}`))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":[{"field":"coupon","message":"is not a known field"}],"message":""}`, testRecorder.Body.String())
+}
+
+func Test_postAlbum_MissingContentType(t *testing.T) {
+	resetAlbums()
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader(`{"id": 10, "title": "Blue Train", "artist": "John Coltrane", "price": 56.99}`))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, testRecorder.Code)
+	assert.Contains(t, testRecorder.Body.String(), `"message":"Content-Type [] not supported, use one of application/json`)
+	assert.Equal(t, 3, len(listAlbums()))
+}
+
+func Test_postOrder_MissingContentType(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"cartId": 1}`))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, testRecorder.Code)
+	assert.Equal(t, `{"errors":null,"message":"Content-Type [] not supported, use application/json"}`, testRecorder.Body.String())
+}
+
+func Test_postAlbum_BodyTooLarge(t *testing.T) {
+	resetAlbums()
+	maxRequestBodyBytes = 32
+	defer func() { maxRequestBodyBytes = 1 << 20 }()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`{"id": 10, "title": "Blue Train", "artist": "John Coltrane", "price": 56.99}`))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, testRecorder.Code)
+	assert.Equal(t, `{"errors":null,"message":"Request body larger than 32 bytes"}`, testRecorder.Body.String())
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	assert.Equal(t, "body-too-large", attributeMap["album-store.error.code"].Emit())
+	assert.Equal(t, 3, len(listAlbums()))
+}
+
+func Test_postAlbum_BodyTooLarge_Idempotent(t *testing.T) {
+	resetAlbums()
+	resetIdempotentResponses()
+	maxRequestBodyBytes = 32
+	defer func() { maxRequestBodyBytes = 1 << 20 }()
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`{"id": 10, "title": "Blue Train", "artist": "John Coltrane", "price": 56.99}`))
+	req.Header.Set("Idempotency-Key", "too-large")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, testRecorder.Code)
+	assert.Equal(t, 3, len(listAlbums()))
+}
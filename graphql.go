@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// acceptLanguageKey carries the request Accept-Language to the resolvers for validation messages.
+type acceptLanguageKey struct{}
+
+type graphqlRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// albumValidationError carries the same binding errors as POST /albums in the GraphQL error extensions.
+type albumValidationError struct {
+	bindingErrors []*model.BindingErrorMsg
+}
+
+func (e albumValidationError) Error() string {
+	return "Album JSON field validation failed"
+}
+
+func (e albumValidationError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"errors": e.bindingErrors}
+}
+
+var albumGraphqlType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Album",
+	Fields: graphql.Fields{
+		"id":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"title":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"artist": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"price":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+// albumInputGraphqlType leaves every field nullable so missing fields are reported by the album binding rules.
+var albumInputGraphqlType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "AlbumInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"id":     &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"title":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"artist": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"price":  &graphql.InputObjectFieldConfig{Type: graphql.Float},
+	},
+})
+
+// startResolverSpan creates a child span of the /graphql request span for a single resolver.
+func startResolverSpan(p graphql.ResolveParams) (context.Context, trace.Span) {
+	ctx, span := telemetry.Tracer().Start(p.Context, fmt.Sprintf("resolve %s.%s", p.Info.ParentType.Name(), p.Info.FieldName))
+	span.SetAttributes(attribute.Key("graphql.field.name").String(p.Info.FieldName))
+	return ctx, span
+}
+
+func newAlbumGraphqlSchema(log zerolog.Logger) (graphql.Schema, error) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"album": &graphql.Field{
+				Type:        albumGraphqlType,
+				Description: "get a single album by id",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					_, span := startResolverSpan(p)
+					defer span.End()
+					albumID := p.Args["id"].(int)
+					album, found := albumByID(albumID)
+					if !found {
+						errorMessage := fmt.Sprintf("Album [%v] not found", albumID)
+						span.SetStatus(codes.Error, errorMessage)
+						return nil, errors.New(errorMessage)
+					}
+					span.SetStatus(codes.Ok, "")
+					return album, nil
+				},
+			},
+			"albums": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(albumGraphqlType))),
+				Description: "list albums, optionally filtered by artist, title and price range",
+				Args: graphql.FieldConfigArgument{
+					"artist":   &graphql.ArgumentConfig{Type: graphql.String, Description: "artist name, case insensitive"},
+					"title":    &graphql.ArgumentConfig{Type: graphql.String, Description: "part of the title, case insensitive"},
+					"minPrice": &graphql.ArgumentConfig{Type: graphql.Float},
+					"maxPrice": &graphql.ArgumentConfig{Type: graphql.Float},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					_, span := startResolverSpan(p)
+					defer span.End()
+					filtered := filterAlbums(p.Args)
+					span.SetAttributes(attribute.Key("album-store.albums.count").Int(len(filtered)))
+					span.SetStatus(codes.Ok, "")
+					return filtered, nil
+				},
+			},
+		},
+	})
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createAlbum": &graphql.Field{
+				Type:        albumGraphqlType,
+				Description: "add a new album to the store",
+				Args: graphql.FieldConfigArgument{
+					"album": &graphql.ArgumentConfig{Type: graphql.NewNonNull(albumInputGraphqlType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					ctx, span := startResolverSpan(p)
+					defer span.End()
+					acceptLanguage, _ := p.Context.Value(acceptLanguageKey{}).(string)
+					input := p.Args["album"].(map[string]interface{})
+					album := model.Album{}
+					album.ID, _ = input["id"].(int)
+					album.Title, _ = input["title"].(string)
+					album.Artist, _ = input["artist"].(string)
+					album.Price, _ = input["price"].(float64)
+					if err := binding.Validator.ValidateStruct(&album); err != nil {
+						var validationErrors validator.ValidationErrors
+						if errors.As(err, &validationErrors) {
+							validationError := albumValidationError{bindingErrors: buildBindingErrorMessages(validationErrors, &album, translatorForLanguages(acceptLanguage))}
+							catalog.validationFailed(ctx, validationErrors, validationError.bindingErrors)
+							resolverLog := telemetry.WithTraceContext(ctx, log)
+							resolverLog.Warn().Msg(validationError.Error())
+							span.SetStatus(codes.Error, validationError.Error())
+							return nil, validationError
+						}
+						span.SetStatus(codes.Error, err.Error())
+						return nil, err
+					}
+					addAlbum(ctx, album)
+					span.SetStatus(codes.Ok, "")
+					return album, nil
+				},
+			},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+func filterAlbums(args map[string]interface{}) []model.Album {
+	artist, _ := args["artist"].(string)
+	title, _ := args["title"].(string)
+	minPrice, hasMinPrice := args["minPrice"].(float64)
+	maxPrice, hasMaxPrice := args["maxPrice"].(float64)
+	albums := listAlbums()
+	filtered := make([]model.Album, 0, len(albums))
+	for _, album := range albums {
+		if artist != "" && !strings.EqualFold(album.Artist, artist) {
+			continue
+		}
+		if title != "" && !strings.Contains(strings.ToLower(album.Title), strings.ToLower(title)) {
+			continue
+		}
+		if (hasMinPrice && album.Price < minPrice) || (hasMaxPrice && album.Price > maxPrice) {
+			continue
+		}
+		filtered = append(filtered, album)
+	}
+	return filtered
+}
+
+// graphqlOperation finds the operation that will run, returning its name and type (query or mutation).
+func graphqlOperation(query string, operationName string) (string, string) {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return operationName, ""
+	}
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		name := ""
+		if operation.Name != nil {
+			name = operation.Name.Value
+		}
+		if operationName == "" || operationName == name {
+			return name, operation.Operation
+		}
+	}
+	return operationName, ""
+}
+
+// Graphql godoc
+// @Summary GraphQL album queries and mutations
+// @Schemes
+// @Description run a GraphQL query (album, albums) or mutation (createAlbum) against the store
+// @Tags graphql
+// @Param request body graphqlRequest true "GraphQL query, operation name and variables"
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 400 {object} model.ServerError
+// @Failure 413 {object} model.ServerError
+// @Failure 415 {object} model.ServerError
+// @Router /graphql [post]
+func postGraphql(log zerolog.Logger) gin.HandlerFunc {
+	schema, err := newAlbumGraphqlSchema(log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("GraphQL schema is invalid")
+	}
+	fn := func(c *gin.Context) {
+		span := trace.SpanFromContext(c.Request.Context())
+		var request graphqlRequest
+		if bindRequestJson(c, span, &request, "GraphQL") {
+			return
+		}
+		operationName, operationType := graphqlOperation(request.Query, request.OperationName)
+		if operationName != "" {
+			span.SetAttributes(attribute.Key("graphql.operation.name").String(operationName))
+		}
+		if operationType != "" {
+			span.SetAttributes(attribute.Key("graphql.operation.type").String(operationType))
+		}
+		telemetry.SetBodyAttribute(c.Request.Context(), span, "graphql.document", request.Query)
+
+		// the operation is a stage named as in the GraphQL semantic conventions, with the resolver spans as its children
+		operationSpanName := "GraphQL Operation"
+		if operationType != "" {
+			operationSpanName = strings.TrimSpace(operationType + " " + operationName)
+		}
+		ctx, operationSpan := telemetry.Tracer().Start(c.Request.Context(), operationSpanName)
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  request.Query,
+			VariableValues: request.Variables,
+			OperationName:  request.OperationName,
+			Context:        context.WithValue(ctx, acceptLanguageKey{}, c.GetHeader("Accept-Language")),
+		})
+		telemetry.EndStage(operationSpan, result.HasErrors())
+		if result.HasErrors() {
+			span.SetStatus(codes.Error, result.Errors[0].Message)
+			for _, resultError := range result.Errors {
+				span.AddEvent(resultError.Message)
+			}
+			telemetry.SetResponseCode(span, http.StatusOK)
+			c.JSON(http.StatusOK, result)
+			return
+		}
+		buildJsonResponse(c, span, http.StatusOK, result)
+	}
+	return fn
+}
+
+// getGraphiql serves the GraphiQL explorer for /graphql, only registered when Gin runs in debug mode.
+func getGraphiql(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(graphiqlPage))
+}
+
+const graphiqlPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Album Store GraphiQL</title>
+  <link rel="stylesheet" href="https://unpkg.com/graphiql@2.4.7/graphiql.min.css" />
+</head>
+<body style="margin: 0;">
+  <div id="graphiql" style="height: 100vh;"></div>
+  <script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/graphiql@2.4.7/graphiql.min.js"></script>
+  <script>
+    const fetcher = GraphiQL.createFetcher({ url: window.location.pathname });
+    ReactDOM.createRoot(document.getElementById('graphiql')).render(React.createElement(GraphiQL, { fetcher: fetcher }));
+  </script>
+</body>
+</html>`
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func Test_graphql_AlbumById(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	requestBody := `{"query": "query GetAlbum($id: Int!) { album(id: $id) { title artist } }", "variables": {"id": 2}}`
+
+	req := newJsonRequest(http.MethodPost, "/graphql", strings.NewReader(requestBody))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, `{"data":{"album":{"artist":"Gerry Mulligan","title":"Jeru"}}}`, testRecorder.Body.String())
+
+	finishedSpans := spanRecorder.Ended()
+	assert.Equal(t, []string{"parse request body", "validate GraphQL", "resolve Query.album", "query GetAlbum", "serialize response", "/graphql"}, spanNames(finishedSpans))
+	assert.Equal(t, finishedSpans[3].SpanContext().SpanID(), finishedSpans[2].Parent().SpanID())
+	assert.Equal(t, finishedSpans[5].SpanContext().SpanID(), finishedSpans[3].Parent().SpanID())
+
+	assert.Equal(t, codes.Ok, finishedSpans[5].Status().Code)
+	attributeMap := makeKeyMap(finishedSpans[5].Attributes())
+	assert.Equal(t, "GetAlbum", attributeMap["graphql.operation.name"].Emit())
+	assert.Equal(t, "query", attributeMap["graphql.operation.type"].Emit())
+	assert.Equal(t, "200", attributeMap["album-store.response.code"].Emit())
+}
+
+func Test_graphql_AlbumById_NotFound(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	requestBody := `{"query": "{ album(id: 666) { title } }"}`
+
+	req := newJsonRequest(http.MethodPost, "/graphql", strings.NewReader(requestBody))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Contains(t, testRecorder.Body.String(), `"message":"Album [666] not found"`)
+
+	finishedSpans := spanRecorder.Ended()
+	assert.Equal(t, []string{"parse request body", "validate GraphQL", "resolve Query.album", "query", "/graphql"}, spanNames(finishedSpans))
+	assert.Equal(t, codes.Error, finishedSpans[2].Status().Code)
+	assert.Equal(t, codes.Error, finishedSpans[3].Status().Code)
+	assert.Equal(t, codes.Error, finishedSpans[4].Status().Code)
+	assert.Equal(t, "Album [666] not found", finishedSpans[4].Status().Description)
+}
+
+func Test_graphql_Albums_Filtered(t *testing.T) {
+	resetAlbums()
+	testRecorder, _, router := setupTestRouter(t)
+
+	requestBody := `{"query": "query Cheap { albums(maxPrice: 40) { id } }"}`
+
+	req := newJsonRequest(http.MethodPost, "/graphql", strings.NewReader(requestBody))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, `{"data":{"albums":[{"id":2},{"id":3}]}}`, testRecorder.Body.String())
+}
+
+func Test_graphql_CreateAlbum(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	requestBody := `{"query": "mutation AddAlbum { createAlbum(album: {id: 10, title: \"The Ozzman Cometh\", artist: \"Black Sabbath\", price: 66.6}) { id title } }"}`
+
+	req := newJsonRequest(http.MethodPost, "/graphql", strings.NewReader(requestBody))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, `{"data":{"createAlbum":{"id":10,"title":"The Ozzman Cometh"}}}`, testRecorder.Body.String())
+
+	finishedSpans := spanRecorder.Ended()
+	assert.Equal(t, []string{"parse request body", "validate GraphQL", "store album", "resolve Mutation.createAlbum", "mutation AddAlbum", "serialize response", "/graphql"}, spanNames(finishedSpans))
+	assert.Equal(t, finishedSpans[3].SpanContext().SpanID(), finishedSpans[2].Parent().SpanID())
+	attributeMap := makeKeyMap(finishedSpans[6].Attributes())
+	assert.Equal(t, "mutation", attributeMap["graphql.operation.type"].Emit())
+
+	assert.Equal(t, 4, len(listAlbums()))
+}
+
+func Test_graphql_CreateAlbum_ValidationErrors(t *testing.T) {
+	resetAlbums()
+	testRecorder, _, router := setupTestRouter(t)
+
+	requestBody := `{"query": "mutation { createAlbum(album: {id: -1, title: \"a\", price: 20000}) { id } }"}`
+
+	req := newJsonRequest(http.MethodPost, "/graphql", strings.NewReader(requestBody))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Contains(t, testRecorder.Body.String(), `"message":"Album JSON field validation failed"`)
+	assert.Contains(t, testRecorder.Body.String(), `"extensions":{"errors":[{"field":"id","message":"must be 1 or greater"},{"field":"title","message":"must be at least 2 characters"},{"field":"artist","message":"is required"},{"field":"price","message":"must be 10000.00 or less"}]}`)
+
+	assert.Equal(t, 3, len(listAlbums()))
+}
+
+func Test_graphql_BadRequest_MissingQuery(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPost, "/graphql", strings.NewReader(`{"variables": {}}`))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":[{"field":"query","message":"is required"}],"message":""}`, testRecorder.Body.String())
+}
+
+func Test_getGraphiql_DebugModeOnly(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/graphql", nil))
+	assert.Equal(t, http.StatusNotFound, testRecorder.Code)
+
+	gin.SetMode(gin.DebugMode)
+	defer gin.SetMode(gin.TestMode)
+	testRecorder, _, router = setupTestRouter(t)
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/graphql", nil))
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Contains(t, testRecorder.Body.String(), "graphiql.min.js")
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/albumpb"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	otelCodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	grpcStatus "google.golang.org/grpc/status"
+)
+
+// albumServiceServer serves the albumpb.AlbumService from the same in-memory albums as the REST handlers.
+type albumServiceServer struct {
+	albumpb.UnimplementedAlbumServiceServer
+	log zerolog.Logger
+}
+
+func albumToProto(album model.Album) *albumpb.Album {
+	return &albumpb.Album{Id: int32(album.ID), Title: album.Title, Artist: album.Artist, Price: album.Price}
+}
+
+func albumFromProto(album *albumpb.Album) model.Album {
+	return model.Album{ID: int(album.GetId()), Title: album.GetTitle(), Artist: album.GetArtist(), Price: album.GetPrice()}
+}
+
+// grpcStatusFromServerError maps a model.ServerError onto a gRPC status, carrying binding errors as BadRequest field violations.
+func grpcStatusFromServerError(ctx context.Context, code codes.Code, serverError model.ServerError) error {
+	span := trace.SpanFromContext(ctx)
+	message := serverError.Message
+	if message == "" && len(serverError.BindingErrors) > 0 {
+		message = "Album JSON field validation failed"
+	}
+	span.SetStatus(otelCodes.Error, message)
+	span.AddEvent(message)
+	telemetry.SetGrpcStatusCode(span, code)
+	statusWithMessage := grpcStatus.New(code, message)
+	if len(serverError.BindingErrors) == 0 {
+		return statusWithMessage.Err()
+	}
+	badRequest := &errdetails.BadRequest{}
+	for _, bindingError := range serverError.BindingErrors {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: bindingError.Field, Description: bindingError.Message})
+	}
+	detailedStatus, err := statusWithMessage.WithDetails(badRequest)
+	if err != nil {
+		return statusWithMessage.Err()
+	}
+	return detailedStatus.Err()
+}
+
+func (s *albumServiceServer) GetAlbum(ctx context.Context, request *albumpb.GetAlbumRequest) (*albumpb.Album, error) {
+	span := trace.SpanFromContext(ctx)
+	telemetry.SetRequestParameters(span, fmt.Sprintf("%s=%d", "ID", request.GetId()))
+	album, found := albumByID(int(request.GetId()))
+	if !found {
+		return nil, grpcStatusFromServerError(ctx, codes.NotFound, model.ServerError{Message: fmt.Sprintf("Album [%v] not found", request.GetId())})
+	}
+	span.SetStatus(otelCodes.Ok, "")
+	return albumToProto(album), nil
+}
+
+func (s *albumServiceServer) ListAlbums(ctx context.Context, _ *albumpb.ListAlbumsRequest) (*albumpb.ListAlbumsResponse, error) {
+	albums := listAlbums()
+	response := &albumpb.ListAlbumsResponse{Albums: make([]*albumpb.Album, len(albums))}
+	for index, album := range albums {
+		response.Albums[index] = albumToProto(album)
+	}
+	trace.SpanFromContext(ctx).SetStatus(otelCodes.Ok, "")
+	return response, nil
+}
+
+func (s *albumServiceServer) CreateAlbum(ctx context.Context, request *albumpb.CreateAlbumRequest) (*albumpb.Album, error) {
+	if request.GetAlbum() == nil {
+		return nil, grpcStatusFromServerError(ctx, codes.InvalidArgument, model.ServerError{Message: "Malformed request. Not valid for Album"})
+	}
+	album := albumFromProto(request.GetAlbum())
+	if err := binding.Validator.ValidateStruct(&album); err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			bindingErrorMessages := buildBindingErrorMessages(validationErrors, &album, translatorForLanguages(strings.Join(metadata.ValueFromIncomingContext(ctx, "accept-language"), ",")))
+			catalog.validationFailed(ctx, validationErrors, bindingErrorMessages)
+			log := telemetry.WithTraceContext(ctx, s.log)
+			log.Warn().Msg("Album field validation failed")
+			return nil, grpcStatusFromServerError(ctx, codes.InvalidArgument, model.ServerError{BindingErrors: bindingErrorMessages})
+		}
+		return nil, grpcStatusFromServerError(ctx, codes.InvalidArgument, model.ServerError{Message: err.Error()})
+	}
+	addAlbum(ctx, album)
+	trace.SpanFromContext(ctx).SetStatus(otelCodes.Ok, "")
+	return albumToProto(album), nil
+}
+
+// newGrpcServer builds the gRPC server with OpenTelemetry interceptors and server reflection for grpcurl.
+func newGrpcServer(log zerolog.Logger) *grpc.Server {
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(otelgrpc.StreamServerInterceptor()),
+	)
+	albumpb.RegisterAlbumServiceServer(grpcServer, &albumServiceServer{log: log})
+	reflection.Register(grpcServer)
+	return grpcServer
+}
+
+// grpcOrHttpHandler routes HTTP/2 requests with a gRPC content-type to the gRPC server and everything else to Gin.
+func grpcOrHttpHandler(grpcServer *grpc.Server, httpHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		httpHandler.ServeHTTP(w, r)
+	})
+}
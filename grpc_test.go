@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/albumpb"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	grpcStatus "google.golang.org/grpc/status"
+)
+
+// setupTestGrpcServer serves REST and gRPC from one h2c listener the same way main does.
+func setupTestGrpcServer(t *testing.T) (albumpb.AlbumServiceClient, *tracetest.SpanRecorder, *httptest.Server) {
+	_, spanRecorder, router := setupTestRouter(t)
+	logInfo := zerolog.New(os.Stdout).With().Timestamp().Logger()
+	server := httptest.NewServer(h2c.NewHandler(grpcOrHttpHandler(newGrpcServer(logInfo), router), &http2.Server{}))
+	t.Cleanup(server.Close)
+	conn, err := grpc.Dial(strings.TrimPrefix(server.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		assert.FailNow(t, "grpc dial fail", err.Error())
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return albumpb.NewAlbumServiceClient(conn), spanRecorder, server
+}
+
+func Test_grpc_GetAlbum(t *testing.T) {
+	resetAlbums()
+	client, spanRecorder, _ := setupTestGrpcServer(t)
+
+	album, err := client.GetAlbum(context.Background(), &albumpb.GetAlbumRequest{Id: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Jeru", album.GetTitle())
+	assert.Equal(t, 17.99, album.GetPrice())
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, "album.v1.AlbumService/GetAlbum", finishedSpans[0].Name())
+}
+
+func Test_grpc_GetAlbum_NotFound(t *testing.T) {
+	resetAlbums()
+	client, spanRecorder, _ := setupTestGrpcServer(t)
+
+	_, err := client.GetAlbum(context.Background(), &albumpb.GetAlbumRequest{Id: 666})
+
+	assert.Equal(t, codes.NotFound, grpcStatus.Code(err))
+	assert.Equal(t, "Album [666] not found", grpcStatus.Convert(err).Message())
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "NotFound", attributeMap["album-store.response.code"].Emit())
+}
+
+func Test_grpc_ListAlbums(t *testing.T) {
+	resetAlbums()
+	client, _, _ := setupTestGrpcServer(t)
+
+	response, err := client.ListAlbums(context.Background(), &albumpb.ListAlbumsRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, len(listAlbums()), len(response.GetAlbums()))
+	assert.Equal(t, "Blue Train", response.GetAlbums()[0].GetTitle())
+}
+
+func Test_grpc_CreateAlbum(t *testing.T) {
+	resetAlbums()
+	client, _, _ := setupTestGrpcServer(t)
+
+	album, err := client.CreateAlbum(context.Background(), &albumpb.CreateAlbumRequest{Album: &albumpb.Album{Id: 10, Title: "The Ozzman Cometh", Artist: "Black Sabbath", Price: 66.60}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(10), album.GetId())
+	assert.Equal(t, 4, len(listAlbums()))
+}
+
+func Test_grpc_CreateAlbum_InvalidArgument(t *testing.T) {
+	resetAlbums()
+	client, _, _ := setupTestGrpcServer(t)
+
+	_, err := client.CreateAlbum(context.Background(), &albumpb.CreateAlbumRequest{Album: &albumpb.Album{Id: 50000000, Title: "aa", Artist: "zz", Price: 20000.00}})
+
+	assert.Equal(t, codes.InvalidArgument, grpcStatus.Code(err))
+	details := grpcStatus.Convert(err).Details()
+	assert.Len(t, details, 1)
+	badRequest := details[0].(*errdetails.BadRequest)
+	assert.Equal(t, 2, len(badRequest.GetFieldViolations()))
+	assert.Equal(t, "id", badRequest.GetFieldViolations()[0].GetField())
+	assert.Equal(t, "must be 10000 or less", badRequest.GetFieldViolations()[0].GetDescription())
+	assert.Equal(t, "price", badRequest.GetFieldViolations()[1].GetField())
+
+	assert.Equal(t, 3, len(listAlbums()))
+}
+
+func Test_grpc_CreateAlbum_InvalidArgument_AcceptLanguage(t *testing.T) {
+	resetAlbums()
+	client, _, _ := setupTestGrpcServer(t)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "accept-language", "fr")
+	_, err := client.CreateAlbum(ctx, &albumpb.CreateAlbumRequest{Album: &albumpb.Album{Id: 10, Title: "T", Artist: "Black Sabbath", Price: 66.60}})
+
+	badRequest := grpcStatus.Convert(err).Details()[0].(*errdetails.BadRequest)
+	assert.Equal(t, "doit contenir au moins 2 caractères", badRequest.GetFieldViolations()[0].GetDescription())
+}
+
+func Test_grpc_SharesListenerWithRest(t *testing.T) {
+	_, _, server := setupTestGrpcServer(t)
+
+	resp, err := http.Get(server.URL + "/status")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func Test_grpc_ReflectionRegistered(t *testing.T) {
+	serviceInfo := newGrpcServer(zerolog.Nop()).GetServiceInfo()
+
+	assert.Contains(t, serviceInfo, "album.v1.AlbumService")
+	assert.Contains(t, serviceInfo, "grpc.reflection.v1alpha.ServerReflection")
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotentResponse is the first response stored for an Idempotency-Key, replayed for repeats of the same request.
+type idempotentResponse struct {
+	requestHash string
+	statusCode  int
+	contentType string
+	body        []byte
+	expires     time.Time
+	complete    bool
+}
+
+var (
+	idempotencyLock     sync.Mutex
+	idempotentResponses = map[string]*idempotentResponse{}
+	idempotencyTTL      = 24 * time.Hour
+)
+
+func resetIdempotentResponses() {
+	idempotencyLock.Lock()
+	defer idempotencyLock.Unlock()
+	idempotentResponses = map[string]*idempotentResponse{}
+}
+
+// responseCaptureWriter keeps a copy of the response body so it can be stored for replay.
+type responseCaptureWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w responseCaptureWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w responseCaptureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func hashRequest(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+	// the same body in another format, or asking for another response format, is a different request
+	hash.Write([]byte(c.ContentType() + "\n" + c.GetHeader("Accept") + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// idempotency makes POST requests carrying an Idempotency-Key header safe to retry.
+// The first response is stored for idempotencyTTL and replayed for repeats of the same request,
+// while reusing the key with a different body is rejected with 422.
+func idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		span := trace.SpanFromContext(c.Request.Context())
+		span.SetAttributes(attribute.Key("album-store.idempotency.key").String(key))
+
+		body, failed := readRequestBody(c, span)
+		if failed {
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := hashRequest(c, body)
+		storeKey := c.Request.URL.Path + " " + key
+
+		now := time.Now()
+		idempotencyLock.Lock()
+		removeExpiredResponses(now)
+		stored, found := idempotentResponses[storeKey]
+		if !found {
+			idempotentResponses[storeKey] = &idempotentResponse{requestHash: requestHash, expires: now.Add(idempotencyTTL)}
+		}
+		var replay idempotentResponse
+		if found {
+			replay = *stored
+		}
+		idempotencyLock.Unlock()
+
+		if found {
+			span.SetAttributes(attribute.Key("album-store.idempotency.replayed").Bool(replay.complete && replay.requestHash == requestHash))
+			switch {
+			case replay.requestHash != requestHash:
+				buildErrorResponse(c, span, problemIdempotencyKeyReused, fmt.Sprintf("Idempotency-Key [%s] already used for a different request", key))
+			case !replay.complete:
+				buildErrorResponse(c, span, problemIdempotencyKeyInProgress, fmt.Sprintf("Idempotency-Key [%s] request still in progress", key))
+			default:
+				telemetry.SetResponseCode(span, replay.statusCode)
+				c.Data(replay.statusCode, replay.contentType, replay.body)
+				c.Abort()
+			}
+			return
+		}
+
+		captureWriter := responseCaptureWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = captureWriter
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			// server errors, and handlers that panicked, are not stored so the client can retry with the same key
+			idempotencyLock.Lock()
+			delete(idempotentResponses, storeKey)
+			idempotencyLock.Unlock()
+		}()
+		c.Next()
+
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			return
+		}
+		idempotencyLock.Lock()
+		idempotentResponses[storeKey] = &idempotentResponse{
+			requestHash: requestHash,
+			statusCode:  c.Writer.Status(),
+			contentType: c.Writer.Header().Get("Content-Type"),
+			body:        captureWriter.body.Bytes(),
+			expires:     now.Add(idempotencyTTL),
+			complete:    true,
+		}
+		idempotencyLock.Unlock()
+		completed = true
+	}
+}
+
+// removeExpiredResponses must be called with idempotencyLock held.
+func removeExpiredResponses(now time.Time) {
+	for storeKey, stored := range idempotentResponses {
+		if now.After(stored.expires) {
+			delete(idempotentResponses, storeKey)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func Test_postAlbum_IdempotencyKey_Replay(t *testing.T) {
+	resetAlbums()
+	resetIdempotentResponses()
+	albumBody := `{"id": 10, "title": "The Ozzman Cometh", "artist": "Black Sabbath", "price": 66.60}`
+
+	testRecorder, _, router := setupTestRouter(t)
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(albumBody))
+	req.Header.Set("Idempotency-Key", "retry-1")
+	router.ServeHTTP(testRecorder, req)
+	assert.Equal(t, http.StatusCreated, testRecorder.Code)
+	firstResponse := testRecorder.Body.String()
+
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	var album model.Album
+	req = newJsonRequest(http.MethodPost, "/albums", strings.NewReader(albumBody))
+	req.Header.Set("Idempotency-Key", "retry-1")
+	router.ServeHTTP(testRecorder, req)
+	if err := json.Unmarshal(testRecorder.Body.Bytes(), &album); err != nil {
+		assert.Fail(t, "json unmarshalling fail", "Should be a valid Album ", testRecorder.Body.String())
+	}
+
+	assert.Equal(t, http.StatusCreated, testRecorder.Code)
+	assert.Equal(t, firstResponse, testRecorder.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", testRecorder.Header().Get("Content-Type"))
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "true", attributeMap["album-store.idempotency.replayed"].Emit())
+	assert.Equal(t, "retry-1", attributeMap["album-store.idempotency.key"].Emit())
+	assert.Equal(t, "201", attributeMap["album-store.response.code"].Emit())
+
+	assert.Equal(t, 10, album.ID)
+	assert.Equal(t, 4, len(listAlbums()))
+}
+
+func Test_postAlbum_IdempotencyKey_DifferentBody(t *testing.T) {
+	resetAlbums()
+	resetIdempotentResponses()
+
+	testRecorder, _, router := setupTestRouter(t)
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`{"id": 10, "title": "The Ozzman Cometh", "artist": "Black Sabbath", "price": 66.60}`))
+	req.Header.Set("Idempotency-Key", "retry-2")
+	router.ServeHTTP(testRecorder, req)
+	assert.Equal(t, http.StatusCreated, testRecorder.Code)
+
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	var serverError model.ServerError
+	req = newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`{"id": 11, "title": "Paranoid", "artist": "Black Sabbath", "price": 12.00}`))
+	req.Header.Set("Idempotency-Key", "retry-2")
+	router.ServeHTTP(testRecorder, req)
+	if err := json.Unmarshal(testRecorder.Body.Bytes(), &serverError); err != nil {
+		assert.Fail(t, "json unmarshalling fail", "Should be ServerError ", testRecorder.Body.String())
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, testRecorder.Code)
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
+
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "false", attributeMap["album-store.idempotency.replayed"].Emit())
+	assert.Equal(t, "422", attributeMap["album-store.response.code"].Emit())
+
+	assert.Equal(t, "Idempotency-Key [retry-2] already used for a different request", serverError.Message)
+	assert.Equal(t, 4, len(listAlbums()))
+}
+
+func Test_postAlbum_IdempotencyKey_Expired(t *testing.T) {
+	resetAlbums()
+	resetIdempotentResponses()
+	idempotencyTTL = time.Nanosecond
+	defer func() { idempotencyTTL = 24 * time.Hour }()
+	albumBody := `{"id": 10, "title": "The Ozzman Cometh", "artist": "Black Sabbath", "price": 66.60}`
+
+	_, _, router := setupTestRouter(t)
+	for i := 0; i < 2; i++ {
+		testRecorder := httptest.NewRecorder()
+		req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(albumBody))
+		req.Header.Set("Idempotency-Key", "retry-3")
+		router.ServeHTTP(testRecorder, req)
+		assert.Equal(t, http.StatusCreated, testRecorder.Code)
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.Equal(t, 5, len(listAlbums()))
+}
+
+func Test_postAlbum_NoIdempotencyKey_CreatesDuplicates(t *testing.T) {
+	resetAlbums()
+	albumBody := `{"id": 10, "title": "The Ozzman Cometh", "artist": "Black Sabbath", "price": 66.60}`
+
+	_, _, router := setupTestRouter(t)
+	for i := 0; i < 2; i++ {
+		testRecorder := httptest.NewRecorder()
+		router.ServeHTTP(testRecorder, newJsonRequest(http.MethodPost, "/albums", strings.NewReader(albumBody)))
+		assert.Equal(t, http.StatusCreated, testRecorder.Code)
+	}
+
+	assert.Equal(t, 5, len(listAlbums()))
+}
+
+func Test_idempotency_Handler_Panics(t *testing.T) {
+	resetIdempotentResponses()
+	router := gin.New()
+	router.Use(telemetry.Recovery(), idempotency())
+	router.POST("/albums", func(c *gin.Context) {
+		panic("album store failed")
+	})
+
+	for attempt := 0; attempt < 2; attempt++ {
+		testRecorder := httptest.NewRecorder()
+		req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`{"id": 10}`))
+		req.Header.Set("Idempotency-Key", "retry-1")
+		router.ServeHTTP(testRecorder, req)
+
+		// a retry runs the handler again rather than being told the first request is in progress
+		assert.Equal(t, http.StatusInternalServerError, testRecorder.Code, attempt)
+	}
+	assert.Empty(t, idempotentResponses)
+}
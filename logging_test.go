@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_requestLogger_TraceContext(t *testing.T) {
+	resetAlbums()
+	var logOutput bytes.Buffer
+	spanRecorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	router := setupRouter(zerolog.New(&logOutput))
+	router.Use(otelgin.Middleware("test-otel"))
+
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`{"id":10,"title":"T","artist":"Black Sabbath","price":66.6}`))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	logLines := readLogLines(t, &logOutput)
+	assert.Len(t, logLines, 2)
+	spanContext := requestSpans(spanRecorder)[0].SpanContext()
+	for _, logLine := range logLines {
+		assert.Equal(t, spanContext.TraceID().String(), logLine["trace_id"])
+		assert.Equal(t, spanContext.SpanID().String(), logLine["span_id"])
+	}
+	assert.Equal(t, "warn", logLines[0]["level"])
+	assert.Equal(t, "Album JSON field validation failed", logLines[0]["message"])
+	assert.Equal(t, "info", logLines[1]["level"])
+	assert.Equal(t, "request handled", logLines[1]["message"])
+	assert.Equal(t, "/albums", logLines[1]["route"])
+	assert.Equal(t, float64(http.StatusBadRequest), logLines[1]["status"])
+}
+
+// readLogLines reads the JSON log lines written to output.
+func readLogLines(t *testing.T, output *bytes.Buffer) []map[string]interface{} {
+	var logLines []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(output.Bytes()), []byte("\n")) {
+		var logLine map[string]interface{}
+		assert.NoError(t, json.Unmarshal(line, &logLine))
+		logLines = append(logLines, logLine)
+	}
+	return logLines
+}
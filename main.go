@@ -5,15 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
-	"io"
 	"net/http"
 	"os"
 	"os/signal"
-	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -23,40 +23,43 @@ import (
 	"github.com/gin-gonic/gin/binding"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
-	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	"go.opentelemetry.io/otel/semconv/v1.17.0"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 
 	"github.com/gin-gonic/gin"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
-	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
-var albums = []model.Album{
-	{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 56.99},
-	{ID: 2, Title: "Jeru", Artist: "Gerry Mulligan", Price: 17.99},
-	{ID: 3, Title: "Sarah Vaughan and Clifford Brown", Artist: "Sarah Vaughan", Price: 39.99},
-}
+var albums = append([]model.Album{}, seedAlbums...)
+
+// albumsLock guards albums, handlers and the catalog size gauge read it while albums are added.
+var albumsLock sync.RWMutex
 
+// listAlbums returns the albums in the catalog. Albums are only appended, so the returned slice stays valid while more are added.
 func listAlbums() []model.Album {
+	albumsLock.RLock()
+	defer albumsLock.RUnlock()
 	return albums
 }
 
 func resetAlbums() {
-	albums = []model.Album{
-		{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 56.99},
-		{ID: 2, Title: "Jeru", Artist: "Gerry Mulligan", Price: 17.99},
-		{ID: 3, Title: "Sarah Vaughan and Clifford Brown", Artist: "Sarah Vaughan", Price: 39.99},
-	}
+	albumsLock.Lock()
+	defer albumsLock.Unlock()
+	albums = append([]model.Album{}, seedAlbums...)
+}
+
+// addAlbum adds album to the catalog and records it in the catalog metrics.
+func addAlbum(ctx context.Context, album model.Album) {
+	ctx, stage := telemetry.StartStage(ctx, "store album")
+	defer telemetry.EndStage(stage, false)
+	albumsLock.Lock()
+	albums = append(albums, album)
+	albumsLock.Unlock()
+	catalog.albumCreated(ctx, album)
 }
 
 // @title           Album Store API
@@ -66,22 +69,31 @@ func resetAlbums() {
 // @license.url   http://www.apache.org/licenses/LICENSE-2.0.html
 // @host      localhost:9080
 // @BasePath /
+// @schemes http
 
 // GetAlbums godoc
 // @Summary Get all Albums
 // @Schemes
 // @Description get all the albums in the store
 // @Tags albums
-// @Produce json
+// @Produce json,application/xml,application/x-yaml,application/msgpack,application/x-protobuf
 // @Success 200 {array} model.Album
+// @Failure 406 {object} model.ServerError
+// @Deprecated
+// @Header 200,201 {string} Deprecation "date v1 was deprecated, @ followed by Unix seconds"
+// @Header 200,201 {string} Sunset "date v1 is removed"
+// @Header 200,201 {string} Link "the resource in v2, rel=successor-version"
 // @Router /albums [get]
+// @Router /v1/albums [get]
 func getAlbums(c *gin.Context) {
 	span := trace.SpanFromContext(c.Request.Context())
-	span.SetName("/albums GET")
-	defer span.End()
+	format, failed := negotiateAlbumFormat(c, span)
+	if failed {
+		return
+	}
 	span.SetStatus(codes.Ok, "")
-	span.SetAttributes(attribute.Key("album-store.response.code").Int(http.StatusOK))
-	c.JSON(http.StatusOK, albums)
+	telemetry.SetResponseCode(span, http.StatusOK)
+	renderAlbums(c, http.StatusOK, format, findAlbums(c.Request.Context()))
 }
 
 // GetAlbumById godoc
@@ -89,23 +101,32 @@ func getAlbums(c *gin.Context) {
 // @Schemes
 // @Description get as single album by id
 // @Tags albums
-// @Param  id query int true  "int valid" minimum(1)
-// @Produce json
+// @Param  id path int true  "album id" minimum(1)
+// @Produce json,application/xml,application/x-yaml,application/msgpack,application/x-protobuf
 // @Success 200 {object} model.Album
 // @Failure 400 {object} model.ServerError
+// @Failure 404 {object} model.Problem
+// @Failure 406 {object} model.ServerError
+// @Deprecated
+// @Header 200,201 {string} Deprecation "date v1 was deprecated, @ followed by Unix seconds"
+// @Header 200,201 {string} Sunset "date v1 is removed"
+// @Header 200,201 {string} Link "the resource in v2, rel=successor-version"
 // @Router /albums/{id} [get]
+// @Router /v1/albums/{id} [get]
 func getAlbumByID(c *gin.Context) {
 	span := trace.SpanFromContext(c.Request.Context())
-	span.SetName("/albums/:id GET")
-	defer span.End()
+	format, failed := negotiateAlbumFormat(c, span)
+	if failed {
+		return
+	}
 	id := c.Param("id")
-	span.SetAttributes(attribute.Key("album-store.request.parameters").String(fmt.Sprintf("%s=%s", "ID", id)))
+	telemetry.SetRequestParameters(span, fmt.Sprintf("%s=%s", "ID", id))
 
 	albumId, err := strconv.Atoi(id)
 	if bindJsonToModelFails(c, err, id, span) {
 		return
 	}
-	findAlbum(c, albumId, span)
+	findAlbum(c, albumId, span, format)
 }
 
 // PostAlbum godoc
@@ -114,30 +135,59 @@ func getAlbumByID(c *gin.Context) {
 // @Description add a new album to the store
 // @Tags albums
 // @Param request body model.Album true "album"
-// @Accept json
-// @Produce json
+// @Param Idempotency-Key header string false "replay the first response for retries of the same request"
+// @Accept json,application/xml,application/x-yaml,application/msgpack,application/x-protobuf
+// @Produce json,application/xml,application/x-yaml,application/msgpack,application/x-protobuf
 // @Success 201 {object} model.Album
 // @Failure 400 {object} model.ServerError
+// @Failure 406 {object} model.ServerError
+// @Failure 409 {object} model.ServerError
+// @Failure 413 {object} model.ServerError
+// @Failure 415 {object} model.ServerError
+// @Failure 422 {object} model.ServerError
+// @Deprecated
+// @Header 200,201 {string} Deprecation "date v1 was deprecated, @ followed by Unix seconds"
+// @Header 200,201 {string} Sunset "date v1 is removed"
+// @Header 200,201 {string} Link "the resource in v2, rel=successor-version"
 // @Router /albums [post]
-func postAlbum(log zerolog.Logger) gin.HandlerFunc {
-	fn := func(context *gin.Context) {
-		span := trace.SpanFromContext(context.Request.Context())
-		span.SetName("/albums POST")
-		defer span.End()
-		//c.ShouldBindBodyWith() // the old way to get the JSON body and did get body and bind
-		requestBodyString, errBody := getRequestBody(context, span)
-		if errBody {
-			return
-		}
-		hasError, albumValue := bindJsonBody(context, span, requestBodyString, log)
+// @Router /v1/albums [post]
+func postAlbum(context *gin.Context) {
+	span := trace.SpanFromContext(context.Request.Context())
+	requestFormat, failed := requestAlbumFormat(context, span)
+	if failed {
+		return
+	}
+	responseFormat, failed := negotiateAlbumFormat(context, span)
+	if failed {
+		return
+	}
+	if requestFormat.name != jsonAlbumFormat.name {
+		_, stage := telemetry.StartStage(context.Request.Context(), "parse request body")
+		requestBodyString, hasError, albumValue := bindAlbumBody(context, span, requestFormat)
+		telemetry.EndStage(stage, hasError)
 		if hasError {
 			return
 		}
-		albums = append(albums, albumValue)
-
-		buildSuccessResponse(context, span, requestBodyString, albumValue)
+		addAlbum(context.Request.Context(), albumValue)
+		buildSuccessResponse(context, span, requestBodyString, albumValue, responseFormat)
+		return
+	}
+	//c.ShouldBindBodyWith() // the old way to get the JSON body and did get body and bind
+	_, stage := telemetry.StartStage(context.Request.Context(), "parse request body")
+	requestBodyString, errBody := getRequestBody(context, span)
+	telemetry.EndStage(stage, errBody)
+	if errBody {
+		return
 	}
-	return fn
+	_, stage = telemetry.StartStage(context.Request.Context(), "validate Album")
+	hasError, albumValue := bindJsonBody(context, span, requestBodyString)
+	telemetry.EndStage(stage, hasError)
+	if hasError {
+		return
+	}
+	addAlbum(context.Request.Context(), albumValue)
+
+	buildSuccessResponse(context, span, requestBodyString, albumValue, responseFormat)
 }
 
 // Status godoc
@@ -146,13 +196,11 @@ func postAlbum(log zerolog.Logger) gin.HandlerFunc {
 // @Description get the status of the service
 // @Tags albums
 // @Produce json
-// @Success 200 {string} status
+// @Success 200 {object} map[string]string
 // @Router /status [get]
 func status(c *gin.Context) {
 	span := trace.SpanFromContext(c.Request.Context())
-	span.SetName("/status")
 	span.SetStatus(codes.Ok, "")
-	defer span.End()
 	c.JSON(http.StatusOK, gin.H{"status": "OK"})
 }
 
@@ -163,43 +211,54 @@ func status(c *gin.Context) {
 // @Tags albums
 // @Produce plain
 // @Success 200 {string} metrics
-// @Router /status [get]
+// @Router /metrics [get]
 func metrics(c *gin.Context) {
 	span := trace.SpanFromContext(c.Request.Context())
-	span.SetName("/metrics")
 	span.SetStatus(codes.Ok, "")
-	defer span.End()
 	promhttp.Handler().ServeHTTP(c.Writer, c.Request)
 }
 
-func findAlbum(c *gin.Context, albumId int, span trace.Span) {
-	for _, album := range albums {
+func albumByID(albumId int) (model.Album, bool) {
+	for _, album := range listAlbums() {
 		if album.ID == albumId {
-			span.SetStatus(codes.Ok, "")
-			span.SetAttributes(attribute.Key("album-store.response.code").Int(http.StatusOK))
-			jsonVal, _ := json.Marshal(album)
-			span.SetAttributes(attribute.Key("album-store.response.body").String(string(jsonVal)))
-			c.JSON(http.StatusOK, album)
-			return
+			return album, true
 		}
 	}
-	errorMessage := fmt.Sprintf("Album [%v] not found", albumId)
-	serverError := model.ServerError{Message: errorMessage}
-	span.SetStatus(codes.Error, serverError.Message)
-	span.AddEvent(errorMessage)
-	span.SetAttributes(attribute.Key("album-store.response.code").Int(http.StatusBadRequest))
-	c.AbortWithStatusJSON(http.StatusBadRequest, serverError)
+	return model.Album{}, false
+}
+
+// findAlbums lists the albums in a "list albums" stage.
+func findAlbums(ctx context.Context) []model.Album {
+	_, stage := telemetry.StartStage(ctx, "list albums")
+	defer telemetry.EndStage(stage, false)
+	return listAlbums()
+}
+
+// findAlbumByID looks the album up in a "find album" stage, failed when there is no album with the id.
+func findAlbumByID(ctx context.Context, albumID int) (model.Album, bool) {
+	_, stage := telemetry.StartStage(ctx, "find album")
+	album, found := albumByID(albumID)
+	telemetry.EndStage(stage, !found)
+	return album, found
+}
+
+func findAlbum(c *gin.Context, albumId int, span trace.Span, format albumFormat) {
+	if album, found := findAlbumByID(c.Request.Context(), albumId); found {
+		span.SetStatus(codes.Ok, "")
+		telemetry.SetResponseCode(span, http.StatusOK)
+		jsonVal, _ := json.Marshal(album)
+		telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.response.body", string(jsonVal))
+		renderAlbums(c, http.StatusOK, format, album)
+		return
+	}
+	catalog.albumNotFound(c.Request.Context())
+	buildErrorResponse(c, span, problemAlbumByIDNotFound, fmt.Sprintf("Album [%v] not found", albumId))
 }
 
 func bindJsonToModelFails(c *gin.Context, err error, id string, span trace.Span) bool {
 	if err != nil {
-		errorMessage := fmt.Sprintf("Album [%s] not found, invalid request", id)
-		serverError := model.ServerError{Message: errorMessage}
-		span.SetStatus(codes.Error, serverError.Message)
-		span.AddEvent(errorMessage)
 		// span.RecordError(err, )// todo - figure out when to use this instead of event
-		span.SetAttributes(attribute.Key("album-store.response.code").Int(http.StatusBadRequest))
-		c.AbortWithStatusJSON(http.StatusBadRequest, serverError)
+		buildErrorResponse(c, span, problemInvalidID, fmt.Sprintf("Album [%s] not found, invalid request", id))
 		return true
 	}
 	return false
@@ -207,89 +266,205 @@ func bindJsonToModelFails(c *gin.Context, err error, id string, span trace.Span)
 
 func getRequestBody(c *gin.Context, span trace.Span) (string, bool) {
 	var requestBody interface{}
-	byteArray, err := io.ReadAll(c.Request.Body)
+	byteArray, failed := readRequestBody(c, span)
+	if failed {
+		return "", true
+	}
 	requestBodyString := string(byteArray[:])
-	if err = json.NewDecoder(strings.NewReader(requestBodyString)).Decode(&requestBody); err != nil {
+	if err := json.NewDecoder(strings.NewReader(requestBodyString)).Decode(&requestBody); err != nil {
 		buildMalformedJsonErrorResponse(c, span, err, requestBodyString)
 		return "", true
 	}
 	return requestBodyString, false
 }
 
-func buildSuccessResponse(c *gin.Context, span trace.Span, requestBodyString string, responseAlbum model.Album) {
+func buildSuccessResponse(c *gin.Context, span trace.Span, requestBodyString string, responseAlbum model.Album, format albumFormat) {
 	span.SetStatus(codes.Ok, "")
-	span.SetAttributes(attribute.Key("album-store.request.body").String(requestBodyString))
-	span.SetAttributes(attribute.Key("album-store.response.code").Int(http.StatusCreated))
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.request.body", requestBodyString)
+	telemetry.SetResponseCode(span, http.StatusCreated)
 	jsonByteArr, _ := json.Marshal(responseAlbum)
-	span.SetAttributes(attribute.Key("album-store.response.body").String(string(jsonByteArr)))
-	c.JSON(http.StatusCreated, responseAlbum)
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.response.body", string(jsonByteArr))
+	renderAlbums(c, http.StatusCreated, format, responseAlbum)
 }
 
-func bindJsonBody(c *gin.Context, span trace.Span, requestBodyString string, log zerolog.Logger) (bool, model.Album) {
+func bindJsonBody(c *gin.Context, span trace.Span, requestBodyString string) (bool, model.Album) {
 	var album model.Album
+	if rejectInvalidFields(c, span, []byte(requestBodyString), &album, "Album") {
+		return true, album
+	}
 	if err := binding.JSON.BindBody([]byte(requestBodyString), &album); err != nil {
-		if processValidationBindingError(c, err, span, requestBodyString, log) {
-			return true, album
+		if !processValidationBindingError(c, err, span, requestBodyString, &album, "Album") {
+			buildMalformedJsonErrorResponse(c, span, err, requestBodyString)
 		}
+		return true, album
 	}
 	return false, album
 }
 
+// bindAlbumBody reads and binds an album sent in a format other than JSON.
+// Binary formats are recorded on the span as the JSON of the decoded album.
+func bindAlbumBody(c *gin.Context, span trace.Span, format albumFormat) (string, bool, model.Album) {
+	var album model.Album
+	byteArray, failed := readRequestBody(c, span)
+	if failed {
+		return "", true, album
+	}
+	err := format.decode(byteArray, &album)
+	requestBodyString := string(byteArray[:])
+	if format.binary {
+		jsonByteArr, _ := json.Marshal(album)
+		requestBodyString = string(jsonByteArr)
+	}
+	if err != nil {
+		if processValidationBindingError(c, err, span, requestBodyString, &album, "Album") {
+			return requestBodyString, true, album
+		}
+		errorMessage := fmt.Sprintf("Malformed %s. Not valid for Album", format.label)
+		span.AddEvent(fmt.Sprintf("Malformed %s. %s", format.label, err))
+		telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.request.body", requestBodyString)
+		buildErrorResponse(c, span, problemMalformedBody, errorMessage)
+		return requestBodyString, true, album
+	}
+	return requestBodyString, false, album
+}
+
+// bindRequestJson reads the request body and binds it to target, in parse and validate stages, writing the error response when it fails.
+func bindRequestJson(c *gin.Context, span trace.Span, target interface{}, modelName string) bool {
+	_, stage := telemetry.StartStage(c.Request.Context(), "parse request body")
+	failed := requireJsonContentType(c, span)
+	var byteArray []byte
+	if !failed {
+		byteArray, failed = readRequestBody(c, span)
+	}
+	telemetry.EndStage(stage, failed)
+	if failed {
+		return true
+	}
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.request.body", string(byteArray[:]))
+	_, stage = telemetry.StartStage(c.Request.Context(), "validate "+modelName)
+	failed = validateRequestJson(c, span, byteArray, target, modelName)
+	telemetry.EndStage(stage, failed)
+	return failed
+}
+
+// validateRequestJson binds body to target, writing the error response when it has unknown fields or fails validation.
+func validateRequestJson(c *gin.Context, span trace.Span, byteArray []byte, target interface{}, modelName string) bool {
+	requestBodyString := string(byteArray[:])
+	if rejectInvalidFields(c, span, byteArray, target, modelName) {
+		return true
+	}
+	if err := binding.JSON.BindBody(byteArray, target); err != nil {
+		if processValidationBindingError(c, err, span, requestBodyString, target, modelName) {
+			return true
+		}
+		errorMessage := fmt.Sprintf("Malformed JSON. Not valid for %s", modelName)
+		span.AddEvent(fmt.Sprintf("Malformed JSON. %s", err))
+		buildErrorResponse(c, span, problemMalformedBody, errorMessage)
+		return true
+	}
+	return false
+}
+
+func parseIDParam(c *gin.Context, span trace.Span, paramName string, resourceName string) (int, bool) {
+	id := c.Param(paramName)
+	telemetry.SetRequestParameters(span, fmt.Sprintf("%s=%s", paramName, id))
+	value, err := strconv.Atoi(id)
+	if err != nil {
+		buildErrorResponse(c, span, problemInvalidID, fmt.Sprintf("%s [%s] not found, invalid request", resourceName, id))
+		return 0, true
+	}
+	return value, false
+}
+
+func buildJsonResponse(c *gin.Context, span trace.Span, statusCode int, response interface{}) {
+	_, stage := telemetry.StartStage(c.Request.Context(), "serialize response")
+	defer telemetry.EndStage(stage, false)
+	span.SetStatus(codes.Ok, "")
+	telemetry.SetResponseCode(span, statusCode)
+	jsonByteArr, _ := json.Marshal(response)
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.response.body", string(jsonByteArr))
+	c.JSON(statusCode, response)
+}
+
+func buildErrorResponse(c *gin.Context, span trace.Span, problem problemType, errorMessage string) {
+	span.SetStatus(codes.Error, errorMessage)
+	span.AddEvent(errorMessage)
+	abortWithProblem(c, span, problem, errorMessage, nil)
+}
+
 func buildMalformedJsonErrorResponse(c *gin.Context, span trace.Span, err error, requestBodyJSON string) bool {
 	span.SetStatus(codes.Error, "Malformed JSON. Not valid for Album")
 	span.AddEvent(fmt.Sprintf("Malformed JSON. %s", err))
-	span.SetAttributes(attribute.Key("album-store.request.body").String(requestBodyJSON))
-	span.SetAttributes(attribute.Key("album-store.response.body").String(`{"message":"Malformed JSON. Not valid for Album"}`))
-	span.SetAttributes(attribute.Key("album-store.response.code").Int(http.StatusBadRequest))
-	c.AbortWithStatusJSON(http.StatusBadRequest, model.ServerError{Message: "Malformed JSON. Not valid for Album"})
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.request.body", requestBodyJSON)
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.response.body", `{"message":"Malformed JSON. Not valid for Album"}`)
+	abortWithProblem(c, span, problemMalformedBody, "Malformed JSON. Not valid for Album", nil)
 	return true
 }
 
-func processValidationBindingError(c *gin.Context, err error, span trace.Span, requestBodyJSON string, log zerolog.Logger) bool {
-	var newAlbum model.Album
+func processValidationBindingError(c *gin.Context, err error, span trace.Span, requestBodyJSON string, target interface{}, modelName string) bool {
 	var validationErrors validator.ValidationErrors
 	if errors.As(err, &validationErrors) {
-		bindingErrorMessages := make([]*model.BindingErrorMsg, len(validationErrors))
-		for index, fieldError := range validationErrors {
-			field, _ := reflect.TypeOf(&newAlbum).Elem().FieldByName(fieldError.Field())
-			fieldJSONName, okay := field.Tag.Lookup("json")
-			if !okay {
-				log.Fatal().Msg(fmt.Sprintf("No json type on Struct model.Album %s Expecting : `json:\"title\" ...`", fieldError.Field()))
-			}
-			bindingErrorMessages[index] = &model.BindingErrorMsg{Field: fieldJSONName, Message: getErrorMsg(fieldError)}
-		}
+		bindingErrorMessages := buildBindingErrorMessages(validationErrors, target, translatorForLanguages(c.GetHeader("Accept-Language")))
+		catalog.validationFailed(c.Request.Context(), validationErrors, bindingErrorMessages)
 		bindingErrorMessage, _ := json.Marshal(bindingErrorMessages)
-		span.SetStatus(codes.Error, "Album JSON field validation failed")
+		telemetry.RequestLogger(c).Warn().RawJSON("errors", bindingErrorMessage).Msg(fmt.Sprintf("%s JSON field validation failed", modelName))
+		span.SetStatus(codes.Error, fmt.Sprintf("%s JSON field validation failed", modelName))
 		span.AddEvent(string(bindingErrorMessage))
-		span.SetAttributes(attribute.Key("album-store.request.body").String(requestBodyJSON))
-		span.SetAttributes(attribute.Key("album-store.response.body").String(fmt.Sprintf(`{"errors":%s}`, bindingErrorMessage)))
-		span.SetAttributes(attribute.Key("album-store.response.code").Int(http.StatusBadRequest))
-		c.AbortWithStatusJSON(http.StatusBadRequest, model.ServerError{BindingErrors: bindingErrorMessages})
+		telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.request.body", requestBodyJSON)
+		telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.response.body", fmt.Sprintf(`{"errors":%s}`, bindingErrorMessage))
+		abortWithProblem(c, span, problemValidationFailed, fmt.Sprintf("%s JSON field validation failed", modelName), bindingErrorMessages)
 		return true
 	}
 	return false
 }
 
-func getErrorMsg(fe validator.FieldError) string {
-	switch fe.Tag() {
-	case "required":
-		return "required field"
-	case "min":
-		return "below minimum value"
-	case "max":
-		return "above maximum value"
-	default:
-		return fmt.Sprintf("Unknown Error %s", fe.Tag())
+// buildBindingErrorMessages converts validator errors on target into messages keyed by the JSON field name, in the translator's language.
+func buildBindingErrorMessages(validationErrors validator.ValidationErrors, target interface{}, translator ut.Translator) []*model.BindingErrorMsg {
+	metadata, okay := metadataFor(target)
+	bindingErrorMessages := make([]*model.BindingErrorMsg, len(validationErrors))
+	for index, fieldError := range validationErrors {
+		fieldJSONName := fieldError.Field()
+		if okay {
+			fieldJSONName = metadata.jsonName(fieldError.StructField())
+		}
+		bindingErrorMessages[index] = &model.BindingErrorMsg{Field: fieldJSONName, Message: validationMessage(fieldError, translator)}
 	}
+	return bindingErrorMessages
 }
 
 func setupRouter(log zerolog.Logger) *gin.Engine {
-	router := gin.Default()
-	router.Use(otelgin.Middleware(serviceName)) // add OpenTelemetry to Gin
+	router := gin.New()
+	// gin.New rather than gin.Default, whose logger and recovery are not correlated with the request trace
+	router.Use(telemetry.Recovery())
+	router.Use(otelgin.Middleware(serviceName, otelgin.WithTracerProvider(telemetry.TracerProvider()))) // add OpenTelemetry to Gin
+	router.Use(telemetry.HttpSemconv())
+	router.Use(telemetry.RequestMetrics(apiVersionLabel))
+	router.Use(telemetry.RequestLogging(log))
+	router.Use(telemetry.BodyCaptureDecision())
+	router.Use(limitRequestBody())
+	router.Use(openAPIValidation())
+	router.Use(idempotency())
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	router.GET("/albums", getAlbums)
-	router.GET("/albums/:id", getAlbumByID)
-	router.POST("/albums", postAlbum(log))
+	router.GET("/v3/api-docs", getOpenAPIDocument)
+	// unversioned album paths answer in the version negotiated with Accept, /v1 and /v2 in their own
+	versioning := apiVersioning()
+	for _, group := range []*gin.RouterGroup{router.Group("", versioning), router.Group("/v1", versioning), router.Group("/v2", versioning)} {
+		group.GET("/albums", versioned(getAlbums, getAlbumsV2))
+		group.GET("/albums/:id", versioned(getAlbumByID, getAlbumByIDV2))
+		group.POST("/albums", versioned(postAlbum, postAlbumV2))
+	}
+	router.GET("/schemas/album", getAlbumSchema)
+	router.POST("/carts", createCart)
+	router.GET("/carts/:id", getCartByID)
+	router.POST("/carts/:id/lines", addCartLine)
+	router.DELETE("/carts/:id/lines/:albumId", removeCartLine)
+	router.POST("/orders", postOrder)
+	router.GET("/orders/:id", getOrderByID)
+	router.PATCH("/orders/:id", patchOrder)
+	router.POST("/graphql", postGraphql(log))
+	if gin.IsDebugging() {
+		router.GET("/graphql", getGraphiql)
+	}
 	router.GET("/status", status)
 	router.GET("/metrics", metrics)
 	return router
@@ -300,27 +475,92 @@ const (
 	startAddress = "0.0.0.0:9080"
 )
 
+func init() {
+	telemetry.ServiceName = serviceName
+}
+
 var version = "No-Version"
 var gitHash = "No-Hash"
 
 func main() {
-	logError := zerolog.New(os.Stderr).With().Timestamp().Logger()
-	logInfo := zerolog.New(os.Stdout).With().Timestamp().Logger()
+	logError := telemetry.NewLogger(os.Stderr)
+	logInfo := telemetry.NewLogger(os.Stdout)
+
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		if err := runGenerate(os.Args[2:], os.Stdout); err != nil {
+			logError.Fatal().Err(err).Msg("generate failed")
+		}
+		return
+	}
 
 	logInfo.Info().Msg(fmt.Sprintf("version: %v-%v", version, gitHash))
-	shutdownTraceProvider, err := initOtelProvider(serviceName, version, gitHash, logInfo)
+	if seedFile := os.Getenv("SEED_FILE"); seedFile != "" {
+		seeds, err := loadSeedAlbums(seedFile)
+		if err != nil {
+			logError.Fatal().Err(err).Msg("seed albums failed to load")
+		}
+		seedAlbums = seeds
+		resetAlbums()
+		logInfo.Info().Msg(fmt.Sprintf("loaded %v seed albums from %v", len(seedAlbums), seedFile))
+	}
+	telemetry.SpanConvention = telemetry.AttributeConventionFromEnv()
+	logInfo.Info().Msg(fmt.Sprintf("span attribute convention: %v", telemetry.SpanConvention))
+	shutdownOtelProviders, err := telemetry.InitOtelProvider(version, gitHash, logInfo)
 	if err != nil {
 		logError.Fatal().Err(err)
 	}
+	// loggers from here on also export over OTLP when OTEL_LOGS_EXPORTER=otlp
+	logError = telemetry.NewLogger(os.Stderr)
+	logInfo = telemetry.NewLogger(os.Stdout)
+	if telemetry.BodyCapture, err = telemetry.BodyCapturePolicyFromEnv(); err != nil {
+		logError.Fatal().Msg(fmt.Sprintf("Env variable %v", err))
+	}
+	logInfo.Info().Msg(fmt.Sprintf("request and response bodies on spans: %v", telemetry.BodyCapture))
 
+	if idempotencyTTLEnv := os.Getenv("IDEMPOTENCY_TTL"); idempotencyTTLEnv != "" {
+		idempotencyTTL, err = time.ParseDuration(idempotencyTTLEnv)
+		if err != nil {
+			logError.Fatal().Msg(fmt.Sprintf("Env variable IDEMPOTENCY_TTL=%v is not a valid duration", idempotencyTTLEnv))
+		}
+	}
+	logInfo.Info().Msg(fmt.Sprintf("Idempotency-Key responses kept for %v", idempotencyTTL))
+
+	if maxBodyBytesEnv := os.Getenv("MAX_BODY_BYTES"); maxBodyBytesEnv != "" {
+		maxRequestBodyBytes, err = strconv.ParseInt(maxBodyBytesEnv, 10, 64)
+		if err != nil || maxRequestBodyBytes < 1 {
+			logError.Fatal().Msg(fmt.Sprintf("Env variable MAX_BODY_BYTES=%v is not a positive number of bytes", maxBodyBytesEnv))
+		}
+	}
+	logInfo.Info().Msg(fmt.Sprintf("request bodies limited to %d bytes", maxRequestBodyBytes))
+
+	if err := setDeprecationDates("v1", os.Getenv("API_V1_DEPRECATION"), os.Getenv("API_V1_SUNSET")); err != nil {
+		logError.Fatal().Msg(fmt.Sprintf("Env variable API_V1_DEPRECATION or API_V1_SUNSET: %v", err))
+	}
+	logInfo.Info().Msg(fmt.Sprintf("API v1 deprecated %s, sunset %s", defaultAPIVersion.deprecation.Format(time.DateOnly), defaultAPIVersion.sunset.Format(time.DateOnly)))
+
+	strictResponseValidation = os.Getenv("OPENAPI_STRICT") == "true"
+	logInfo.Info().Msg(fmt.Sprintf("responses validated against the OpenAPI document: %v", strictResponseValidation))
+
+	switch errorFormat := os.Getenv("ERROR_FORMAT"); errorFormat {
+	case "", "legacy":
+	case "problem":
+		problemDetailsErrors = true
+	default:
+		logError.Fatal().Msg(fmt.Sprintf("Env variable ERROR_FORMAT=%v must be legacy or problem", errorFormat))
+	}
+	logInfo.Info().Msg(fmt.Sprintf("errors sent as application/problem+json for all requests: %v", problemDetailsErrors))
+
+	catalog = newCatalogMetrics()
 	router := setupRouter(logInfo)
+	grpcServer := newGrpcServer(logInfo)
 	//serve requests until termination signal is sent.
+	//gRPC and REST share the h2c listener, routed by content-type
 	srv := &http.Server{
 		Addr:    startAddress,
-		Handler: h2c.NewHandler(router, &http2.Server{}),
+		Handler: h2c.NewHandler(grpcOrHttpHandler(grpcServer, router), &http2.Server{}),
 	}
 
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		// service connections
@@ -334,11 +574,11 @@ func main() {
 	ctxServer, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer cancel()
 
-	logInfo.Info().Msg("OpenTelemetry TraceProvider flushing & shutting down")
-	if err := shutdownTraceProvider(ctxServer); err != nil {
+	logInfo.Info().Msg("OpenTelemetry TraceProvider, MeterProvider & LoggerProvider flushing & shutting down")
+	if err := shutdownOtelProviders(ctxServer); err != nil {
 		logError.Fatal().Err(err)
 	}
-	logInfo.Info().Msg("OpenTelemetry TraceProvider exited")
+	logInfo.Info().Msg("OpenTelemetry TraceProvider, MeterProvider & LoggerProvider exited")
 
 	if err := srv.Shutdown(ctxServer); err != nil {
 		logError.Fatal().Err(err)
@@ -347,73 +587,3 @@ func main() {
 
 	logInfo.Info().Msg("Server exiting")
 }
-
-// Set up the context for this Application in Open Telemetry
-// application name, application version, k8s namespace , k8s instance name (horizontal scaling)
-func setupOtelResource(serviceName string, version string, gitHash string, ctx context.Context, namespace *string, instanceName *string) (*resource.Resource, error) {
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceNameKey.String(serviceName),
-			semconv.ServiceVersionKey.String(version+"-"+gitHash),
-			semconv.ServiceNamespaceKey.String(*namespace),
-			semconv.ServiceInstanceIDKey.String(*instanceName),
-		),
-	)
-	return res, err
-}
-
-// InitOtelProvider - Initializes an OTLP exporter, and configures the corresponding trace and metric providers.
-func initOtelProvider(serviceName string, version string, gitHash string, log zerolog.Logger) (func(context.Context) error, error) {
-	ctx := context.Background()
-
-	namespace := os.Getenv("NAMESPACE")
-	instanceName := os.Getenv("INSTANCE_NAME")
-	otelLocation := os.Getenv("OTEL_LOCATION")
-	if instanceName == "" || otelLocation == "" || namespace == "" {
-		log.Fatal().Msg(fmt.Sprintf("Env variables not assigned NAMESPACE=%v, INSTANCE_NAME=%v, OTEL_LOCATION=%v", namespace, instanceName, otelLocation))
-	}
-
-	otelResource, err := setupOtelResource(serviceName, version, gitHash, ctx, &namespace, &instanceName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
-	}
-
-	otelTraceExporter, err := setupOtelHttpTrace(ctx, &otelLocation)
-	if err != nil {
-		return nil, err
-	}
-
-	traceProvider := setupOtelTraceProvider(otelTraceExporter, otelResource)
-	return traceProvider.Shutdown, nil //return shutdown signal so the application can trigger shutting itself down
-}
-
-func setupOtelTraceProvider(traceExporter *otlptrace.Exporter, otelResource *resource.Resource) *sdktrace.TracerProvider {
-	// Register the trace exporter with a TracerProvider, using a batch span processor to aggregate spans before export.
-	batchSpanProcessor := sdktrace.NewBatchSpanProcessor(traceExporter)
-	tracerProvider := sdktrace.NewTracerProvider(
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
-		sdktrace.WithResource(otelResource),
-		sdktrace.WithSpanProcessor(batchSpanProcessor),
-	)
-	otel.SetTracerProvider(tracerProvider)
-	otel.SetTextMapPropagator(propagation.TraceContext{}) // set global propagator to tracecontext (the default is no-op).
-	return tracerProvider
-}
-
-func setupOtelHttpTrace(ctx context.Context, otelLocation *string) (*otlptrace.Exporter, error) {
-	// insecure transport here DO NOT USE IN PROD
-	client := otlptracehttp.NewClient(
-		otlptracehttp.WithInsecure(),
-		otlptracehttp.WithEndpoint(*otelLocation),
-		otlptracehttp.WithCompression(otlptracehttp.GzipCompression),
-	)
-	err := client.Start(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to start http client: %w", err)
-	}
-	traceExporter, err := otlptrace.New(ctx, client)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
-	}
-	return traceExporter, nil
-}
@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -34,16 +37,46 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
-func setupTestRouter() (*httptest.ResponseRecorder, *tracetest.SpanRecorder, *gin.Engine) {
+func setupTestRouter(t testing.TB) (*httptest.ResponseRecorder, *tracetest.SpanRecorder, *gin.Engine) {
 	logInfo := zerolog.New(os.Stdout).With().Timestamp().Logger()
 	spanRecorder := tracetest.NewSpanRecorder()
 	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	// dual-emit so the tests cover the album-store.* and the HTTP semantic convention keys
+	spanConvention := telemetry.SpanConvention
+	t.Cleanup(func() { telemetry.SpanConvention = spanConvention })
+	telemetry.SpanConvention = telemetry.DualConvention
 	router := setupRouter(logInfo)
 	testRecorder := httptest.NewRecorder()
 	router.Use(otelgin.Middleware("test-otel"))
 	return testRecorder, spanRecorder, router
 }
 
+// newJsonRequest is a test request with a body sent as application/json.
+func newJsonRequest(method string, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// requestSpans are the ended server spans, leaving out the stage spans of the handlers.
+func requestSpans(spanRecorder *tracetest.SpanRecorder) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range spanRecorder.Ended() {
+		if span.SpanKind() == trace.SpanKindServer {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, len(spans))
+	for index, span := range spans {
+		names[index] = span.Name()
+	}
+	return names
+}
+
 func makeKeyMap(attributes []attribute.KeyValue) map[attribute.Key]attribute.Value {
 	var attributeMap = make(map[attribute.Key]attribute.Value)
 	for _, keyValue := range attributes {
@@ -53,7 +86,7 @@ func makeKeyMap(attributes []attribute.KeyValue) map[attribute.Key]attribute.Val
 }
 
 func Test_getAllAlbums(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 
 	var albums []model.Album
 
@@ -65,7 +98,7 @@ func Test_getAllAlbums(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Ok, finishedSpans[0].Status().Code)
@@ -75,12 +108,13 @@ func Test_getAllAlbums(t *testing.T) {
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "200", attributeMap["album-store.response.code"].Emit())
+	assert.Equal(t, "200", attributeMap["http.response.status_code"].Emit())
 
 	assert.Equal(t, listAlbums(), albums)
 }
 
 func Test_getAlbumById(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 
 	var album model.Album
 
@@ -92,7 +126,7 @@ func Test_getAlbumById(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Ok, finishedSpans[0].Status().Code)
@@ -102,6 +136,10 @@ func Test_getAlbumById(t *testing.T) {
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "200", attributeMap["album-store.response.code"].Emit())
+	assert.Equal(t, "200", attributeMap["http.response.status_code"].Emit())
+	assert.Equal(t, "ID=2", attributeMap["album-store.request.parameters"].Emit())
+	assert.Equal(t, "/albums/2", attributeMap["url.path"].Emit())
+	assert.Equal(t, "GET", attributeMap["http.request.method"].Emit())
 	assert.Equal(t, `{"id":2,"title":"Jeru","artist":"Gerry Mulligan","price":17.99}`, attributeMap["album-store.response.body"].Emit())
 
 	assert.Equal(t, listAlbums()[1], album)
@@ -109,7 +147,7 @@ func Test_getAlbumById(t *testing.T) {
 }
 
 func Test_getAlbumById_InvalidID_Character(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 
 	var serverError model.ServerError
 
@@ -121,7 +159,7 @@ func Test_getAlbumById_InvalidID_Character(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	expectedErrorMessage := "Album [X] not found, invalid request"
@@ -134,12 +172,13 @@ func Test_getAlbumById_InvalidID_Character(t *testing.T) {
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "400", attributeMap["album-store.response.code"].Emit())
+	assert.Equal(t, "400", attributeMap["http.response.status_code"].Emit())
 
 	assert.Equal(t, expectedErrorMessage, serverError.Message)
 }
 
 func Test_getAlbumById_NotFound(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 
 	var serverError model.ServerError
 	invalidAlbumID := -1666
@@ -152,7 +191,7 @@ func Test_getAlbumById_NotFound(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	expectedErrorMessage := fmt.Sprintf("Album [%v] not found", invalidAlbumID)
@@ -165,6 +204,7 @@ func Test_getAlbumById_NotFound(t *testing.T) {
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "400", attributeMap["album-store.response.code"].Emit())
+	assert.Equal(t, "400", attributeMap["http.response.status_code"].Emit())
 
 	assert.Equal(t, expectedErrorMessage, serverError.Message)
 }
@@ -172,13 +212,13 @@ func Test_getAlbumById_NotFound(t *testing.T) {
 func Test_postAlbum(t *testing.T) {
 	resetAlbums()
 
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	var album model.Album
 
 	expectedAlbum := model.Album{ID: 10, Title: "The Ozzman Cometh", Artist: "Black Sabbath", Price: 66.60}
 	albumBody := `{"id": 10, "title": "The Ozzman Cometh", "artist": "Black Sabbath", "price": 66.60}`
 
-	req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader(albumBody))
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(albumBody))
 	router.ServeHTTP(testRecorder, req)
 	if err := json.Unmarshal(testRecorder.Body.Bytes(), &album); err != nil {
 		assert.Fail(t, "json unmarshalling fail", "Should be a valid Album ", testRecorder.Body.String())
@@ -186,7 +226,7 @@ func Test_postAlbum(t *testing.T) {
 
 	assert.Equal(t, http.StatusCreated, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Ok, finishedSpans[0].Status().Code)
@@ -198,20 +238,73 @@ func Test_postAlbum(t *testing.T) {
 	assert.Equal(t, `{"id": 10, "title": "The Ozzman Cometh", "artist": "Black Sabbath", "price": 66.60}`, attributeMap["album-store.request.body"].Emit())
 	assert.Equal(t, `{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}`, attributeMap["album-store.response.body"].Emit())
 	assert.Equal(t, "201", attributeMap["album-store.response.code"].Emit())
+	assert.Equal(t, "201", attributeMap["http.response.status_code"].Emit())
 
 	assert.Equal(t, album, expectedAlbum)
 	assert.Equal(t, len(listAlbums()), 4)
 }
 
+func Test_postAlbum_Stage_Spans(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	albumBody := `{"id": 10, "title": "The Ozzman Cometh", "artist": "Black Sabbath", "price": 66.60}`
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(albumBody))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusCreated, testRecorder.Code)
+	finishedSpans := spanRecorder.Ended()
+	assert.Equal(t, []string{"parse request body", "validate Album", "store album", "serialize response", "/albums"}, spanNames(finishedSpans))
+	requestSpan := finishedSpans[4]
+	for _, stage := range finishedSpans[:4] {
+		assert.Equal(t, requestSpan.SpanContext().SpanID(), stage.Parent().SpanID(), stage.Name())
+		assert.Equal(t, codes.Ok, stage.Status().Code, stage.Name())
+		assert.False(t, stage.EndTime().After(requestSpan.EndTime()), stage.Name())
+	}
+	// otelgin ends the request span, after recording the status code written
+	attributeMap := makeKeyMap(requestSpan.Attributes())
+	assert.Equal(t, "/albums", attributeMap["http.route"].Emit())
+	assert.Equal(t, "201", attributeMap["http.status_code"].Emit())
+}
+
+func Test_getAlbums_Stage_Spans(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	finishedSpans := spanRecorder.Ended()
+	assert.Equal(t, []string{"list albums", "serialize response", "/albums"}, spanNames(finishedSpans))
+	for _, stage := range finishedSpans[:2] {
+		assert.Equal(t, finishedSpans[2].SpanContext().SpanID(), stage.Parent().SpanID(), stage.Name())
+		assert.Equal(t, codes.Ok, stage.Status().Code, stage.Name())
+	}
+}
+
+func Test_getAlbumById_NotFound_Stage_Spans(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/666", nil)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	finishedSpans := spanRecorder.Ended()
+	assert.Equal(t, []string{"find album", "/albums/:id"}, spanNames(finishedSpans))
+	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
+	assert.Equal(t, codes.Error, finishedSpans[1].Status().Code)
+}
+
 func Test_postAlbum_BadRequest_BadJSON_MissingValues(t *testing.T) {
 	resetAlbums()
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 
 	var serverError model.ServerError
-	album := `{"xid": 10, "titlex": "Blue Train", "artistx": "Lead Belly", "pricex": 56.99, "X": "asdf"}`
-	bindingErrorMessage := `[{"field":"id","message":"below minimum value"},{"field":"title","message":"required field"},{"field":"artist","message":"required field"},{"field":"price","message":"required field"}]`
+	album := `{}`
+	bindingErrorMessage := `[{"field":"id","message":"must be 1 or greater"},{"field":"title","message":"is required"},{"field":"artist","message":"is required"},{"field":"price","message":"is required"}]`
 
-	req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader(album))
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(album))
 	router.ServeHTTP(testRecorder, req)
 	if err := json.Unmarshal(testRecorder.Body.Bytes(), &serverError); err != nil {
 		var ve validator.ValidationErrors
@@ -221,7 +314,7 @@ func Test_postAlbum_BadRequest_BadJSON_MissingValues(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
@@ -232,30 +325,30 @@ func Test_postAlbum_BadRequest_BadJSON_MissingValues(t *testing.T) {
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "400", attributeMap["album-store.response.code"].Emit())
+	assert.Equal(t, "400", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, album, attributeMap["album-store.request.body"].Emit())
 	assert.Equal(t, fmt.Sprintf("{\"errors\":%v}", bindingErrorMessage), attributeMap["album-store.response.body"].Emit())
-	assert.Equal(t, `{"xid": 10, "titlex": "Blue Train", "artistx": "Lead Belly", "pricex": 56.99, "X": "asdf"}`, attributeMap["album-store.request.body"].Emit())
 
 	assert.Equal(t, 4, len(serverError.BindingErrors))
 	assert.Equal(t, "title", serverError.BindingErrors[1].Field)
-	assert.Equal(t, "required field", serverError.BindingErrors[1].Message)
+	assert.Equal(t, "is required", serverError.BindingErrors[1].Message)
 	assert.Equal(t, "artist", serverError.BindingErrors[2].Field)
-	assert.Equal(t, "required field", serverError.BindingErrors[2].Message)
+	assert.Equal(t, "is required", serverError.BindingErrors[2].Message)
 	assert.Equal(t, "price", serverError.BindingErrors[3].Field)
-	assert.Equal(t, "required field", serverError.BindingErrors[3].Message)
+	assert.Equal(t, "is required", serverError.BindingErrors[3].Message)
 
 	assert.Equal(t, len(listAlbums()), 3)
 }
 
 func Test_postAlbum_BadRequest_BadJSON_MinValues(t *testing.T) {
 	resetAlbums()
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 
 	album := `{"id": -1, "title": "a", "artist": "z", "price": -0.1}`
-	bindingErrorMessage := `[{"field":"id","message":"below minimum value"},{"field":"title","message":"below minimum value"},{"field":"artist","message":"below minimum value"},{"field":"price","message":"below minimum value"}]`
+	bindingErrorMessage := `[{"field":"id","message":"must be 1 or greater"},{"field":"title","message":"must be at least 2 characters"},{"field":"artist","message":"must be at least 2 characters"},{"field":"price","message":"must be 0.0 or greater"}]`
 	var serverError model.ServerError
 
-	req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader(album))
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(album))
 	router.ServeHTTP(testRecorder, req)
 	if err := json.Unmarshal(testRecorder.Body.Bytes(), &serverError); err != nil {
 		var ve validator.ValidationErrors
@@ -265,7 +358,7 @@ func Test_postAlbum_BadRequest_BadJSON_MinValues(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
@@ -276,31 +369,32 @@ func Test_postAlbum_BadRequest_BadJSON_MinValues(t *testing.T) {
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "400", attributeMap["album-store.response.code"].Emit())
+	assert.Equal(t, "400", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, album, attributeMap["album-store.request.body"].Emit())
 	assert.Equal(t, fmt.Sprintf("{\"errors\":%v}", bindingErrorMessage), attributeMap["album-store.response.body"].Emit())
 
 	assert.Equal(t, 4, len(serverError.BindingErrors))
 	assert.Equal(t, "id", serverError.BindingErrors[0].Field)
-	assert.Equal(t, "below minimum value", serverError.BindingErrors[0].Message)
+	assert.Equal(t, "must be 1 or greater", serverError.BindingErrors[0].Message)
 	assert.Equal(t, "title", serverError.BindingErrors[1].Field)
-	assert.Equal(t, "below minimum value", serverError.BindingErrors[1].Message)
+	assert.Equal(t, "must be at least 2 characters", serverError.BindingErrors[1].Message)
 	assert.Equal(t, "artist", serverError.BindingErrors[2].Field)
-	assert.Equal(t, "below minimum value", serverError.BindingErrors[2].Message)
+	assert.Equal(t, "must be at least 2 characters", serverError.BindingErrors[2].Message)
 	assert.Equal(t, "price", serverError.BindingErrors[3].Field)
-	assert.Equal(t, "below minimum value", serverError.BindingErrors[3].Message)
+	assert.Equal(t, "must be 0.0 or greater", serverError.BindingErrors[3].Message)
 
 	assert.Equal(t, len(listAlbums()), 3)
 }
 
 func Test_postAlbum_BadRequest_BadJSON_MaxValues(t *testing.T) {
 	resetAlbums()
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 
 	album := `{"id": 50000000, "title": "aa", "artist": "zz", "price": 20000.00}`
-	bindingErrorMessage := `[{"field":"id","message":"above maximum value"},{"field":"price","message":"above maximum value"}]`
+	bindingErrorMessage := `[{"field":"id","message":"must be 10000 or less"},{"field":"price","message":"must be 10000.00 or less"}]`
 	var serverError model.ServerError
 
-	req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader(album))
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(album))
 	router.ServeHTTP(testRecorder, req)
 	if err := json.Unmarshal(testRecorder.Body.Bytes(), &serverError); err != nil {
 		var ve validator.ValidationErrors
@@ -310,7 +404,7 @@ func Test_postAlbum_BadRequest_BadJSON_MaxValues(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
@@ -321,26 +415,27 @@ func Test_postAlbum_BadRequest_BadJSON_MaxValues(t *testing.T) {
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "400", attributeMap["album-store.response.code"].Emit())
+	assert.Equal(t, "400", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, album, attributeMap["album-store.request.body"].Emit())
 	assert.Equal(t, fmt.Sprintf("{\"errors\":%v}", bindingErrorMessage), attributeMap["album-store.response.body"].Emit())
 
 	assert.Equal(t, 2, len(serverError.BindingErrors))
 	assert.Equal(t, "id", serverError.BindingErrors[0].Field)
-	assert.Equal(t, "above maximum value", serverError.BindingErrors[0].Message)
+	assert.Equal(t, "must be 10000 or less", serverError.BindingErrors[0].Message)
 	assert.Equal(t, "price", serverError.BindingErrors[1].Field)
-	assert.Equal(t, "above maximum value", serverError.BindingErrors[1].Message)
+	assert.Equal(t, "must be 10000.00 or less", serverError.BindingErrors[1].Message)
 
 	assert.Equal(t, len(listAlbums()), 3)
 }
 
 func Test_postAlbum_BadRequest_Malformed_JSON(t *testing.T) {
 	resetAlbums()
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 
 	var serverError model.ServerError
 	requestBody := `{"id": -1,`
 
-	req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader(requestBody))
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(requestBody))
 	router.ServeHTTP(testRecorder, req)
 	if err := json.Unmarshal(testRecorder.Body.Bytes(), &serverError); err != nil {
 		assert.Fail(t, "", "should be ServerError ")
@@ -348,7 +443,7 @@ func Test_postAlbum_BadRequest_Malformed_JSON(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
@@ -359,6 +454,7 @@ func Test_postAlbum_BadRequest_Malformed_JSON(t *testing.T) {
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "400", attributeMap["album-store.response.code"].Emit())
+	assert.Equal(t, "400", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, requestBody, attributeMap["album-store.request.body"].Emit())
 	assert.Equal(t, `{"message":"Malformed JSON. Not valid for Album"}`, attributeMap["album-store.response.body"].Emit())
 
@@ -368,9 +464,23 @@ func Test_postAlbum_BadRequest_Malformed_JSON(t *testing.T) {
 	assert.Equal(t, len(listAlbums()), 3)
 }
 
+func Test_postAlbum_BadRequest_Wrong_Type(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`{"title": 1, "artist": "the artist", "price": 1.0}`))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":null,"message":"Malformed JSON. Not valid for Album"}`, testRecorder.Body.String())
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Equal(t, "Malformed JSON. Not valid for Album", finishedSpans[0].Status().Description)
+	assert.Equal(t, len(listAlbums()), 3)
+}
+
 func Test_getSwagger(t *testing.T) {
 	resetAlbums()
-	testRecorder, _, router := setupTestRouter()
+	testRecorder, _, router := setupTestRouter(t)
 
 	req := httptest.NewRequest(http.MethodGet, "/swagger/index.html", nil)
 	router.ServeHTTP(testRecorder, req)
@@ -381,7 +491,7 @@ func Test_getSwagger(t *testing.T) {
 }
 
 func Test_getStatus(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 
 	req := httptest.NewRequest(http.MethodGet, "/status", nil)
 	router.ServeHTTP(testRecorder, req)
@@ -391,7 +501,7 @@ func Test_getStatus(t *testing.T) {
 	assert.Equal(t, http.StatusOK, testRecorder.Code)
 	assert.Equal(t, `{"status":"OK"}`, responseBodyString)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Ok, finishedSpans[0].Status().Code)
@@ -401,7 +511,7 @@ func Test_getStatus(t *testing.T) {
 }
 
 func Test_getMetrics(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
 	router.ServeHTTP(testRecorder, req)
 
@@ -410,7 +520,7 @@ func Test_getMetrics(t *testing.T) {
 	assert.Equal(t, http.StatusOK, testRecorder.Code)
 	assert.Contains(t, responseBodyString, `go_gc_duration_seconds`)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Ok, finishedSpans[0].Status().Code)
@@ -420,7 +530,7 @@ func Test_getMetrics(t *testing.T) {
 }
 
 func Benchmark_getAllAlbums(b *testing.B) {
-	testRecorder, _, router := setupTestRouter()
+	testRecorder, _, router := setupTestRouter(b)
 
 	var albums []model.Album
 	req := httptest.NewRequest(http.MethodGet, "/albums", nil)
@@ -435,7 +545,7 @@ func Benchmark_getAllAlbums(b *testing.B) {
 }
 
 func Benchmark_getAlbumById(b *testing.B) {
-	testRecorder, _, router := setupTestRouter()
+	testRecorder, _, router := setupTestRouter(b)
 
 	var album model.Album
 	req := httptest.NewRequest(http.MethodGet, "/albums/2", nil)
@@ -450,7 +560,7 @@ func Benchmark_getAlbumById(b *testing.B) {
 }
 
 func Benchmark_getAlbumById_BadRequest(b *testing.B) {
-	testRecorder, _, router := setupTestRouter()
+	testRecorder, _, router := setupTestRouter(b)
 
 	var serverError model.ServerError
 	req := httptest.NewRequest(http.MethodGet, "/albums/5666", nil)
@@ -465,11 +575,11 @@ func Benchmark_getAlbumById_BadRequest(b *testing.B) {
 }
 
 func Benchmark_postAlbum(b *testing.B) {
-	testRecorder, _, router := setupTestRouter()
+	testRecorder, _, router := setupTestRouter(b)
 
 	var albumReturned model.Album
 	albumJson := `{"id": "10", "title": "The Ozzman Cometh", "artist": "Black Sabbath", "price": 56.99}`
-	req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader(albumJson))
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(albumJson))
 
 	for i := 0; i < b.N; i++ {
 		router.ServeHTTP(testRecorder, req)
@@ -481,11 +591,11 @@ func Benchmark_postAlbum(b *testing.B) {
 }
 
 func Benchmark_postAlbum_BadRequest_BadJson(b *testing.B) {
-	testRecorder, _, router := setupTestRouter()
+	testRecorder, _, router := setupTestRouter(b)
 
 	var returnedError model.ServerError
 	albumJson := `{"xid": "10", "titlex": "Blue Train", "artistx": "John Coltrane", "pricex": 56.99, "X": "asdf"}`
-	req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader(albumJson))
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(albumJson))
 
 	for i := 0; i < b.N; i++ {
 		router.ServeHTTP(testRecorder, req)
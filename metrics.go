@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+)
+
+// apiVersionLabel labels the request metrics with the API version of the request.
+func apiVersionLabel(c *gin.Context) []attribute.KeyValue {
+	return []attribute.KeyValue{attribute.Key("album-store.api.version").String(requestAPIVersion(c).name)}
+}
+
+// maxArtistLabels bounds the artist label values on albumsCreated, artists after the first maxArtistLabels are counted as otherArtist.
+const maxArtistLabels = 100
+
+const otherArtist = "other"
+
+// catalogMetrics are the domain metrics of the album catalog, a nil *catalogMetrics records nothing.
+type catalogMetrics struct {
+	albumsCreated      metric.Int64Counter
+	albumPrice         metric.Float64Histogram
+	validationFailures metric.Int64Counter
+	albumsNotFound     metric.Int64Counter
+	artistLabels       map[string]bool
+	artistLabelsMutex  sync.Mutex
+}
+
+// catalog records the catalog metrics. main creates it once after the MeterProvider is set, so its instruments and the size gauge callback are registered once.
+var catalog *catalogMetrics
+
+// newCatalogMetrics creates the catalog instruments, including the catalog size gauge observed from albums on each collection.
+func newCatalogMetrics() *catalogMetrics {
+	meter := global.Meter(serviceName)
+	metrics := &catalogMetrics{artistLabels: map[string]bool{}}
+	var err error
+	if _, err = meter.Int64ObservableGauge("album-store.catalog.size", metric.WithDescription("Albums in the catalog."),
+		metric.WithInt64Callback(func(_ context.Context, observer metric.Int64Observer) error {
+			observer.Observe(int64(len(listAlbums())))
+			return nil
+		})); err != nil {
+		otel.Handle(err)
+	}
+	if metrics.albumsCreated, err = meter.Int64Counter("album-store.albums.created", metric.WithDescription("Albums added to the catalog by artist.")); err != nil {
+		otel.Handle(err)
+	}
+	if metrics.albumPrice, err = meter.Float64Histogram("album-store.album.price", metric.WithUnit(model.AlbumCurrency), metric.WithDescription("Prices of albums added to the catalog.")); err != nil {
+		otel.Handle(err)
+	}
+	if metrics.validationFailures, err = meter.Int64Counter("album-store.validation.failures", metric.WithDescription("Fields failing validation by validation rule.")); err != nil {
+		otel.Handle(err)
+	}
+	if metrics.albumsNotFound, err = meter.Int64Counter("album-store.albums.not_found", metric.WithDescription("Album lookups by id that found no album.")); err != nil {
+		otel.Handle(err)
+	}
+	return metrics
+}
+
+// albumCreated counts album by its artist and records its price.
+func (metrics *catalogMetrics) albumCreated(ctx context.Context, album model.Album) {
+	if metrics == nil {
+		return
+	}
+	metrics.albumsCreated.Add(ctx, 1, metric.WithAttributes(attribute.Key("album-store.album.artist").String(metrics.artistLabel(album.Artist))))
+	metrics.albumPrice.Record(ctx, album.Price)
+}
+
+// artistLabel is artist while fewer than maxArtistLabels artists have been seen, otherArtist after.
+func (metrics *catalogMetrics) artistLabel(artist string) string {
+	metrics.artistLabelsMutex.Lock()
+	defer metrics.artistLabelsMutex.Unlock()
+	if metrics.artistLabels[artist] {
+		return artist
+	}
+	if len(metrics.artistLabels) >= maxArtistLabels {
+		return otherArtist
+	}
+	metrics.artistLabels[artist] = true
+	return artist
+}
+
+// validationFailed counts each field failing validation by its JSON name and the rule it failed.
+func (metrics *catalogMetrics) validationFailed(ctx context.Context, validationErrors validator.ValidationErrors, bindingErrorMessages []*model.BindingErrorMsg) {
+	if metrics == nil {
+		return
+	}
+	for index, fieldError := range validationErrors {
+		metrics.validationFailures.Add(ctx, 1, metric.WithAttributes(
+			attribute.Key("album-store.validation.field").String(bindingErrorMessages[index].Field),
+			attribute.Key("album-store.validation.rule").String(fieldError.Tag()),
+		))
+	}
+}
+
+// albumNotFound counts a lookup by id that found no album.
+func (metrics *catalogMetrics) albumNotFound(ctx context.Context) {
+	if metrics == nil {
+		return
+	}
+	metrics.albumsNotFound.Add(ctx, 1)
+}
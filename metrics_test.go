@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/albumpb"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/global"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// setupTestMeter sets a MeterProvider with a reader the test can collect from, before the router creates its instruments.
+func setupTestMeter() sdkmetric.Reader {
+	reader := sdkmetric.NewManualReader()
+	global.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	return reader
+}
+
+func collectMetric(t *testing.T, reader sdkmetric.Reader, name string) metricdata.Aggregation {
+	var resourceMetrics metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &resourceMetrics))
+	for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+		for _, metrics := range scopeMetrics.Metrics {
+			if metrics.Name == name {
+				return metrics.Data
+			}
+		}
+	}
+	t.Fatalf("metric %s not recorded", name)
+	return nil
+}
+
+func Test_requestMetrics(t *testing.T) {
+	resetAlbums()
+	reader := setupTestMeter()
+	_, _, router := setupTestRouter(t)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/albums/1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/albums/2", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v2/albums/X", nil))
+
+	requests := collectMetric(t, reader, "http.server.requests").(metricdata.Sum[int64])
+	assert.Len(t, requests.DataPoints, 2)
+	okay := attribute.NewSet(
+		attribute.Key("http.method").String(http.MethodGet),
+		attribute.Key("http.route").String("/albums/:id"),
+		attribute.Key("http.status_code").Int(http.StatusOK),
+		attribute.Key("album-store.api.version").String("v1"),
+	)
+	invalid := attribute.NewSet(
+		attribute.Key("http.method").String(http.MethodGet),
+		attribute.Key("http.route").String("/v2/albums/:id"),
+		attribute.Key("http.status_code").Int(http.StatusBadRequest),
+		attribute.Key("album-store.api.version").String("v2"),
+	)
+	for _, dataPoint := range requests.DataPoints {
+		switch {
+		case dataPoint.Attributes.Equals(&okay):
+			assert.Equal(t, int64(2), dataPoint.Value)
+		case dataPoint.Attributes.Equals(&invalid):
+			assert.Equal(t, int64(1), dataPoint.Value)
+		default:
+			assert.Fail(t, "unexpected attributes", dataPoint.Attributes.Encoded(attribute.DefaultEncoder()))
+		}
+	}
+
+	duration := collectMetric(t, reader, "http.server.duration").(metricdata.Histogram[float64])
+	assert.Len(t, duration.DataPoints, 2)
+}
+
+func Test_requestMetrics_Errors(t *testing.T) {
+	reader := setupTestMeter()
+	_, _, router := setupTestRouter(t)
+	router.GET("/failing", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/failing", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	requestErrors := collectMetric(t, reader, "http.server.errors").(metricdata.Sum[int64])
+	assert.Len(t, requestErrors.DataPoints, 1)
+	assert.Equal(t, int64(1), requestErrors.DataPoints[0].Value)
+	route, _ := requestErrors.DataPoints[0].Attributes.Value("http.route")
+	assert.Equal(t, "/failing", route.AsString())
+}
+
+func Test_catalogMetrics(t *testing.T) {
+	resetAlbums()
+	reader := setupTestMeter()
+	catalog = newCatalogMetrics()
+	t.Cleanup(func() { catalog = nil })
+	_, _, router := setupTestRouter(t)
+
+	router.ServeHTTP(httptest.NewRecorder(), newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}`)))
+	router.ServeHTTP(httptest.NewRecorder(), newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`{"id":11,"title":"T","artist":"Black Sabbath","price":20000}`)))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/albums/1666", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v2/albums/1666", nil))
+
+	size := collectMetric(t, reader, "album-store.catalog.size").(metricdata.Gauge[int64])
+	assert.Equal(t, int64(4), size.DataPoints[0].Value)
+
+	created := collectMetric(t, reader, "album-store.albums.created").(metricdata.Sum[int64])
+	assert.Len(t, created.DataPoints, 1)
+	assert.Equal(t, int64(1), created.DataPoints[0].Value)
+	artist, _ := created.DataPoints[0].Attributes.Value("album-store.album.artist")
+	assert.Equal(t, "Black Sabbath", artist.AsString())
+
+	price := collectMetric(t, reader, "album-store.album.price").(metricdata.Histogram[float64])
+	assert.Equal(t, uint64(1), price.DataPoints[0].Count)
+	assert.Equal(t, 66.6, price.DataPoints[0].Sum)
+
+	failures := collectMetric(t, reader, "album-store.validation.failures").(metricdata.Sum[int64])
+	assert.Len(t, failures.DataPoints, 2)
+	for _, dataPoint := range failures.DataPoints {
+		field, _ := dataPoint.Attributes.Value("album-store.validation.field")
+		rule, _ := dataPoint.Attributes.Value("album-store.validation.rule")
+		assert.Contains(t, []string{"title min", "price max"}, field.AsString()+" "+rule.AsString())
+	}
+
+	notFound := collectMetric(t, reader, "album-store.albums.not_found").(metricdata.Sum[int64])
+	assert.Equal(t, int64(2), notFound.DataPoints[0].Value)
+}
+
+func Test_catalogMetrics_Validation_GraphQL_And_gRPC(t *testing.T) {
+	resetAlbums()
+	reader := setupTestMeter()
+	catalog = newCatalogMetrics()
+	t.Cleanup(func() { catalog = nil })
+	client, _, server := setupTestGrpcServer(t)
+
+	resp, err := http.Post(server.URL+"/graphql", "application/json", strings.NewReader(`{"query": "mutation { createAlbum(album: {id: 10, title: \"a\", artist: \"Black Sabbath\", price: 20}) { id } }"}`))
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	_, err = client.CreateAlbum(context.Background(), &albumpb.CreateAlbumRequest{Album: &albumpb.Album{Id: 10, Title: "Paranoid", Artist: "Black Sabbath", Price: 20000.00}})
+	assert.Error(t, err)
+
+	failures := collectMetric(t, reader, "album-store.validation.failures").(metricdata.Sum[int64])
+	assert.Len(t, failures.DataPoints, 2)
+	for _, dataPoint := range failures.DataPoints {
+		field, _ := dataPoint.Attributes.Value("album-store.validation.field")
+		rule, _ := dataPoint.Attributes.Value("album-store.validation.rule")
+		assert.Contains(t, []string{"title min", "price max"}, field.AsString()+" "+rule.AsString())
+		assert.Equal(t, int64(1), dataPoint.Value)
+	}
+}
+
+func Test_catalogMetrics_Artist_Cardinality(t *testing.T) {
+	metrics := &catalogMetrics{artistLabels: map[string]bool{}}
+	for index := 0; index < maxArtistLabels; index++ {
+		assert.Equal(t, fmt.Sprintf("artist %d", index), metrics.artistLabel(fmt.Sprintf("artist %d", index)))
+	}
+
+	assert.Equal(t, otherArtist, metrics.artistLabel("one too many"))
+	assert.Equal(t, "artist 0", metrics.artistLabel("artist 0"))
+}
+
+func Test_catalogMetrics_Size_While_Adding(t *testing.T) {
+	resetAlbums()
+	reader := setupTestMeter()
+	catalog = newCatalogMetrics()
+	t.Cleanup(func() { catalog = nil })
+
+	var adding sync.WaitGroup
+	for id := 100; id < 110; id++ {
+		adding.Add(1)
+		go func(id int) {
+			defer adding.Done()
+			addAlbum(context.Background(), model.Album{ID: id, Title: "Paranoid", Artist: "Black Sabbath", Price: 20.99})
+		}(id)
+		collectMetric(t, reader, "album-store.catalog.size")
+	}
+	adding.Wait()
+
+	size := collectMetric(t, reader, "album-store.catalog.size").(metricdata.Gauge[int64])
+	assert.Equal(t, int64(len(seedAlbums)+10), size.DataPoints[0].Value)
+}
@@ -1,8 +1,32 @@
 package model
 
+import "math"
+
 type Album struct {
-	ID     int     `json:"id" binding:"min=1,max=10000"`
-	Title  string  `json:"title" binding:"required,min=2,max=1000"`
-	Artist string  `json:"artist" binding:"required,min=2,max=1000"`
-	Price  float64 `json:"price" binding:"required,min=0.0,max=10000.00"`
+	ID     int     `json:"id" yaml:"id" xml:"id" binding:"min=1,max=10000"`
+	Title  string  `json:"title" yaml:"title" xml:"title" binding:"required,min=2,max=1000"`
+	Artist string  `json:"artist" yaml:"artist" xml:"artist" binding:"required,min=2,max=1000"`
+	Price  float64 `json:"price" yaml:"price" xml:"price" binding:"required,min=0.0,max=10000.00"`
+}
+
+// AlbumCurrency is the currency album prices are held in.
+const AlbumCurrency = "USD"
+
+// AlbumV2 is the album of the v2 API, the price is in cents with its currency so it is exact.
+type AlbumV2 struct {
+	ID         int    `json:"id" binding:"min=1,max=10000"`
+	Title      string `json:"title" binding:"required,min=2,max=1000"`
+	Artist     string `json:"artist" binding:"required,min=2,max=1000"`
+	PriceCents int    `json:"priceCents" binding:"required,min=0,max=1000000"`
+	Currency   string `json:"currency" binding:"required,oneof=USD"`
+}
+
+// V2 converts the album to the v2 API model.
+func (album Album) V2() AlbumV2 {
+	return AlbumV2{ID: album.ID, Title: album.Title, Artist: album.Artist, PriceCents: int(math.Round(album.Price * 100)), Currency: AlbumCurrency}
+}
+
+// V1 converts the v2 album to the stored model.
+func (album AlbumV2) V1() Album {
+	return Album{ID: album.ID, Title: album.Title, Artist: album.Artist, Price: float64(album.PriceCents) / 100}
 }
@@ -0,0 +1,14 @@
+package model
+
+// Problem is an RFC 7807 problem details error body, sent as application/problem+json.
+// Code is the stable error catalog code clients match on, Errors carries field validation failures.
+type Problem struct {
+	Type          string             `json:"type"`
+	Title         string             `json:"title"`
+	Status        int                `json:"status"`
+	Detail        string             `json:"detail,omitempty"`
+	Instance      string             `json:"instance,omitempty"`
+	Code          string             `json:"code"`
+	TraceID       string             `json:"trace_id,omitempty"`
+	BindingErrors []*BindingErrorMsg `json:"errors,omitempty"`
+}
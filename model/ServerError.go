@@ -6,6 +6,6 @@ type BindingErrorMsg struct {
 }
 
 type ServerError struct {
-	BindingErrors []*BindingErrorMsg `json:"errors"`
+	BindingErrors []*BindingErrorMsg `json:"errors" extensions:"x-nullable"`
 	Message       string             `json:"message"`
 }
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/albumpb"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+)
+
+// albumFormat is a representation album resources can be sent and received in, chosen by the Accept and Content-Type headers.
+type albumFormat struct {
+	name      string
+	label     string
+	mimeTypes []string
+	binary    bool
+	decode    func(body []byte, album *model.Album) error
+}
+
+// albumXML and albumListXML give the XML documents lower case root elements.
+type albumXML struct {
+	XMLName xml.Name `xml:"album"`
+	model.Album
+}
+
+type albumListXML struct {
+	XMLName xml.Name      `xml:"albums"`
+	Albums  []model.Album `xml:"album"`
+}
+
+// albumFormats are in order of preference, JSON first so requests without Accept or Content-Type keep getting JSON.
+var albumFormats = []albumFormat{
+	{name: "json", label: "JSON", mimeTypes: []string{binding.MIMEJSON},
+		decode: func(body []byte, album *model.Album) error { return binding.JSON.BindBody(body, album) }},
+	{name: "protobuf", label: "Protobuf", mimeTypes: []string{binding.MIMEPROTOBUF, "application/protobuf"}, binary: true,
+		decode: decodeProtobufAlbum},
+	{name: "msgpack", label: "MessagePack", mimeTypes: []string{binding.MIMEMSGPACK2, binding.MIMEMSGPACK}, binary: true,
+		decode: func(body []byte, album *model.Album) error { return binding.MsgPack.BindBody(body, album) }},
+	{name: "xml", label: "XML", mimeTypes: []string{binding.MIMEXML, binding.MIMEXML2},
+		decode: func(body []byte, album *model.Album) error { return binding.XML.BindBody(body, album) }},
+	{name: "yaml", label: "YAML", mimeTypes: []string{binding.MIMEYAML, "application/yaml", "text/yaml"},
+		decode: func(body []byte, album *model.Album) error { return binding.YAML.BindBody(body, album) }},
+}
+
+var jsonAlbumFormat = albumFormats[0]
+
+func decodeProtobufAlbum(body []byte, album *model.Album) error {
+	var protoAlbum albumpb.Album
+	if err := proto.Unmarshal(body, &protoAlbum); err != nil {
+		return err
+	}
+	*album = albumFromProto(&protoAlbum)
+	return binding.Validator.ValidateStruct(album)
+}
+
+func albumMimeTypes() []string {
+	var mimeTypes []string
+	for _, format := range albumFormats {
+		mimeTypes = append(mimeTypes, format.mimeTypes...)
+	}
+	return mimeTypes
+}
+
+func albumFormatForMimeType(mimeType string) (albumFormat, bool) {
+	for _, format := range albumFormats {
+		for _, formatMimeType := range format.mimeTypes {
+			if strings.EqualFold(mimeType, formatMimeType) {
+				return format, true
+			}
+		}
+	}
+	return albumFormat{}, false
+}
+
+// negotiateAlbumFormat picks the response format from the Accept header, writing a 406 when none of the album formats are acceptable.
+func negotiateAlbumFormat(c *gin.Context, span trace.Span) (albumFormat, bool) {
+	format, found := albumFormatForMimeType(c.NegotiateFormat(albumMimeTypes()...))
+	if !found {
+		buildErrorResponse(c, span, problemNotAcceptable, fmt.Sprintf("Accept [%s] not supported, use one of %s", c.GetHeader("Accept"), strings.Join(albumMimeTypes(), ", ")))
+		return albumFormat{}, true
+	}
+	span.SetAttributes(attribute.Key("album-store.response.format").String(format.name))
+	return format, false
+}
+
+// requestAlbumFormat picks the request body format from the Content-Type header, writing a 415 when it is missing or not an album format.
+func requestAlbumFormat(c *gin.Context, span trace.Span) (albumFormat, bool) {
+	contentType := c.ContentType()
+	format, found := albumFormatForMimeType(contentType)
+	if !found {
+		buildErrorResponse(c, span, problemUnsupportedMediaType, fmt.Sprintf("Content-Type [%s] not supported, use one of %s", contentType, strings.Join(albumMimeTypes(), ", ")))
+		return albumFormat{}, true
+	}
+	span.SetAttributes(attribute.Key("album-store.request.format").String(format.name))
+	return format, false
+}
+
+// renderAlbums writes a model.Album or []model.Album in the negotiated format.
+func renderAlbums(c *gin.Context, statusCode int, format albumFormat, response interface{}) {
+	_, stage := telemetry.StartStage(c.Request.Context(), "serialize response")
+	defer telemetry.EndStage(stage, false)
+	switch format.name {
+	case "protobuf":
+		switch value := response.(type) {
+		case model.Album:
+			c.ProtoBuf(statusCode, albumToProto(value))
+		case []model.Album:
+			protoAlbums := &albumpb.ListAlbumsResponse{Albums: make([]*albumpb.Album, len(value))}
+			for index, album := range value {
+				protoAlbums.Albums[index] = albumToProto(album)
+			}
+			c.ProtoBuf(statusCode, protoAlbums)
+		}
+	case "msgpack":
+		c.Render(statusCode, render.MsgPack{Data: response})
+	case "xml":
+		switch value := response.(type) {
+		case model.Album:
+			c.XML(statusCode, albumXML{Album: value})
+		case []model.Album:
+			c.XML(statusCode, albumListXML{Albums: value})
+		}
+	case "yaml":
+		c.YAML(statusCode, response)
+	default:
+		c.JSON(statusCode, response)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/albumpb"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_getAllAlbums_Protobuf(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+	req.Header.Set("Accept", "application/x-protobuf")
+	router.ServeHTTP(testRecorder, req)
+
+	var response albumpb.ListAlbumsResponse
+	assert.NoError(t, proto.Unmarshal(testRecorder.Body.Bytes(), &response))
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, "application/x-protobuf", testRecorder.Header().Get("Content-Type"))
+	assert.Equal(t, 3, len(response.GetAlbums()))
+	assert.Equal(t, "Blue Train", response.GetAlbums()[0].GetTitle())
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "protobuf", attributeMap["album-store.response.format"].Emit())
+}
+
+func Test_getAlbumById_Formats(t *testing.T) {
+	resetAlbums()
+	expected := model.Album{ID: 2, Title: "Jeru", Artist: "Gerry Mulligan", Price: 17.99}
+
+	testRecorder, _, router := setupTestRouter(t)
+	req := httptest.NewRequest(http.MethodGet, "/albums/2", nil)
+	req.Header.Set("Accept", "application/xml")
+	router.ServeHTTP(testRecorder, req)
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, `<album><id>2</id><title>Jeru</title><artist>Gerry Mulligan</artist><price>17.99</price></album>`, testRecorder.Body.String())
+
+	testRecorder, _, router = setupTestRouter(t)
+	req = httptest.NewRequest(http.MethodGet, "/albums/2", nil)
+	req.Header.Set("Accept", "application/yaml")
+	router.ServeHTTP(testRecorder, req)
+	var yamlAlbum model.Album
+	assert.NoError(t, yaml.Unmarshal(testRecorder.Body.Bytes(), &yamlAlbum))
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, expected, yamlAlbum)
+
+	testRecorder, _, router = setupTestRouter(t)
+	req = httptest.NewRequest(http.MethodGet, "/albums/2", nil)
+	req.Header.Set("Accept", "application/msgpack")
+	router.ServeHTTP(testRecorder, req)
+	var msgpackAlbum model.Album
+	assert.NoError(t, codec.NewDecoderBytes(testRecorder.Body.Bytes(), new(codec.MsgpackHandle)).Decode(&msgpackAlbum))
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, expected, msgpackAlbum)
+}
+
+func Test_getAlbumById_WildcardAccept_Json(t *testing.T) {
+	resetAlbums()
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/2", nil)
+	req.Header.Set("Accept", "text/html,*/*;q=0.8")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, "application/json; charset=utf-8", testRecorder.Header().Get("Content-Type"))
+}
+
+func Test_getAlbums_NotAcceptable(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+	req.Header.Set("Accept", "text/csv")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusNotAcceptable, testRecorder.Code)
+	assert.Contains(t, testRecorder.Body.String(), `"message":"Accept [text/csv] not supported, use one of application/json`)
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "406", attributeMap["album-store.response.code"].Emit())
+}
+
+func Test_postAlbum_Formats(t *testing.T) {
+	album := model.Album{ID: 10, Title: "The Ozzman Cometh", Artist: "Black Sabbath", Price: 66.60}
+	xmlBody, _ := xml.Marshal(albumXML{Album: album})
+	yamlBody, _ := yaml.Marshal(album)
+	protoBody, _ := proto.Marshal(albumToProto(album))
+	var msgpackBody []byte
+	_ = codec.NewEncoderBytes(&msgpackBody, new(codec.MsgpackHandle)).Encode(album)
+
+	for contentType, body := range map[string][]byte{
+		binding.MIMEXML:      xmlBody,
+		binding.MIMEYAML:     yamlBody,
+		binding.MIMEPROTOBUF: protoBody,
+		binding.MIMEMSGPACK2: msgpackBody,
+	} {
+		resetAlbums()
+		testRecorder, spanRecorder, router := setupTestRouter(t)
+
+		req := newJsonRequest(http.MethodPost, "/albums", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		router.ServeHTTP(testRecorder, req)
+
+		assert.Equal(t, http.StatusCreated, testRecorder.Code, contentType)
+		assert.Equal(t, `{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}`, testRecorder.Body.String(), contentType)
+		assert.Equal(t, 4, len(listAlbums()), contentType)
+
+		finishedSpans := requestSpans(spanRecorder)
+		assert.Len(t, finishedSpans, 1)
+		attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+		assert.Equal(t, "json", attributeMap["album-store.response.format"].Emit())
+		assert.NotEqual(t, "json", attributeMap["album-store.request.format"].Emit())
+	}
+}
+
+func Test_postAlbum_Protobuf_ValidationErrors(t *testing.T) {
+	resetAlbums()
+	testRecorder, _, router := setupTestRouter(t)
+
+	protoBody, _ := proto.Marshal(&albumpb.Album{Id: 10, Title: "T", Artist: "Black Sabbath", Price: 66.60})
+	req := newJsonRequest(http.MethodPost, "/albums", bytes.NewReader(protoBody))
+	req.Header.Set("Content-Type", binding.MIMEPROTOBUF)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":[{"field":"title","message":"must be at least 2 characters"}],"message":""}`, testRecorder.Body.String())
+	assert.Equal(t, 3, len(listAlbums()))
+}
+
+func Test_postAlbum_Malformed_XML(t *testing.T) {
+	resetAlbums()
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`<album><id>10</id>`))
+	req.Header.Set("Content-Type", binding.MIMEXML)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":null,"message":"Malformed XML. Not valid for Album"}`, testRecorder.Body.String())
+}
+
+func Test_postAlbum_UnsupportedMediaType(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`10,The Ozzman Cometh,Black Sabbath,66.60`))
+	req.Header.Set("Content-Type", "text/csv")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, testRecorder.Code)
+	assert.Contains(t, testRecorder.Body.String(), `"message":"Content-Type [text/csv] not supported`)
+	assert.Equal(t, 3, len(listAlbums()))
+
+	finishedSpans := requestSpans(spanRecorder)
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "415", attributeMap["album-store.response.code"].Emit())
+}
+
+func Test_postAlbum_Json_AcceptYaml(t *testing.T) {
+	resetAlbums()
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`{"id": 10, "title": "The Ozzman Cometh", "artist": "Black Sabbath", "price": 66.60}`))
+	req.Header.Set("Content-Type", binding.MIMEJSON)
+	req.Header.Set("Accept", binding.MIMEYAML)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusCreated, testRecorder.Code)
+	assert.Equal(t, "id: 10\ntitle: The Ozzman Cometh\nartist: Black Sabbath\nprice: 66.6\n", testRecorder.Body.String())
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/api"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// openAPIVersion is 3.0.3 because openapi2conv emits OpenAPI 3.0 schemas (nullable, boolean exclusiveMinimum), not JSON Schema 2020-12.
+const openAPIVersion = "3.0.3"
+
+// openAPIDocument is converted when the service starts from the Swagger 2 document swag generates from the handler annotations,
+// so the annotations stay the one place the API is described.
+var openAPIDocument = newOpenAPIDocument(api.SwaggerInfo.ReadDoc())
+
+func newOpenAPIDocument(swaggerJson string) *openapi3.T {
+	var swagger openapi2.T
+	if err := json.Unmarshal([]byte(swaggerJson), &swagger); err != nil {
+		panic(err)
+	}
+	document, err := openapi2conv.ToV3(&swagger)
+	if err != nil {
+		panic(err)
+	}
+	document.OpenAPI = openAPIVersion
+	return document
+}
+
+// GetOpenAPIDocument godoc
+// @Summary OpenAPI document
+// @Schemes
+// @Description get the OpenAPI 3.0 document of the service
+// @Tags service
+// @Produce json
+// @Success 200 {object} object
+// @Router /v3/api-docs [get]
+func getOpenAPIDocument(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	span.SetStatus(codes.Ok, "")
+	telemetry.SetResponseCode(span, http.StatusOK)
+	c.JSON(http.StatusOK, openAPIDocument)
+}
+
+// strictResponseValidation also validates responses against openAPIDocument, set with OPENAPI_STRICT=true.
+// A response that does not match is still sent, the violation is recorded so contract drift shows up in production.
+var strictResponseValidation = false
+
+// openAPIValidation validates the path, query and header parameters of requests to documented routes against openAPIDocument,
+// writing a 400 listing the violations. Request bodies are not validated against the document: its body schemas are generated
+// from the binding tags, binding enforces every constraint they give (Test_openAPIDocument_BodySchemasMatchBinding) and
+// answers violations with localized messages, the validation-failed code and the catalog metrics.
+// Requests are only validated when errors are sent as problem details, legacy clients keep the error bodies the handlers have always sent.
+func openAPIValidation() gin.HandlerFunc {
+	responseViolations, err := global.Meter(serviceName).Int64Counter("album-store.openapi.response_violations",
+		metric.WithDescription("Responses that do not match the OpenAPI document."))
+	if err != nil {
+		otel.Handle(err)
+	}
+	return func(c *gin.Context) {
+		route := openAPIRoute(c)
+		if route == nil {
+			c.Next()
+			return
+		}
+		span := trace.SpanFromContext(c.Request.Context())
+		requestInput := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: map[string]string{},
+			Route:      route,
+			Options:    &openapi3filter.Options{ExcludeRequestBody: true, MultiError: true},
+		}
+		for _, param := range c.Params {
+			requestInput.PathParams[param.Key] = param.Value
+		}
+		if useProblemDetails(c) && rejectInvalidRequest(c, span, requestInput) {
+			return
+		}
+		if !strictResponseValidation {
+			c.Next()
+			return
+		}
+		captureWriter := responseCaptureWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = captureWriter
+		c.Next()
+		validateResponse(c, requestInput, captureWriter.body.Bytes(), responseViolations)
+	}
+}
+
+// rejectInvalidRequest writes a 400 listing the violations when the request does not match the document.
+func rejectInvalidRequest(c *gin.Context, span trace.Span, requestInput *openapi3filter.RequestValidationInput) bool {
+	err := openapi3filter.ValidateRequest(c.Request.Context(), requestInput)
+	if err == nil {
+		return false
+	}
+	bindingErrorMessages := openAPIViolations(err)
+	bindingErrorMessage, _ := json.Marshal(bindingErrorMessages)
+	span.SetStatus(codes.Error, "Request does not match the OpenAPI document")
+	span.AddEvent(string(bindingErrorMessage))
+	abortWithProblem(c, span, problemRequestInvalid, "Request does not match the OpenAPI document", bindingErrorMessages)
+	return true
+}
+
+// openAPIRoute finds the documented operation for the gin route that matched the request, nil when the route is not documented.
+func openAPIRoute(c *gin.Context) *routers.Route {
+	path := ginPathParam.ReplaceAllString(c.FullPath(), "{$1}")
+	// an unversioned path answered in another version than the default is documented under that version's prefix
+	// apiVersioning runs after this middleware, on the album routes only, so the version is read from the request here
+	if version, _, _ := requestedAPIVersion(c); version.name != "" && version.name != defaultAPIVersion.name && openAPIDocument.Paths.Find("/"+version.name+path) != nil {
+		path = "/" + version.name + path
+	}
+	pathItem := openAPIDocument.Paths.Find(path)
+	if c.FullPath() == "" || pathItem == nil || pathItem.GetOperation(c.Request.Method) == nil {
+		return nil
+	}
+	return &routers.Route{Spec: openAPIDocument, Path: path, PathItem: pathItem, Method: c.Request.Method, Operation: pathItem.GetOperation(c.Request.Method)}
+}
+
+var ginPathParam = regexp.MustCompile(`:([^/]+)`)
+
+// validateResponse counts and records a response that does not match the document on a child span of the request span,
+// next to the handler stages. Only JSON bodies are validated.
+func validateResponse(c *gin.Context, requestInput *openapi3filter.RequestValidationInput, body []byte, responseViolations metric.Int64Counter) {
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: requestInput,
+		Status:                 c.Writer.Status(),
+		Header:                 c.Writer.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(body)),
+		Options: &openapi3filter.Options{
+			ExcludeResponseBody:   !strings.HasPrefix(c.Writer.Header().Get("Content-Type"), binding.MIMEJSON),
+			IncludeResponseStatus: true,
+			MultiError:            true,
+		},
+	}
+	err := openapi3filter.ValidateResponse(c.Request.Context(), responseInput)
+	if err == nil {
+		return
+	}
+	responseViolations.Add(c.Request.Context(), 1, metric.WithAttributes(
+		attribute.Key("http.method").String(c.Request.Method),
+		attribute.Key("http.route").String(c.FullPath()),
+		attribute.Key("http.status_code").Int(c.Writer.Status()),
+		attribute.Key("album-store.api.version").String(requestAPIVersion(c).name),
+	))
+	_, span := telemetry.Tracer().Start(c.Request.Context(), "OpenAPI response validation")
+	defer span.End()
+	span.SetStatus(codes.Error, "Response does not match the OpenAPI document")
+	span.AddEvent("Response does not match the OpenAPI document", trace.WithAttributes(
+		attribute.Key("album-store.openapi.route").String(c.Request.Method+" "+requestInput.Route.Path),
+		attribute.Key("album-store.openapi.violation").String(err.Error()),
+		attribute.Key("album-store.api.version").String(requestAPIVersion(c).name),
+	), trace.WithAttributes(telemetry.SpanConvention.ResponseCodeAttributes(c.Writer.Status())...))
+}
+
+// openAPIViolations lists each failed parameter with its name as the field.
+func openAPIViolations(err error) []*model.BindingErrorMsg {
+	errs := []error{err}
+	var multiError openapi3.MultiError
+	if errors.As(err, &multiError) {
+		errs = multiError
+	}
+	var bindingErrorMessages []*model.BindingErrorMsg
+	for _, err := range errs {
+		var requestError *openapi3filter.RequestError
+		if !errors.As(err, &requestError) || requestError.Parameter == nil {
+			bindingErrorMessages = append(bindingErrorMessages, &model.BindingErrorMsg{Field: "", Message: err.Error()})
+			continue
+		}
+		message := requestError.Error()
+		var parseError *openapi3filter.ParseError
+		var schemaError *openapi3.SchemaError
+		switch {
+		case errors.As(requestError.Err, &parseError):
+			message = fmt.Sprintf("%v is %s", parseError.Value, parseError.Reason)
+		case errors.As(requestError.Err, &schemaError):
+			message = schemaError.Reason
+		case requestError.Err != nil:
+			message = requestError.Err.Error()
+		}
+		bindingErrorMessages = append(bindingErrorMessages, &model.BindingErrorMsg{Field: requestError.Parameter.Name, Message: message})
+	}
+	return bindingErrorMessages
+}
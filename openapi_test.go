@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// undocumentedRoutes are served but deliberately left out of the OpenAPI document.
+var undocumentedRoutes = map[string]bool{
+	"GET /swagger/*any": true,
+	"GET /graphql":      true, // GraphiQL, only in debug mode
+}
+
+func openAPIPath(ginPath string) string {
+	return ginPathParam.ReplaceAllString(ginPath, "{$1}")
+}
+
+func Test_getOpenAPIDocument(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/v3/api-docs", nil))
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	document, err := openapi3.NewLoader().LoadFromData(testRecorder.Body.Bytes())
+	assert.NoError(t, err)
+	assert.NoError(t, document.Validate(context.Background()))
+	assert.Equal(t, "3.0.3", document.OpenAPI)
+	assert.Equal(t, "http://localhost:9080/", document.Servers[0].URL)
+	assert.Equal(t, "/v3/api-docs", requestSpans(spanRecorder)[0].Name())
+}
+
+func Test_openAPIDocument_MatchesRouter(t *testing.T) {
+	_, _, router := setupTestRouter(t)
+
+	routes := map[string]bool{}
+	for _, route := range router.Routes() {
+		key := route.Method + " " + route.Path
+		if undocumentedRoutes[key] {
+			continue
+		}
+		path := openAPIPath(route.Path)
+		routes[route.Method+" "+path] = true
+		pathItem := openAPIDocument.Paths.Find(path)
+		if !assert.NotNil(t, pathItem, "route %s is not documented", key) {
+			continue
+		}
+		operation := pathItem.GetOperation(route.Method)
+		if !assert.NotNil(t, operation, "route %s is not documented", key) {
+			continue
+		}
+		for _, match := range ginPathParam.FindAllStringSubmatch(route.Path, -1) {
+			parameter := operation.Parameters.GetByInAndName(openapi3.ParameterInPath, match[1])
+			assert.NotNil(t, parameter, "route %s does not document path parameter %s", key, match[1])
+		}
+	}
+	for path, pathItem := range openAPIDocument.Paths {
+		for method := range pathItem.Operations() {
+			assert.True(t, routes[strings.ToUpper(method)+" "+path], "documented %s %s is not a route", method, path)
+		}
+	}
+}
+
+// Test_openAPIDocument_BodySchemasMatchBinding checks binding enforces every constraint the document gives a request body,
+// which is why openAPIValidation leaves bodies to binding.
+func Test_openAPIDocument_BodySchemasMatchBinding(t *testing.T) {
+	documented := 0
+	for _, metadata := range modelRegistry {
+		schemaRef := openAPIDocument.Components.Schemas["model."+metadata.name]
+		if schemaRef == nil {
+			continue
+		}
+		documented++
+		schema := schemaRef.Value
+		for name, propertyRef := range schema.Properties {
+			field, okay := metadata.byJsonName(name)
+			if !assert.True(t, okay, "model.%s %s is documented but not bound", metadata.name, name) {
+				continue
+			}
+			property := propertyRef.Value
+			minimum, maximum := property.Min, property.Max
+			if property.Type == openapi3.TypeString {
+				minimum, maximum = uintLimit(&property.MinLength), uintLimit(property.MaxLength)
+			}
+			assert.Equal(t, minimum, field.minimum, "model.%s %s minimum", metadata.name, name)
+			assert.Equal(t, maximum, field.maximum, "model.%s %s maximum", metadata.name, name)
+			for _, value := range property.Enum {
+				assert.Contains(t, field.enum, value, "model.%s %s enum", metadata.name, name)
+			}
+		}
+		for _, name := range schema.Required {
+			field, okay := metadata.byJsonName(name)
+			assert.True(t, okay && field.required, "model.%s %s is documented as required but binding does not require it", metadata.name, name)
+		}
+	}
+	assert.Equal(t, 5, documented)
+}
+
+func uintLimit(limit *uint64) *float64 {
+	if limit == nil || *limit == 0 {
+		return nil
+	}
+	value := float64(*limit)
+	return &value
+}
+
+func Test_openAPIValidation_Request_BelowMinimum(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/-1666", nil)
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	router.ServeHTTP(testRecorder, req)
+
+	var problem model.Problem
+	assert.NoError(t, json.Unmarshal(testRecorder.Body.Bytes(), &problem))
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, "request-invalid", problem.Code)
+	assert.Equal(t, []*model.BindingErrorMsg{{Field: "id", Message: "number must be at least 1"}}, problem.BindingErrors)
+}
+
+func Test_openAPIValidation_Request_InvalidCharacter(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/X", nil)
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	router.ServeHTTP(testRecorder, req)
+
+	var problem model.Problem
+	assert.NoError(t, json.Unmarshal(testRecorder.Body.Bytes(), &problem))
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, "request-invalid", problem.Code)
+	assert.Equal(t, "Request does not match the OpenAPI document", problem.Detail)
+	assert.Equal(t, []*model.BindingErrorMsg{{Field: "id", Message: "X is an invalid integer"}}, problem.BindingErrors)
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
+	assert.Equal(t, "Request does not match the OpenAPI document", finishedSpans[0].Status().Description)
+	assert.Equal(t, `[{"field":"id","message":"X is an invalid integer"}]`, finishedSpans[0].Events()[0].Name)
+	assert.Equal(t, "request-invalid", makeKeyMap(finishedSpans[0].Attributes())["album-store.error.code"].Emit())
+}
+
+func Test_openAPIValidation_Request_LegacyFormatNotValidated(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/orders/0", nil))
+
+	assert.Equal(t, http.StatusNotFound, testRecorder.Code)
+	assert.Equal(t, `{"errors":null,"message":"Order [0] not found"}`, testRecorder.Body.String())
+}
+
+func Test_openAPIValidation_StrictResponse_Matches(t *testing.T) {
+	strictResponseValidation = true
+	defer func() { strictResponseValidation = false }()
+	reader := setupTestMeter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/albums/1", nil))
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, int64(0), responseViolations(t, reader))
+	assert.Len(t, requestSpans(spanRecorder), 1)
+}
+
+func Test_openAPIValidation_StrictResponse_Drift(t *testing.T) {
+	strictResponseValidation = true
+	defer func() { strictResponseValidation = false }()
+	reader := setupTestMeter()
+	spanRecorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	router := gin.New()
+	router.Use(otelgin.Middleware("test-otel"))
+	router.Use(openAPIValidation())
+	router.GET("/albums", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"albums": "not an array"})
+	})
+	testRecorder := httptest.NewRecorder()
+
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/albums", nil))
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, `{"albums":"not an array"}`, testRecorder.Body.String())
+	violations := collectMetric(t, reader, "album-store.openapi.response_violations").(metricdata.Sum[int64])
+	assert.Len(t, violations.DataPoints, 1)
+	assert.Equal(t, int64(1), violations.DataPoints[0].Value)
+	assert.Equal(t, attribute.NewSet(
+		attribute.Key("http.method").String(http.MethodGet),
+		attribute.Key("http.route").String("/albums"),
+		attribute.Key("http.status_code").Int(http.StatusOK),
+		attribute.Key("album-store.api.version").String("v1"),
+	), violations.DataPoints[0].Attributes)
+	finishedSpans := spanRecorder.Ended()
+	assert.Len(t, finishedSpans, 2)
+	assert.Equal(t, "OpenAPI response validation", finishedSpans[0].Name())
+	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
+	assert.Equal(t, "Response does not match the OpenAPI document", finishedSpans[0].Events()[0].Name)
+	eventAttributes := makeKeyMap(finishedSpans[0].Events()[0].Attributes)
+	assert.Equal(t, "GET /albums", eventAttributes["album-store.openapi.route"].Emit())
+	assert.Contains(t, eventAttributes["album-store.openapi.violation"].Emit(), "response body doesn't match schema")
+	assert.Equal(t, finishedSpans[1].SpanContext().SpanID(), finishedSpans[0].Parent().SpanID())
+}
+
+// responseViolations totals the response violations recorded by reader, 0 when none were recorded.
+func responseViolations(t *testing.T, reader sdkmetric.Reader) int64 {
+	var resourceMetrics metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &resourceMetrics))
+	total := int64(0)
+	for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+		for _, metrics := range scopeMetrics.Metrics {
+			if metrics.Name == "album-store.openapi.response_violations" {
+				for _, dataPoint := range metrics.Data.(metricdata.Sum[int64]).DataPoints {
+					total += dataPoint.Value
+				}
+			}
+		}
+	}
+	return total
+}
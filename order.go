@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ordersLock  sync.Mutex
+	orders      = map[int]*model.Order{}
+	nextOrderID = 1
+)
+
+func resetOrders() {
+	ordersLock.Lock()
+	defer ordersLock.Unlock()
+	orders = map[int]*model.Order{}
+	nextOrderID = 1
+}
+
+// PostOrder godoc
+// @Summary Checkout cart
+// @Schemes
+// @Description checkout a cart, charging the payment processor and creating a paid order
+// @Tags orders
+// @Param request body model.OrderRequest true "order"
+// @Accept json
+// @Produce json
+// @Success 201 {object} model.Order
+// @Failure 400 {object} model.ServerError
+// @Failure 402 {object} model.ServerError
+// @Failure 404 {object} model.ServerError
+// @Failure 413 {object} model.ServerError
+// @Failure 415 {object} model.ServerError
+// @Router /orders [post]
+func postOrder(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	var orderRequest model.OrderRequest
+	if bindRequestJson(c, span, &orderRequest, "Order") {
+		return
+	}
+
+	_, stage := telemetry.StartStage(c.Request.Context(), "find cart")
+	checkoutCart, found, empty := claimCart(orderRequest.CartID)
+	telemetry.EndStage(stage, !found || empty)
+	if !found {
+		buildErrorResponse(c, span, problemCartNotFound, fmt.Sprintf("Cart [%v] not found", orderRequest.CartID))
+		return
+	}
+	if empty {
+		buildErrorResponse(c, span, problemCartEmpty, fmt.Sprintf("Cart [%v] is empty", orderRequest.CartID))
+		return
+	}
+
+	order, err := checkout(c.Request.Context(), checkoutCart)
+	span.SetAttributes(attribute.Key("album-store.order.id").Int(order.ID))
+	if err != nil {
+		restoreCart(checkoutCart)
+		buildErrorResponse(c, span, problemPaymentFailed, fmt.Sprintf("Payment for order [%v] failed: %v", order.ID, err))
+		return
+	}
+	buildJsonResponse(c, span, http.StatusCreated, order)
+}
+
+// claimCart removes a non-empty cart from carts under cartsLock, so concurrent checkouts of the same cart cannot both
+// charge it. An empty cart is left in place.
+func claimCart(cartID int) (cart model.Cart, found bool, empty bool) {
+	cartsLock.Lock()
+	defer cartsLock.Unlock()
+	claimed, found := carts[cartID]
+	if !found {
+		return model.Cart{}, false, false
+	}
+	if len(claimed.Lines) == 0 {
+		return model.Cart{}, true, true
+	}
+	delete(carts, cartID)
+	return copyCart(claimed), true, false
+}
+
+// restoreCart puts a claimed cart back after a failed checkout so the customer can retry.
+func restoreCart(cart model.Cart) {
+	cartsLock.Lock()
+	defer cartsLock.Unlock()
+	restored := copyCart(&cart)
+	carts[cart.ID] = &restored
+}
+
+// checkout creates a pending order for a claimed cart and charges it, moving the order to paid on success
+// and to cancelled when the charge fails, so a retry starts a new order instead of leaving one pending that is never paid.
+func checkout(ctx context.Context, cart model.Cart) (model.Order, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "checkout")
+	defer span.End()
+
+	ordersLock.Lock()
+	order := &model.Order{ID: nextOrderID, CartID: cart.ID, Lines: cart.Lines, Total: cart.Total, Status: model.OrderPending}
+	orders[order.ID] = order
+	nextOrderID++
+	ordersLock.Unlock()
+	span.SetAttributes(
+		attribute.Key("album-store.order.id").Int(order.ID),
+		attribute.Key("album-store.order.total").Float64(order.Total),
+	)
+
+	paymentID, err := DefaultPaymentProcessor.Charge(ctx, order.ID, order.Total)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		ordersLock.Lock()
+		order.Status = model.OrderCancelled
+		cancelledOrder := *order
+		ordersLock.Unlock()
+		span.SetAttributes(attribute.Key("album-store.order.status").String(string(cancelledOrder.Status)))
+		return cancelledOrder, err
+	}
+
+	ordersLock.Lock()
+	order.Status = model.OrderPaid
+	order.PaymentID = paymentID
+	paidOrder := *order
+	ordersLock.Unlock()
+
+	span.SetAttributes(attribute.Key("album-store.order.status").String(string(paidOrder.Status)))
+	span.SetStatus(codes.Ok, "")
+	return paidOrder, nil
+}
+
+// GetOrderById godoc
+// @Summary Get order by id
+// @Schemes
+// @Description get an order and its current status
+// @Tags orders
+// @Param  id path int true  "order id" minimum(1)
+// @Produce json
+// @Success 200 {object} model.Order
+// @Failure 400 {object} model.ServerError
+// @Failure 404 {object} model.ServerError
+// @Router /orders/{id} [get]
+func getOrderByID(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	orderID, failed := parseIDParam(c, span, "id", "Order")
+	if failed {
+		return
+	}
+	_, stage := telemetry.StartStage(c.Request.Context(), "find order")
+	ordersLock.Lock()
+	order, found := orders[orderID]
+	var response model.Order
+	if found {
+		response = *order
+	}
+	ordersLock.Unlock()
+	telemetry.EndStage(stage, !found)
+	if !found {
+		buildErrorResponse(c, span, problemOrderNotFound, fmt.Sprintf("Order [%v] not found", orderID))
+		return
+	}
+	buildJsonResponse(c, span, http.StatusOK, response)
+}
+
+// PatchOrder godoc
+// @Summary Update order status
+// @Schemes
+// @Description move an order through its lifecycle: pending -> paid -> shipped, or cancelled from pending or paid
+// @Tags orders
+// @Param  id path int true  "order id" minimum(1)
+// @Param request body model.OrderStatusRequest true "order status"
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Order
+// @Failure 400 {object} model.ServerError
+// @Failure 404 {object} model.ServerError
+// @Failure 409 {object} model.ServerError
+// @Failure 413 {object} model.ServerError
+// @Failure 415 {object} model.ServerError
+// @Router /orders/{id} [patch]
+func patchOrder(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	orderID, failed := parseIDParam(c, span, "id", "Order")
+	if failed {
+		return
+	}
+	var statusRequest model.OrderStatusRequest
+	if bindRequestJson(c, span, &statusRequest, "OrderStatus") {
+		return
+	}
+
+	_, stage := telemetry.StartStage(c.Request.Context(), "update order")
+	ordersLock.Lock()
+	order, found := orders[orderID]
+	var response model.Order
+	var currentStatus model.OrderStatus
+	allowed := false
+	if found {
+		currentStatus = order.Status
+		allowed = currentStatus.CanTransitionTo(statusRequest.Status)
+		if allowed {
+			order.Status = statusRequest.Status
+		}
+		response = *order
+	}
+	ordersLock.Unlock()
+	telemetry.EndStage(stage, !found || !allowed)
+
+	if !found {
+		buildErrorResponse(c, span, problemOrderNotFound, fmt.Sprintf("Order [%v] not found", orderID))
+		return
+	}
+	if !allowed {
+		buildErrorResponse(c, span, problemOrderTransition, fmt.Sprintf("Order [%v] cannot move from %s to %s", orderID, currentStatus, statusRequest.Status))
+		return
+	}
+	span.SetAttributes(attribute.Key("album-store.order.status").String(string(response.Status)))
+	buildJsonResponse(c, span, http.StatusOK, response)
+}
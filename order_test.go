@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// MockPaymentProcessor is the mock payment processor
+type MockPaymentProcessor struct {
+	ChargeFunc func(ctx context.Context, orderID int, amount float64) (string, error)
+}
+
+// Charge is the mock payment processor's `Charge` func
+func (m *MockPaymentProcessor) Charge(ctx context.Context, orderID int, amount float64) (string, error) {
+	return m.ChargeFunc(ctx, orderID, amount)
+}
+
+func setupCheckoutCart(router *gin.Engine) {
+	resetAlbums()
+	resetCarts()
+	resetOrders()
+	router.ServeHTTP(httptest.NewRecorder(), newJsonRequest(http.MethodPost, "/carts", nil))
+	router.ServeHTTP(httptest.NewRecorder(), newJsonRequest(http.MethodPost, "/carts/1/lines", strings.NewReader(`{"albumId": 1, "quantity": 2}`)))
+}
+
+func Test_postOrder(t *testing.T) {
+	DefaultPaymentProcessor = &fakePaymentProcessor{declineOver: 10000.00}
+	_, _, router := setupTestRouter(t)
+	setupCheckoutCart(router)
+
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	var order model.Order
+
+	req := newJsonRequest(http.MethodPost, "/orders", strings.NewReader(`{"cartId": 1}`))
+	router.ServeHTTP(testRecorder, req)
+	if err := json.Unmarshal(testRecorder.Body.Bytes(), &order); err != nil {
+		assert.Fail(t, "json unmarshal fail", "should be Order ", testRecorder.Body.String())
+	}
+
+	assert.Equal(t, http.StatusCreated, testRecorder.Code)
+
+	finishedSpans := spanRecorder.Ended()
+	assert.Equal(t, []string{"parse request body", "validate Order", "find cart", "payment charge", "checkout", "serialize response", "/orders"}, spanNames(finishedSpans))
+	for _, stage := range finishedSpans[:6] {
+		assert.Equal(t, codes.Ok, stage.Status().Code, stage.Name())
+	}
+	assert.Equal(t, finishedSpans[6].SpanContext().SpanID(), finishedSpans[4].Parent().SpanID())
+	assert.Equal(t, finishedSpans[4].SpanContext().SpanID(), finishedSpans[3].Parent().SpanID())
+	assert.Equal(t, codes.Ok, finishedSpans[6].Status().Code)
+
+	attributeMap := makeKeyMap(finishedSpans[6].Attributes())
+	assert.Equal(t, "201", attributeMap["album-store.response.code"].Emit())
+	assert.Equal(t, "1", attributeMap["album-store.order.id"].Emit())
+
+	assert.Equal(t, 1, order.ID)
+	assert.Equal(t, model.OrderPaid, order.Status)
+	assert.Equal(t, "fake-payment-1", order.PaymentID)
+	assert.Equal(t, 113.98, order.Total)
+
+	// the cart is consumed by checkout
+	testRecorder = httptest.NewRecorder()
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/carts/1", nil))
+	assert.Equal(t, http.StatusNotFound, testRecorder.Code)
+}
+
+func Test_postOrder_PaymentDeclined(t *testing.T) {
+	DefaultPaymentProcessor = &MockPaymentProcessor{ChargeFunc: func(context.Context, int, float64) (string, error) {
+		return "", errors.New("card expired")
+	}}
+	defer func() { DefaultPaymentProcessor = &fakePaymentProcessor{declineOver: 10000.00} }()
+	_, _, router := setupTestRouter(t)
+	setupCheckoutCart(router)
+
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	var serverError model.ServerError
+
+	req := newJsonRequest(http.MethodPost, "/orders", strings.NewReader(`{"cartId": 1}`))
+	router.ServeHTTP(testRecorder, req)
+	if err := json.Unmarshal(testRecorder.Body.Bytes(), &serverError); err != nil {
+		assert.Fail(t, "json unmarshal fail", "should be ServerError ", testRecorder.Body.String())
+	}
+
+	assert.Equal(t, http.StatusPaymentRequired, testRecorder.Code)
+
+	finishedSpans := spanRecorder.Ended()
+	assert.Equal(t, []string{"parse request body", "validate Order", "find cart", "checkout", "/orders"}, spanNames(finishedSpans))
+	assert.Equal(t, codes.Error, finishedSpans[3].Status().Code)
+	assert.Equal(t, codes.Error, finishedSpans[4].Status().Code)
+	assert.Equal(t, "Payment for order [1] failed: card expired", finishedSpans[4].Status().Description)
+
+	assert.Equal(t, "Payment for order [1] failed: card expired", serverError.Message)
+
+	// the order is cancelled and the cart kept for another attempt
+	testRecorder = httptest.NewRecorder()
+	var order model.Order
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+	_ = json.Unmarshal(testRecorder.Body.Bytes(), &order)
+	assert.Equal(t, model.OrderCancelled, order.Status)
+
+	testRecorder = httptest.NewRecorder()
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/carts/1", nil))
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+}
+
+func Test_postOrder_ConcurrentCheckoutChargesOnce(t *testing.T) {
+	charging := make(chan struct{})
+	release := make(chan struct{})
+	charges := 0
+	DefaultPaymentProcessor = &MockPaymentProcessor{ChargeFunc: func(context.Context, int, float64) (string, error) {
+		charges++
+		close(charging)
+		<-release
+		return "payment-1", nil
+	}}
+	defer func() { DefaultPaymentProcessor = &fakePaymentProcessor{declineOver: 10000.00} }()
+	_, _, router := setupTestRouter(t)
+	setupCheckoutCart(router)
+
+	firstRecorder := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		router.ServeHTTP(firstRecorder, newJsonRequest(http.MethodPost, "/orders", strings.NewReader(`{"cartId": 1}`)))
+		close(done)
+	}()
+	<-charging
+
+	secondRecorder := httptest.NewRecorder()
+	router.ServeHTTP(secondRecorder, newJsonRequest(http.MethodPost, "/orders", strings.NewReader(`{"cartId": 1}`)))
+	close(release)
+	<-done
+
+	assert.Equal(t, http.StatusCreated, firstRecorder.Code)
+	assert.Equal(t, http.StatusNotFound, secondRecorder.Code)
+	assert.Equal(t, 1, charges)
+}
+
+func Test_postOrder_EmptyCart(t *testing.T) {
+	resetCarts()
+	resetOrders()
+	_, _, router := setupTestRouter(t)
+	router.ServeHTTP(httptest.NewRecorder(), newJsonRequest(http.MethodPost, "/carts", nil))
+
+	testRecorder := httptest.NewRecorder()
+	var serverError model.ServerError
+
+	req := newJsonRequest(http.MethodPost, "/orders", strings.NewReader(`{"cartId": 1}`))
+	router.ServeHTTP(testRecorder, req)
+	_ = json.Unmarshal(testRecorder.Body.Bytes(), &serverError)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, "Cart [1] is empty", serverError.Message)
+}
+
+func Test_patchOrder_StateMachine(t *testing.T) {
+	DefaultPaymentProcessor = &fakePaymentProcessor{declineOver: 10000.00}
+	_, _, router := setupTestRouter(t)
+	setupCheckoutCart(router)
+	router.ServeHTTP(httptest.NewRecorder(), newJsonRequest(http.MethodPost, "/orders", strings.NewReader(`{"cartId": 1}`)))
+
+	var order model.Order
+	testRecorder := httptest.NewRecorder()
+	router.ServeHTTP(testRecorder, newJsonRequest(http.MethodPatch, "/orders/1", strings.NewReader(`{"status": "shipped"}`)))
+	_ = json.Unmarshal(testRecorder.Body.Bytes(), &order)
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, model.OrderShipped, order.Status)
+
+	var serverError model.ServerError
+	testRecorder = httptest.NewRecorder()
+	router.ServeHTTP(testRecorder, newJsonRequest(http.MethodPatch, "/orders/1", strings.NewReader(`{"status": "cancelled"}`)))
+	_ = json.Unmarshal(testRecorder.Body.Bytes(), &serverError)
+	assert.Equal(t, http.StatusConflict, testRecorder.Code)
+	assert.Equal(t, "Order [1] cannot move from shipped to cancelled", serverError.Message)
+
+	testRecorder = httptest.NewRecorder()
+	router.ServeHTTP(testRecorder, newJsonRequest(http.MethodPatch, "/orders/1", strings.NewReader(`{"status": "paid"}`)))
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+}
+
+func Test_orderStatus_CanTransitionTo(t *testing.T) {
+	assert.True(t, model.OrderPending.CanTransitionTo(model.OrderPaid))
+	assert.True(t, model.OrderPending.CanTransitionTo(model.OrderCancelled))
+	assert.True(t, model.OrderPaid.CanTransitionTo(model.OrderShipped))
+	assert.True(t, model.OrderPaid.CanTransitionTo(model.OrderCancelled))
+	assert.False(t, model.OrderPending.CanTransitionTo(model.OrderShipped))
+	assert.False(t, model.OrderShipped.CanTransitionTo(model.OrderCancelled))
+	assert.False(t, model.OrderCancelled.CanTransitionTo(model.OrderPaid))
+}
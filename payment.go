@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PaymentProcessor charges an order total at checkout. Swap the implementation for a real provider or a test double.
+type PaymentProcessor interface {
+	Charge(ctx context.Context, orderID int, amount float64) (string, error)
+}
+
+var (
+	DefaultPaymentProcessor PaymentProcessor
+	errPaymentDeclined      = errors.New("payment declined")
+)
+
+func init() {
+	DefaultPaymentProcessor = &fakePaymentProcessor{declineOver: 10000.00}
+}
+
+// fakePaymentProcessor approves every charge up to declineOver, so checkout can be demoed without a payment provider.
+type fakePaymentProcessor struct {
+	declineOver float64
+}
+
+func (p *fakePaymentProcessor) Charge(ctx context.Context, orderID int, amount float64) (string, error) {
+	_, span := telemetry.Tracer().Start(ctx, "payment charge", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	span.SetAttributes(
+		attribute.Key("payment.order.id").Int(orderID),
+		attribute.Key("payment.amount").Float64(amount),
+	)
+	if amount > p.declineOver {
+		span.SetStatus(codes.Error, errPaymentDeclined.Error())
+		span.AddEvent(fmt.Sprintf("amount %.2f over limit %.2f", amount, p.declineOver))
+		return "", errPaymentDeclined
+	}
+	paymentID := fmt.Sprintf("fake-payment-%d", orderID)
+	span.SetAttributes(attribute.Key("payment.id").String(paymentID))
+	span.SetStatus(codes.Ok, "")
+	return paymentID, nil
+}
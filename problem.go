@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	problemTypeBaseURI = "/problems/"
+)
+
+// problemType is an entry in the error catalog. The code is stable for clients to match on,
+// legacyStatus is the status sent when errors use the legacy model.ServerError body.
+type problemType struct {
+	code         string
+	title        string
+	status       int
+	legacyStatus int
+}
+
+var (
+	problemInvalidID        = problemType{code: "invalid-id", title: "Invalid id", status: http.StatusBadRequest, legacyStatus: http.StatusBadRequest}
+	problemMalformedBody    = problemType{code: "malformed-body", title: "Malformed request body", status: http.StatusBadRequest, legacyStatus: http.StatusBadRequest}
+	problemValidationFailed = problemType{code: "validation-failed", title: "Field validation failed", status: http.StatusBadRequest, legacyStatus: http.StatusBadRequest}
+	problemRequestInvalid   = problemType{code: "request-invalid", title: "Request does not match the OpenAPI document", status: http.StatusBadRequest, legacyStatus: http.StatusBadRequest}
+	problemInvalidFields    = problemType{code: "invalid-fields", title: "Unknown or duplicate fields", status: http.StatusBadRequest, legacyStatus: http.StatusBadRequest}
+	problemBodyTooLarge     = problemType{code: "body-too-large", title: "Request body too large", status: http.StatusRequestEntityTooLarge, legacyStatus: http.StatusRequestEntityTooLarge}
+	problemAlbumNotFound    = problemType{code: "album-not-found", title: "Album not found", status: http.StatusNotFound, legacyStatus: http.StatusNotFound}
+	// GET /albums/{id} has always answered a missing album with 400 in the legacy format
+	problemAlbumByIDNotFound        = problemType{code: "album-not-found", title: "Album not found", status: http.StatusNotFound, legacyStatus: http.StatusBadRequest}
+	problemCartNotFound             = problemType{code: "cart-not-found", title: "Cart not found", status: http.StatusNotFound, legacyStatus: http.StatusNotFound}
+	problemCartAlbumNotFound        = problemType{code: "cart-album-not-found", title: "Album not in cart", status: http.StatusNotFound, legacyStatus: http.StatusNotFound}
+	problemCartEmpty                = problemType{code: "cart-empty", title: "Cart is empty", status: http.StatusBadRequest, legacyStatus: http.StatusBadRequest}
+	problemOrderNotFound            = problemType{code: "order-not-found", title: "Order not found", status: http.StatusNotFound, legacyStatus: http.StatusNotFound}
+	problemOrderTransition          = problemType{code: "order-invalid-transition", title: "Order status change not allowed", status: http.StatusConflict, legacyStatus: http.StatusConflict}
+	problemPaymentFailed            = problemType{code: "payment-failed", title: "Payment failed", status: http.StatusPaymentRequired, legacyStatus: http.StatusPaymentRequired}
+	problemIdempotencyKeyReused     = problemType{code: "idempotency-key-reused", title: "Idempotency-Key used for a different request", status: http.StatusUnprocessableEntity, legacyStatus: http.StatusUnprocessableEntity}
+	problemIdempotencyKeyInProgress = problemType{code: "idempotency-key-in-progress", title: "Idempotency-Key request in progress", status: http.StatusConflict, legacyStatus: http.StatusConflict}
+	problemNotAcceptable            = problemType{code: "not-acceptable", title: "Response format not supported", status: http.StatusNotAcceptable, legacyStatus: http.StatusNotAcceptable}
+	problemUnsupportedMediaType     = problemType{code: "unsupported-media-type", title: "Request format not supported", status: http.StatusUnsupportedMediaType, legacyStatus: http.StatusUnsupportedMediaType}
+	problemVersionNotSupported      = problemType{code: "version-not-supported", title: "API version not supported", status: http.StatusNotAcceptable, legacyStatus: http.StatusNotAcceptable}
+)
+
+// problemCatalog lists every error code album-store can return.
+var problemCatalog = []problemType{
+	problemInvalidID, problemMalformedBody, problemValidationFailed, problemRequestInvalid, problemInvalidFields, problemBodyTooLarge, problemAlbumNotFound,
+	problemCartNotFound, problemCartAlbumNotFound, problemCartEmpty,
+	problemOrderNotFound, problemOrderTransition, problemPaymentFailed,
+	problemIdempotencyKeyReused, problemIdempotencyKeyInProgress,
+	problemNotAcceptable, problemUnsupportedMediaType, problemVersionNotSupported,
+}
+
+// problemDetailsErrors switches every response to application/problem+json, set with ERROR_FORMAT=problem.
+// Otherwise existing clients keep the legacy model.ServerError body and only requests accepting application/problem+json get problems.
+var problemDetailsErrors = false
+
+func useProblemDetails(c *gin.Context) bool {
+	return problemDetailsErrors || strings.Contains(c.GetHeader("Accept"), problemContentType)
+}
+
+func (p problemType) statusFor(c *gin.Context) int {
+	if useProblemDetails(c) {
+		return p.status
+	}
+	return p.legacyStatus
+}
+
+// abortWithProblem writes the error as problem details or as the legacy model.ServerError, recording the code and status on the span.
+func abortWithProblem(c *gin.Context, span trace.Span, problem problemType, detail string, bindingErrors []*model.BindingErrorMsg) {
+	span.SetAttributes(attribute.Key("album-store.error.code").String(problem.code))
+	statusCode := problem.statusFor(c)
+	telemetry.SetResponseCode(span, statusCode)
+	if len(bindingErrors) == 0 { // binding errors are logged where they are read
+		logProblem(c, statusCode, problem.code, detail)
+	}
+	if !useProblemDetails(c) {
+		if len(bindingErrors) > 0 {
+			detail = "" // legacy validation errors never carried a message
+		}
+		c.AbortWithStatusJSON(statusCode, model.ServerError{Message: detail, BindingErrors: bindingErrors})
+		return
+	}
+	body := model.Problem{
+		Type:          problemTypeBaseURI + problem.code,
+		Title:         problem.title,
+		Status:        statusCode,
+		Detail:        detail,
+		Instance:      c.Request.URL.Path,
+		Code:          problem.code,
+		BindingErrors: bindingErrors,
+	}
+	if span.SpanContext().HasTraceID() {
+		body.TraceID = span.SpanContext().TraceID().String()
+	}
+	problemJson, _ := json.Marshal(body)
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.response.body", string(problemJson))
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(statusCode, body)
+}
+
+// logProblem logs an error response with the request logger, as an error when it is the server's fault.
+func logProblem(c *gin.Context, statusCode int, code string, detail string) {
+	log := telemetry.RequestLogger(c)
+	event := log.Warn()
+	if statusCode >= http.StatusInternalServerError {
+		event = log.Error()
+	}
+	event.Int("status", statusCode).Str("code", code).Msg(detail)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getAlbumById_NotFound_Problem(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/666", nil)
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	router.ServeHTTP(testRecorder, req)
+
+	var problem model.Problem
+	assert.NoError(t, json.Unmarshal(testRecorder.Body.Bytes(), &problem))
+	assert.Equal(t, http.StatusNotFound, testRecorder.Code)
+	assert.Equal(t, "application/problem+json", testRecorder.Header().Get("Content-Type"))
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, model.Problem{
+		Type:     "/problems/album-not-found",
+		Title:    "Album not found",
+		Status:   http.StatusNotFound,
+		Detail:   "Album [666] not found",
+		Instance: "/albums/666",
+		Code:     "album-not-found",
+		TraceID:  finishedSpans[0].SpanContext().TraceID().String(),
+	}, problem)
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "album-not-found", attributeMap["album-store.error.code"].Emit())
+	assert.Equal(t, "404", attributeMap["album-store.response.code"].Emit())
+}
+
+func Test_postAlbum_ValidationErrors_Problem(t *testing.T) {
+	resetAlbums()
+	problemDetailsErrors = true
+	defer func() { problemDetailsErrors = false }()
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`{"id": 10, "title": "T", "artist": "Black Sabbath", "price": 66.60}`))
+	router.ServeHTTP(testRecorder, req)
+
+	var problem model.Problem
+	assert.NoError(t, json.Unmarshal(testRecorder.Body.Bytes(), &problem))
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, "validation-failed", problem.Code)
+	assert.Equal(t, "Album JSON field validation failed", problem.Detail)
+	assert.Equal(t, []*model.BindingErrorMsg{{Field: "title", Message: "must be at least 2 characters"}}, problem.BindingErrors)
+	assert.Equal(t, 3, len(listAlbums()))
+}
+
+func Test_getCartById_NotFound_Legacy(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/carts/666", nil))
+
+	assert.Equal(t, http.StatusNotFound, testRecorder.Code)
+	assert.Equal(t, `{"errors":null,"message":"Cart [666] not found"}`, testRecorder.Body.String())
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	assert.Equal(t, "cart-not-found", attributeMap["album-store.error.code"].Emit())
+}
+
+func Test_problemCatalog_StableCodes(t *testing.T) {
+	codes := map[string]bool{}
+	for _, problem := range problemCatalog {
+		assert.False(t, codes[problem.code], "duplicate code %s", problem.code)
+		codes[problem.code] = true
+		assert.NotEmpty(t, problem.title)
+		assert.GreaterOrEqual(t, problem.status, 400)
+		assert.GreaterOrEqual(t, problem.legacyStatus, 400)
+	}
+	assert.Equal(t, problemAlbumNotFound.code, problemAlbumByIDNotFound.code)
+}
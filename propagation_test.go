@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func Test_propagator_Inbound(t *testing.T) {
+	propagator, _ := telemetry.NewPropagator("tracecontext,baggage,b3multi,jaeger")
+	otel.SetTextMapPropagator(propagator)
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	for _, test := range []struct {
+		name    string
+		headers map[string]string
+	}{
+		{name: "b3multi", headers: map[string]string{"X-B3-TraceId": "0af7651916cd43dd8448eb211c80319c", "X-B3-SpanId": "b7ad6b7169203331", "X-B3-Sampled": "1"}},
+		{name: "jaeger", headers: map[string]string{"uber-trace-id": "0af7651916cd43dd8448eb211c80319c:b7ad6b7169203331:0:1"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			testRecorder, spanRecorder, router := setupTestRouter(t)
+
+			req := httptest.NewRequest(http.MethodGet, "/status", nil)
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+			router.ServeHTTP(testRecorder, req)
+
+			finishedSpans := requestSpans(spanRecorder)
+			assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", finishedSpans[0].SpanContext().TraceID().String())
+			assert.Equal(t, "b7ad6b7169203331", finishedSpans[0].Parent().SpanID().String())
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_bodyCapture_Redacts_Album_Store_And_Proxy_Bodies(t *testing.T) {
+	telemetry.BodyCapture, _ = telemetry.NewBodyCapturePolicy(1, "", 0, "$..price")
+	defer func() { telemetry.BodyCapture = nil }()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`[{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}]`))),
+		}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Contains(t, testRecorder.Body.String(), `"price":66.6`)
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	redactedBody := `[{"artist":"Black Sabbath","id":10,"price":"[REDACTED]","title":"The Ozzman Cometh"}]`
+	assert.Equal(t, redactedBody, attributeMap["album-store.response.body"].Emit())
+	assert.Equal(t, redactedBody, attributeMap["proxy-service.response.body"].Emit())
+}
+
+func Test_bodyCapture_Route_Disabled(t *testing.T) {
+	telemetry.BodyCapture, _ = telemetry.NewBodyCapturePolicy(1, "/albums/:id=0", 0, "")
+	defer func() { telemetry.BodyCapture = nil }()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}`))),
+		}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/10", nil)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	assert.NotContains(t, attributeMap, "album-store.response.body")
+	assert.NotContains(t, attributeMap, "proxy-service.response.body")
+	assert.Equal(t, "200", attributeMap["album-store.response.code"].Emit())
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// proxyAlbumStore forwards the request path to album-store, passing through the JSON body when hasBody is set.
+func proxyAlbumStore(c *gin.Context, methodName string, hasBody bool) {
+	span := trace.SpanFromContext(c.Request.Context())
+	for _, param := range c.Params {
+		telemetry.SetRequestParameters(span, param.Key+"="+param.Value)
+		// path params are expected to be numbers so fail if cannot covert to integer
+		_, err := strconv.Atoi(param.Value)
+		if buildErrorInvalidRequestParameters(c, err, param.Value, span) {
+			return
+		}
+	}
+	var requestBody io.Reader
+	if hasBody {
+		requestBodyString, failed := processRequestBody(c, span, c.Request.Body, new(interface{}))
+		if failed {
+			return
+		}
+		requestBody = strings.NewReader(requestBodyString)
+	}
+	// proxy call to album-Store
+	resp, err := Send(c.Request.Context(), c.Request.Method, albumStoreURL+c.Request.URL.Path, "application/json", requestBody, forwardedHeaders(c))
+	setResponseCodeIfPresent(resp, span)
+	if handleResponseHasError(c, err, methodName, span) {
+		return
+	}
+	albumStoreResponseBodyJson, failed := processResponseBody(c, span, resp.Body)
+	if failed {
+		return
+	}
+	if handleResponseCodeHasError(c, resp, albumStoreResponseBodyJson, methodName, span) {
+		return
+	}
+	telemetry.SetResponseCode(span, resp.StatusCode)
+	span.SetStatus(codes.Ok, "")
+	_, stage := telemetry.StartStage(c.Request.Context(), "serialize response")
+	c.JSON(resp.StatusCode, albumStoreResponseBodyJson)
+	telemetry.EndStage(stage, false)
+}
+
+// CreateCart godoc
+// @Summary Create cart
+// @Schemes
+// @Description create a new empty shopping cart
+// @Tags carts
+// @Produce json
+// @Success 201 {object} model.Cart
+// @Failure 500 {object} model.ServerError
+// @Failure 502 {object} model.Problem
+// @Router /carts [post]
+func createCart(c *gin.Context) {
+	proxyAlbumStore(c, "createCart", false)
+}
+
+// GetCartById godoc
+// @Summary Get cart by id
+// @Schemes
+// @Description get a shopping cart with its lines and computed total
+// @Tags carts
+// @Param  id path int true  "cart id" minimum(1)
+// @Produce json
+// @Success 200 {object} model.Cart
+// @Failure 400 {object} model.ServerError
+// @Failure 404 {object} model.ServerError
+// @Failure 500 {object} model.ServerError
+// @Failure 502 {object} model.Problem
+// @Router /carts/{id} [get]
+func getCartByID(c *gin.Context) {
+	proxyAlbumStore(c, "getCartById", false)
+}
+
+// AddCartLine godoc
+// @Summary Add album to cart
+// @Schemes
+// @Description add an album to a cart, increasing the quantity if the album is already in the cart
+// @Tags carts
+// @Param  id path int true  "cart id" minimum(1)
+// @Param request body model.CartLineRequest true "cart line"
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Cart
+// @Failure 400 {object} model.ServerError
+// @Failure 404 {object} model.ServerError
+// @Failure 500 {object} model.ServerError
+// @Failure 502 {object} model.Problem
+// @Router /carts/{id}/lines [post]
+func addCartLine(c *gin.Context) {
+	proxyAlbumStore(c, "addCartLine", true)
+}
+
+// RemoveCartLine godoc
+// @Summary Remove album from cart
+// @Schemes
+// @Description remove an album line from a cart
+// @Tags carts
+// @Param  id path int true  "cart id" minimum(1)
+// @Param  albumId path int true  "album id" minimum(1)
+// @Produce json
+// @Success 200 {object} model.Cart
+// @Failure 400 {object} model.ServerError
+// @Failure 404 {object} model.ServerError
+// @Failure 500 {object} model.ServerError
+// @Failure 502 {object} model.Problem
+// @Router /carts/{id}/lines/{albumId} [delete]
+func removeCartLine(c *gin.Context) {
+	proxyAlbumStore(c, "removeCartLine", false)
+}
+
+// PostOrder godoc
+// @Summary Checkout cart
+// @Schemes
+// @Description checkout a cart, charging the payment processor and creating a paid order
+// @Tags orders
+// @Param request body model.OrderRequest true "order"
+// @Accept json
+// @Produce json
+// @Success 201 {object} model.Order
+// @Failure 400 {object} model.ServerError
+// @Failure 402 {object} model.ServerError
+// @Failure 404 {object} model.ServerError
+// @Failure 500 {object} model.ServerError
+// @Failure 502 {object} model.Problem
+// @Router /orders [post]
+func postOrder(c *gin.Context) {
+	proxyAlbumStore(c, "postOrder", true)
+}
+
+// GetOrderById godoc
+// @Summary Get order by id
+// @Schemes
+// @Description get an order and its current status
+// @Tags orders
+// @Param  id path int true  "order id" minimum(1)
+// @Produce json
+// @Success 200 {object} model.Order
+// @Failure 400 {object} model.ServerError
+// @Failure 404 {object} model.ServerError
+// @Failure 500 {object} model.ServerError
+// @Failure 502 {object} model.Problem
+// @Router /orders/{id} [get]
+func getOrderByID(c *gin.Context) {
+	proxyAlbumStore(c, "getOrderById", false)
+}
+
+// PatchOrder godoc
+// @Summary Update order status
+// @Schemes
+// @Description move an order through its lifecycle: pending -> paid -> shipped, or cancelled from pending or paid
+// @Tags orders
+// @Param  id path int true  "order id" minimum(1)
+// @Param request body model.OrderStatusRequest true "order status"
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Order
+// @Failure 400 {object} model.ServerError
+// @Failure 404 {object} model.ServerError
+// @Failure 409 {object} model.ServerError
+// @Failure 500 {object} model.ServerError
+// @Failure 502 {object} model.Problem
+// @Router /orders/{id} [patch]
+func patchOrder(c *gin.Context) {
+	proxyAlbumStore(c, "patchOrder", true)
+}
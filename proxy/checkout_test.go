@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func Test_postOrder_Success(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	DefaultClient = &MockClient{}
+
+	requestBody := `{"cartId":1}`
+	responseBody := `{"cartId":1,"id":1,"lines":[{"albumId":1,"lineTotal":113.98,"price":56.99,"quantity":2,"title":"Blue Train"}],"paymentId":"fake-payment-1","status":"paid","total":113.98}`
+
+	var proxiedRequest *http.Request
+	var proxiedBody string
+	MockResponseFunc = func(req *http.Request) (*http.Response, error) {
+		proxiedRequest = req
+		bodyBytes, _ := io.ReadAll(req.Body)
+		proxiedBody = string(bodyBytes)
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       io.NopCloser(bytes.NewReader([]byte(responseBody))),
+		}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(requestBody))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusCreated, testRecorder.Code)
+	assert.Equal(t, responseBody, testRecorder.Body.String())
+
+	assert.Equal(t, http.MethodPost, proxiedRequest.Method)
+	assert.Equal(t, albumStoreURL+"/orders", proxiedRequest.URL.String())
+	assert.Equal(t, "application/json", proxiedRequest.Header.Get("Content-Type"))
+	assert.Equal(t, requestBody, proxiedBody)
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, codes.Ok, finishedSpans[0].Status().Code)
+
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "201", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "201", attributeMap["album-store.response.code"].Emit())
+	assert.Equal(t, requestBody, attributeMap["proxy-service.request.body"].Emit())
+}
+
+func Test_removeCartLine_Success(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	DefaultClient = &MockClient{}
+
+	responseBody := `{"id":1,"lines":[],"total":0}`
+
+	var proxiedRequest *http.Request
+	MockResponseFunc = func(req *http.Request) (*http.Response, error) {
+		proxiedRequest = req
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte(responseBody))),
+		}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/carts/1/lines/2", nil)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, responseBody, testRecorder.Body.String())
+	assert.Equal(t, http.MethodDelete, proxiedRequest.Method)
+	assert.Equal(t, albumStoreURL+"/carts/1/lines/2", proxiedRequest.URL.String())
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, "/carts/:id/lines/:albumId", finishedSpans[0].Name())
+}
+
+func Test_getCartById_Failure_BadId(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	DefaultClient = &MockClient{}
+
+	//Mock not used so setup as ignored
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		return nil, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/carts/X", nil)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":null,"message":"error invalid ID [X] requested"}`, testRecorder.Body.String())
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
+}
+
+func Test_patchOrder_Failure_Album_Returns_Error(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	DefaultClient = &MockClient{}
+
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("ERROR FROM WEB SERVER")
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, "/orders/1", strings.NewReader(`{"status":"shipped"}`))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusInternalServerError, testRecorder.Code)
+	assert.Equal(t, `{"errors":null,"message":"error contacting album-store patchOrder ERROR FROM WEB SERVER"}`, testRecorder.Body.String())
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
+}
@@ -0,0 +1,188 @@
+// Package client is a typed Go client for the album-store API, for services calling album-store instead of hand-rolling HTTP calls.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mcarr-and/go-gin-otelcollector/proxy-service/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const problemContentType = "application/problem+json"
+
+// OtelHttpClient sends the requests, otelhttp.DefaultClient adds a client span around each and propagates the trace to album-store.
+type OtelHttpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// AlbumStoreClient calls album-store at baseURL. Album-store's response code and body are recorded on the span in the request context.
+type AlbumStoreClient struct {
+	baseURL               string
+	httpClient            OtelHttpClient
+	bodyAttribute         BodyAttributeFunc
+	responseCodeAttribute ResponseCodeAttributeFunc
+}
+
+// BodyAttributeFunc records body on span under key, so callers can limit or redact the bodies recorded.
+type BodyAttributeFunc func(ctx context.Context, span trace.Span, key string, body string)
+
+// ResponseCodeAttributeFunc records album-store's response status code on span, so callers can choose the attribute keys.
+type ResponseCodeAttributeFunc func(span trace.Span, statusCode int)
+
+func NewAlbumStoreClient(baseURL string, httpClient OtelHttpClient) *AlbumStoreClient {
+	return &AlbumStoreClient{baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: httpClient, bodyAttribute: recordBody, responseCodeAttribute: recordResponseCode}
+}
+
+// WithBodyAttribute records album-store response bodies with bodyAttribute instead of in full.
+func (albumStoreClient *AlbumStoreClient) WithBodyAttribute(bodyAttribute BodyAttributeFunc) *AlbumStoreClient {
+	albumStoreClient.bodyAttribute = bodyAttribute
+	return albumStoreClient
+}
+
+// WithResponseCodeAttribute records album-store response status codes with responseCodeAttribute instead of as album-store.response.code.
+func (albumStoreClient *AlbumStoreClient) WithResponseCodeAttribute(responseCodeAttribute ResponseCodeAttributeFunc) *AlbumStoreClient {
+	albumStoreClient.responseCodeAttribute = responseCodeAttribute
+	return albumStoreClient
+}
+
+func recordBody(_ context.Context, span trace.Span, key string, body string) {
+	span.SetAttributes(attribute.Key(key).String(body))
+}
+
+func recordResponseCode(span trace.Span, statusCode int) {
+	span.SetAttributes(attribute.Key("album-store.response.code").Int(statusCode))
+}
+
+// ResponseError is an error status from album-store. It wraps the album-store model.ServerError,
+// Problem is also set when album-store answered with application/problem+json. An error body that is not JSON,
+// e.g. from a load balancer in front of album-store, leaves ServerError empty and is kept in Body.
+type ResponseError struct {
+	StatusCode  int
+	Body        []byte
+	ServerError model.ServerError
+	Problem     *model.Problem
+}
+
+// NewResponseError reads an album-store error body, either a legacy model.ServerError or a problem.
+func NewResponseError(statusCode int, header http.Header, body []byte) *ResponseError {
+	responseError := &ResponseError{StatusCode: statusCode, Body: body}
+	if strings.HasPrefix(header.Get("Content-Type"), problemContentType) {
+		var problem model.Problem
+		if err := json.Unmarshal(body, &problem); err == nil && problem.Code != "" {
+			responseError.Problem = &problem
+			responseError.ServerError = model.ServerError{Message: problem.Detail, BindingErrors: problem.BindingErrors}
+			return responseError
+		}
+	}
+	_ = json.Unmarshal(body, &responseError.ServerError)
+	return responseError
+}
+
+func (responseError *ResponseError) Error() string {
+	return fmt.Sprintf("album-store returned %d %s", responseError.StatusCode, responseError.ServerError.Message)
+}
+
+func (responseError *ResponseError) Unwrap() error {
+	return &responseError.ServerError
+}
+
+// MalformedResponseError is a successful response from album-store that is not JSON or does not decode into the expected model.
+type MalformedResponseError struct {
+	StatusCode int
+	Body       []byte
+	Err        error
+}
+
+func (malformedError *MalformedResponseError) Error() string {
+	return fmt.Sprintf("album-store returned malformed JSON %v", malformedError.Err)
+}
+
+func (malformedError *MalformedResponseError) Unwrap() error {
+	return malformedError.Err
+}
+
+// ListAlbums gets all the albums. header is copied onto the request, e.g. Idempotency-Key or Accept-Language, and may be nil.
+func (albumStoreClient *AlbumStoreClient) ListAlbums(ctx context.Context, header http.Header) ([]model.Album, error) {
+	var albums []model.Album
+	err := albumStoreClient.send(ctx, http.MethodGet, "/albums", nil, header, &albums)
+	return albums, err
+}
+
+// GetAlbum gets the album with id.
+func (albumStoreClient *AlbumStoreClient) GetAlbum(ctx context.Context, id int, header http.Header) (model.Album, error) {
+	var album model.Album
+	err := albumStoreClient.send(ctx, http.MethodGet, fmt.Sprintf("/albums/%d", id), nil, header, &album)
+	return album, err
+}
+
+// CreateAlbum adds album to the store, returning the album as stored. Validation is done by album-store.
+func (albumStoreClient *AlbumStoreClient) CreateAlbum(ctx context.Context, album model.Album, header http.Header) (model.Album, error) {
+	var createdAlbum model.Album
+	err := albumStoreClient.send(ctx, http.MethodPost, "/albums", album, header, &createdAlbum)
+	return createdAlbum, err
+}
+
+// CreateAlbumJson adds the album in albumJson to the store, sending the bytes unchanged so album-store's strict decoding
+// sees unknown and duplicate fields a decoded model.Album would drop.
+func (albumStoreClient *AlbumStoreClient) CreateAlbumJson(ctx context.Context, albumJson []byte, header http.Header) (model.Album, error) {
+	var createdAlbum model.Album
+	err := albumStoreClient.send(ctx, http.MethodPost, "/albums", json.RawMessage(albumJson), header, &createdAlbum)
+	return createdAlbum, err
+}
+
+// send makes the request and decodes a 2xx JSON response into response. A json.RawMessage request is sent as is.
+// Errors from the OtelHttpClient are returned unchanged, an error status is a *ResponseError, whatever its body,
+// and a 2xx body that cannot be decoded a *MalformedResponseError.
+func (albumStoreClient *AlbumStoreClient) send(ctx context.Context, method string, path string, request interface{}, header http.Header, response interface{}) error {
+	var body io.Reader
+	switch request := request.(type) {
+	case nil:
+	case json.RawMessage:
+		body = bytes.NewReader(request)
+	default:
+		requestJson, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(requestJson)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, albumStoreClient.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := albumStoreClient.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	span := trace.SpanFromContext(ctx)
+	albumStoreClient.responseCodeAttribute(span, resp.StatusCode)
+	responseBody, err := io.ReadAll(resp.Body)
+	albumStoreClient.bodyAttribute(ctx, span, "album-store.response.body", string(responseBody))
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return NewResponseError(resp.StatusCode, resp.Header, responseBody)
+	}
+	if err != nil {
+		return &MalformedResponseError{StatusCode: resp.StatusCode, Body: responseBody, Err: err}
+	}
+	if !json.Valid(responseBody) {
+		return &MalformedResponseError{StatusCode: resp.StatusCode, Body: responseBody, Err: fmt.Errorf("invalid JSON %q", responseBody)}
+	}
+	if err := json.Unmarshal(responseBody, response); err != nil {
+		return &MalformedResponseError{StatusCode: resp.StatusCode, Body: responseBody, Err: err}
+	}
+	return nil
+}
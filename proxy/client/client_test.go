@@ -0,0 +1,179 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/mcarr-and/go-gin-otelcollector/proxy-service/model"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// mockClient answers every request with the response or error, keeping the request it was sent.
+type mockClient struct {
+	request    *http.Request
+	statusCode int
+	header     http.Header
+	body       string
+	err        error
+}
+
+func (m *mockClient) Do(req *http.Request) (*http.Response, error) {
+	m.request = req
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &http.Response{StatusCode: m.statusCode, Header: m.header, Body: io.NopCloser(bytes.NewReader([]byte(m.body)))}, nil
+}
+
+func Test_GetAlbum(t *testing.T) {
+	httpClient := &mockClient{statusCode: http.StatusOK, body: `{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}`}
+
+	album, err := NewAlbumStoreClient("http://album-store:9080/", httpClient).GetAlbum(context.Background(), 10, http.Header{"Accept-Language": {"fr"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.Album{ID: 10, Title: "The Ozzman Cometh", Artist: "Black Sabbath", Price: 66.6}, album)
+	assert.Equal(t, "http://album-store:9080/albums/10", httpClient.request.URL.String())
+	assert.Equal(t, "fr", httpClient.request.Header.Get("Accept-Language"))
+}
+
+func Test_ListAlbums(t *testing.T) {
+	httpClient := &mockClient{statusCode: http.StatusOK, body: `[{"id":1,"title":"Blue Train","artist":"John Coltrane","price":56.99}]`}
+
+	albums, err := NewAlbumStoreClient("http://album-store:9080", httpClient).ListAlbums(context.Background(), nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Album{{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 56.99}}, albums)
+	assert.Equal(t, http.MethodGet, httpClient.request.Method)
+}
+
+func Test_CreateAlbum(t *testing.T) {
+	httpClient := &mockClient{statusCode: http.StatusCreated, body: `{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}`}
+	album := model.Album{ID: 10, Title: "The Ozzman Cometh", Artist: "Black Sabbath", Price: 66.6}
+
+	createdAlbum, err := NewAlbumStoreClient("http://album-store:9080", httpClient).CreateAlbum(context.Background(), album, http.Header{"Idempotency-Key": {"retry-1"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, album, createdAlbum)
+	requestBody, _ := io.ReadAll(httpClient.request.Body)
+	assert.Equal(t, `{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}`, string(requestBody))
+	assert.Equal(t, "application/json", httpClient.request.Header.Get("Content-Type"))
+	assert.Equal(t, "retry-1", httpClient.request.Header.Get("Idempotency-Key"))
+}
+
+func Test_CreateAlbumJson(t *testing.T) {
+	httpClient := &mockClient{statusCode: http.StatusCreated, body: `{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}`}
+	albumJson := `{"id":10, "title":"Ozzman", "title":"The Ozzman Cometh", "artist":"Black Sabbath", "price":66.6, "label":"Epic"}`
+
+	createdAlbum, err := NewAlbumStoreClient("http://album-store:9080", httpClient).CreateAlbumJson(context.Background(), []byte(albumJson), nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "The Ozzman Cometh", createdAlbum.Title)
+	requestBody, _ := io.ReadAll(httpClient.request.Body)
+	assert.Equal(t, albumJson, string(requestBody))
+	assert.Equal(t, "application/json", httpClient.request.Header.Get("Content-Type"))
+}
+
+func Test_CreateAlbum_ResponseError(t *testing.T) {
+	httpClient := &mockClient{statusCode: http.StatusBadRequest, body: `{"errors":[{"field":"title","message":"is required"}],"message":""}`}
+
+	_, err := NewAlbumStoreClient("http://album-store:9080", httpClient).CreateAlbum(context.Background(), model.Album{}, nil)
+
+	var responseError *ResponseError
+	assert.True(t, errors.As(err, &responseError))
+	assert.Equal(t, http.StatusBadRequest, responseError.StatusCode)
+	assert.Nil(t, responseError.Problem)
+	var serverError *model.ServerError
+	assert.True(t, errors.As(err, &serverError))
+	assert.Equal(t, []*model.BindingErrorMsg{{Field: "title", Message: "is required"}}, serverError.BindingErrors)
+}
+
+func Test_GetAlbum_ResponseError_Problem(t *testing.T) {
+	httpClient := &mockClient{
+		statusCode: http.StatusNotFound,
+		header:     http.Header{"Content-Type": {"application/problem+json"}},
+		body:       `{"type":"/problems/album-not-found","title":"Album not found","status":404,"detail":"Album [99] not found","code":"album-not-found"}`,
+	}
+
+	_, err := NewAlbumStoreClient("http://album-store:9080", httpClient).GetAlbum(context.Background(), 99, nil)
+
+	var responseError *ResponseError
+	assert.True(t, errors.As(err, &responseError))
+	assert.Equal(t, "album-not-found", responseError.Problem.Code)
+	assert.Equal(t, "Album [99] not found", responseError.ServerError.Message)
+	assert.Equal(t, "album-store returned 404 Album [99] not found", err.Error())
+}
+
+func Test_GetAlbum_Malformed_Response(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	ctx, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)).Tracer("test").Start(context.Background(), "test")
+	httpClient := &mockClient{statusCode: http.StatusOK, body: `{"id":10,`}
+
+	_, err := NewAlbumStoreClient("http://album-store:9080", httpClient).GetAlbum(ctx, 10, nil)
+	span.End()
+
+	var malformedError *MalformedResponseError
+	assert.True(t, errors.As(err, &malformedError))
+	assert.Equal(t, `{"id":10,`, string(malformedError.Body))
+	attributes := spanRecorder.Ended()[0].Attributes()
+	assert.Contains(t, attributes, attribute.Key("album-store.response.code").Int(http.StatusOK))
+	assert.Contains(t, attributes, attribute.Key("album-store.response.body").String(`{"id":10,`))
+}
+
+func Test_GetAlbum_ResponseError_Not_Json(t *testing.T) {
+	httpClient := &mockClient{statusCode: http.StatusBadGateway, body: `<html>bad gateway</html>`}
+
+	_, err := NewAlbumStoreClient("http://album-store:9080", httpClient).GetAlbum(context.Background(), 10, nil)
+
+	var responseError *ResponseError
+	assert.True(t, errors.As(err, &responseError))
+	assert.Equal(t, http.StatusBadGateway, responseError.StatusCode)
+	assert.Equal(t, `<html>bad gateway</html>`, string(responseError.Body))
+	assert.Equal(t, model.ServerError{}, responseError.ServerError)
+}
+
+func Test_GetAlbum_Unavailable(t *testing.T) {
+	unavailable := errors.New("connection refused")
+
+	_, err := NewAlbumStoreClient("http://album-store:9080", &mockClient{err: unavailable}).GetAlbum(context.Background(), 10, nil)
+
+	assert.Equal(t, unavailable, err)
+}
+
+func Test_WithBodyAttribute(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	ctx, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)).Tracer("test").Start(context.Background(), "test")
+	httpClient := &mockClient{statusCode: http.StatusOK, body: `{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}`}
+	redactBody := func(ctx context.Context, span trace.Span, key string, body string) {
+		span.SetAttributes(attribute.Key(key).String("[REDACTED]"))
+	}
+
+	_, err := NewAlbumStoreClient("http://album-store:9080", httpClient).WithBodyAttribute(redactBody).GetAlbum(ctx, 10, nil)
+	span.End()
+
+	assert.NoError(t, err)
+	assert.Contains(t, spanRecorder.Ended()[0].Attributes(), attribute.Key("album-store.response.body").String("[REDACTED]"))
+}
+
+func Test_WithResponseCodeAttribute(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	ctx, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)).Tracer("test").Start(context.Background(), "test")
+	httpClient := &mockClient{statusCode: http.StatusOK, body: `{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}`}
+	responseCode := func(span trace.Span, statusCode int) {
+		span.SetAttributes(attribute.Key("upstream.status").Int(statusCode))
+	}
+
+	_, err := NewAlbumStoreClient("http://album-store:9080", httpClient).WithResponseCodeAttribute(responseCode).GetAlbum(ctx, 10, nil)
+	span.End()
+
+	assert.NoError(t, err)
+	assert.Contains(t, spanRecorder.Ended()[0].Attributes(), attribute.Key("upstream.status").Int(http.StatusOK))
+	assert.NotContains(t, spanRecorder.Ended()[0].Attributes(), attribute.Key("album-store.response.code").Int(http.StatusOK))
+}
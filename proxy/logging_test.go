@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_requestLogger_TraceContext(t *testing.T) {
+	var logOutput bytes.Buffer
+	spanRecorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	router := setupRouter(zerolog.New(&logOutput))
+	router.Use(otelgin.Middleware("test-otel"))
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("ERROR FROM WEB SERVER")
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/albums", nil))
+
+	logLines := readLogLines(t, &logOutput)
+	assert.Len(t, logLines, 3)
+	spanContext := requestSpans(spanRecorder)[0].SpanContext()
+	for _, logLine := range logLines {
+		assert.Equal(t, spanContext.TraceID().String(), logLine["trace_id"])
+		assert.Equal(t, spanContext.SpanID().String(), logLine["span_id"])
+	}
+	assert.Equal(t, "warn", logLines[0]["level"])
+	assert.Equal(t, "album-store request getAlbums failed", logLines[0]["message"])
+	assert.Equal(t, "ERROR FROM WEB SERVER", logLines[0]["error"])
+	assert.Equal(t, "error", logLines[1]["level"])
+	assert.Equal(t, "upstream-unavailable", logLines[1]["code"])
+	assert.Equal(t, "error", logLines[2]["level"])
+	assert.Equal(t, "request handled", logLines[2]["message"])
+	assert.Equal(t, "/albums", logLines[2]["route"])
+}
+
+// readLogLines reads the JSON log lines written to output.
+func readLogLines(t *testing.T, output *bytes.Buffer) []map[string]interface{} {
+	var logLines []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(output.Bytes()), []byte("\n")) {
+		var logLine map[string]interface{}
+		assert.NoError(t, json.Unmarshal(line, &logLine))
+		logLines = append(logLines, logLine)
+	}
+	return logLines
+}
@@ -3,20 +3,16 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	_ "github.com/mcarr-and/go-gin-otelcollector/proxy-service/api"
+	"github.com/mcarr-and/go-gin-otelcollector/proxy-service/client"
 	"github.com/mcarr-and/go-gin-otelcollector/proxy-service/model"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
-	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	"go.opentelemetry.io/otel/semconv/v1.17.0"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 	"io"
@@ -31,14 +27,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
-	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
-type OtelHttpClient interface {
-	Do(req *http.Request) (*http.Response, error)
-}
+type OtelHttpClient = client.OtelHttpClient
 
 var (
 	DefaultClient OtelHttpClient
@@ -46,6 +39,7 @@ var (
 
 func init() {
 	DefaultClient = otelhttp.DefaultClient
+	telemetry.ServiceName = serviceName
 }
 
 // @title           Proxy Service API
@@ -55,36 +49,34 @@ func init() {
 // @license.url   http://www.apache.org/licenses/LICENSE-2.0.html
 // @host      localhost:9070
 // @BasePath /
+// @schemes http
 
 // GetAlbums godoc
 // @Summary Get all Albums
 // @Schemes
 // @Description get all the albums in the store
 // @Tags albums
-// @Produce json
+// @Produce json,application/xml,application/x-yaml,application/msgpack,application/x-protobuf
 // @Success 200 {array} model.Album
+// @Failure 400 {object} model.ServerError
+// @Failure 406 {object} model.ServerError
 // @Failure 500 {object} model.ServerError
+// @Failure 502 {object} model.Problem
 // @Router /albums [get]
 func getAlbums(c *gin.Context) {
 	span := trace.SpanFromContext(c.Request.Context())
-	span.SetName("/albums GET")
-	defer span.End()
-	// proxy call to album-Store
-	resp, err := Get(c.Request.Context(), albumStoreURL+"/albums")
-	setResponseCodeIfPresent(resp, span)
-	if handleResponseHasError(c, err, "getAlbums", span) {
-		return
-	}
-	albumStoreResponseBodyJson, failed := processResponseBody(c, span, resp.Body)
+	format, failed := negotiateAlbumFormat(c, span)
 	if failed {
 		return
 	}
-	if handleResponseCodeHasError(c, resp.StatusCode, "getAlbums", span) {
+	albums, err := albumStore().ListAlbums(c.Request.Context(), forwardedHeaders(c))
+	if handleAlbumStoreError(c, err, "getAlbums", span) {
 		return
 	}
-	span.SetAttributes(attribute.Key("proxy-service.response.code").Int(http.StatusOK))
+	setResponseBody(c, span, albums)
+	telemetry.SetResponseCode(span, http.StatusOK)
 	span.SetStatus(codes.Ok, "")
-	c.JSON(http.StatusOK, albumStoreResponseBodyJson)
+	renderAlbums(c, http.StatusOK, format, albums)
 }
 
 // GetAlbumById godoc
@@ -92,39 +84,36 @@ func getAlbums(c *gin.Context) {
 // @Schemes
 // @Description get as single album by id
 // @Tags albums
-// @Param  id query int true  "int valid" minimum(1)
-// @Produce json
+// @Param  id path int true  "album id" minimum(1)
+// @Produce json,application/xml,application/x-yaml,application/msgpack,application/x-protobuf
 // @Success 200 {object} model.Album
 // @Failure 400 {object} model.ServerError
+// @Failure 404 {object} model.Problem
+// @Failure 406 {object} model.ServerError
 // @Failure 500 {object} model.ServerError
+// @Failure 502 {object} model.Problem
 // @Router /albums/{id} [get]
 func getAlbumByID(c *gin.Context) {
 	span := trace.SpanFromContext(c.Request.Context())
-	span.SetName("/albums/:id GET")
-	defer span.End()
+	format, failed := negotiateAlbumFormat(c, span)
+	if failed {
+		return
+	}
 	id := c.Param("id")
-	span.SetAttributes(attribute.Key("proxy-service.request.parameters").String(fmt.Sprintf("%s=%s", "ID", id)))
+	telemetry.SetRequestParameters(span, fmt.Sprintf("%s=%s", "ID", id))
 	albumID, err := strconv.Atoi(id)
 	// param ID is expected to be a number so fail if cannot covert to integer
 	if buildErrorInvalidRequestParameters(c, err, id, span) {
 		return
 	}
-	// proxy call to album-Store
-	resp, err := Get(c.Request.Context(), fmt.Sprintf("%v/albums/%v", albumStoreURL, albumID))
-	setResponseCodeIfPresent(resp, span)
-	if handleResponseHasError(c, err, "getAlbumById", span) {
+	album, err := albumStore().GetAlbum(c.Request.Context(), albumID, forwardedHeaders(c))
+	if handleAlbumStoreError(c, err, "getAlbumById", span) {
 		return
 	}
-	albumStoreResponseBodyJson, failed := processResponseBody(c, span, resp.Body)
-	if failed {
-		return
-	}
-	if handleResponseCodeHasError(c, resp.StatusCode, "getAlbumById", span) {
-		return
-	}
-	span.SetAttributes(attribute.Key("proxy-service.response.code").Int(http.StatusOK))
+	setResponseBody(c, span, album)
+	telemetry.SetResponseCode(span, http.StatusOK)
 	span.SetStatus(codes.Ok, "")
-	c.JSON(http.StatusOK, albumStoreResponseBodyJson)
+	renderAlbums(c, http.StatusOK, format, album)
 }
 
 // PostAlbum godoc
@@ -133,57 +122,107 @@ func getAlbumByID(c *gin.Context) {
 // @Description add a new album to the store
 // @Tags albums
 // @Param request body model.Album true "album"
-// @Accept json
-// @Produce json
+// @Param Idempotency-Key header string false "replay the first response for retries of the same request"
+// @Accept json,application/xml,application/x-yaml,application/msgpack,application/x-protobuf
+// @Produce json,application/xml,application/x-yaml,application/msgpack,application/x-protobuf
 // @Success 201 {object} model.Album
 // @Failure 400 {object} model.ServerError
+// @Failure 406 {object} model.ServerError
+// @Failure 409 {object} model.ServerError
+// @Failure 413 {object} model.ServerError
+// @Failure 415 {object} model.ServerError
+// @Failure 422 {object} model.ServerError
 // @Failure 500 {object} model.ServerError
+// @Failure 502 {object} model.Problem
 // @Router /albums [post]
 func postAlbum(c *gin.Context) {
 	span := trace.SpanFromContext(c.Request.Context())
-	span.SetName("/albums POST")
-	defer span.End()
-	requestBodyString, failed := processRequestBody(c, span, c.Request.Body)
+	requestFormat, failed := requestAlbumFormat(c, span)
 	if failed {
 		return
 	}
-	// proxy call to album-Store
-	resp, err := Post(c.Request.Context(), albumStoreURL+"/albums", "application/json", strings.NewReader(fmt.Sprintf("%v", requestBodyString)))
-	setResponseCodeIfPresent(resp, span)
-	if handleResponseHasError(c, err, "postAlbum", span) {
-		return
-	}
-	albumStoreResponseBodyJson, failed := processResponseBody(c, span, resp.Body)
+	responseFormat, failed := negotiateAlbumFormat(c, span)
 	if failed {
 		return
 	}
-	if handleResponseCodeHasError(c, resp.StatusCode, "postAlbum", span) {
+	var createdAlbum model.Album
+	var err error
+	if requestFormat.name == jsonAlbumFormat.name {
+		// the JSON body is forwarded unchanged so album-store rejects unknown and duplicate fields
+		var requestBodyString string
+		if requestBodyString, failed = processRequestBody(c, span, c.Request.Body, new(interface{})); failed {
+			return
+		}
+		createdAlbum, err = albumStore().CreateAlbumJson(c.Request.Context(), []byte(requestBodyString), forwardedHeaders(c))
+	} else {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			buildMalformedRequestJsonErrorResponse(c, span, string(body), fmt.Sprintf("request body could not be read: %v", err))
+			return
+		}
+		var album model.Album
+		if album, failed = processFormattedRequestBody(c, span, requestFormat, body); failed {
+			return
+		}
+		createdAlbum, err = albumStore().CreateAlbum(c.Request.Context(), album, forwardedHeaders(c))
+	}
+	if handleAlbumStoreError(c, err, "postAlbum", span) {
 		return
 	}
-	span.SetAttributes(attribute.Key("proxy-service.response.code").Int(http.StatusCreated))
+	setResponseBody(c, span, createdAlbum)
+	telemetry.SetResponseCode(span, http.StatusCreated)
 	span.SetStatus(codes.Ok, "")
-	c.JSON(http.StatusCreated, albumStoreResponseBodyJson)
+	renderAlbums(c, http.StatusCreated, responseFormat, createdAlbum)
+}
+
+// albumStore returns the album-store client, built per request so tests can swap DefaultClient.
+func albumStore() *client.AlbumStoreClient {
+	return client.NewAlbumStoreClient(albumStoreURL, DefaultClient).WithBodyAttribute(telemetry.SetBodyAttribute).WithResponseCodeAttribute(setAlbumStoreResponseCode)
+}
+
+// forwardedHeaders returns the inbound headers album-store needs to see, so a client retry through the proxy keeps its Idempotency-Key,
+// validation messages are in the client's language and a client wanting problem details gets album-store's error code.
+func forwardedHeaders(c *gin.Context) http.Header {
+	header := http.Header{}
+	if idempotencyKey := c.GetHeader("Idempotency-Key"); idempotencyKey != "" {
+		header.Set("Idempotency-Key", idempotencyKey)
+	}
+	if acceptLanguage := c.GetHeader("Accept-Language"); acceptLanguage != "" {
+		header.Set("Accept-Language", acceptLanguage)
+	}
+	if useProblemDetails(c) {
+		header.Set("Accept", "application/json, "+problemContentType)
+	}
+	return header
+}
+
+func setResponseBody(c *gin.Context, span trace.Span, response interface{}) {
+	responseJson, _ := json.Marshal(response)
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "proxy-service.response.body", string(responseJson))
 }
 
 func setResponseCodeIfPresent(resp *http.Response, span trace.Span) {
 	if resp != nil {
-		span.SetAttributes(attribute.Key("album-store.response.code").Int(resp.StatusCode))
+		setAlbumStoreResponseCode(span, resp.StatusCode)
 	}
 }
 
+// setAlbumStoreResponseCode records album-store's response to the proxy with the keys of the span convention.
+func setAlbumStoreResponseCode(span trace.Span, statusCode int) {
+	telemetry.SetUpstreamResponseCode(span, albumStoreServiceName, statusCode)
+}
+
 // Status godoc
 // @Summary Status of service
 // @Schemes
 // @Description get the status of the service
 // @Tags albums
 // @Produce json
-// @Success 200 {string} status
+// @Success 200 {object} map[string]string
 // @Router /status [get]
 func status(c *gin.Context) {
 	span := trace.SpanFromContext(c.Request.Context())
-	span.SetName("/status")
 	span.SetStatus(codes.Ok, "")
-	defer span.End()
 	c.JSON(http.StatusOK, gin.H{"status": "OK"})
 }
 
@@ -194,12 +233,10 @@ func status(c *gin.Context) {
 // @Tags albums
 // @Produce plain
 // @Success 200 {string} metrics
-// @Router /status [get]
+// @Router /metrics [get]
 func metrics(c *gin.Context) {
 	span := trace.SpanFromContext(c.Request.Context())
-	span.SetName("/metrics")
 	span.SetStatus(codes.Ok, "")
-	defer span.End()
 	promhttp.Handler().ServeHTTP(c.Writer, c.Request)
 }
 
@@ -208,7 +245,7 @@ func processResponseBody(c *gin.Context, span trace.Span, body io.ReadCloser) (i
 	byteArray, err := io.ReadAll(body)
 	jsonBodyString := string(byteArray[:])
 	if err = json.NewDecoder(strings.NewReader(jsonBodyString)).Decode(&jsonBody); err != nil {
-		buildMalformedResponseJsonErrorResponse(c, span, jsonBodyString, "error from album-store Malformed JSON returned", http.StatusInternalServerError)
+		buildMalformedResponseJsonErrorResponse(c, span, jsonBodyString, "error from album-store Malformed JSON returned")
 		return jsonBody, true
 	}
 
@@ -217,47 +254,46 @@ func processResponseBody(c *gin.Context, span trace.Span, body io.ReadCloser) (i
 		errorMessage := fmt.Sprintf("error album-store closing response %v", err)
 		span.AddEvent(errorMessage)
 		span.SetStatus(codes.Error, errorMessage)
-		span.SetAttributes(attribute.Key("http.response.code").Int(http.StatusInternalServerError))
-		c.AbortWithStatusJSON(http.StatusInternalServerError, model.ServerError{Message: errorMessage})
+		abortWithProblem(c, span, problemUpstreamBadResponse, errorMessage)
 		return jsonBody, true
 	}
-	span.SetAttributes(attribute.Key("album-store.response.body").String(jsonBodyString))
-	span.SetAttributes(attribute.Key("proxy-service.response.body").String(jsonBodyString))
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.response.body", jsonBodyString)
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "proxy-service.response.body", jsonBodyString)
 	return jsonBody, false
 }
 
-func processRequestBody(c *gin.Context, span trace.Span, reader io.ReadCloser) (string, bool) {
-	var requestBody interface{}
+// processRequestBody reads the JSON request body into target, writing a 400 when it is not JSON or does not decode into target.
+func processRequestBody(c *gin.Context, span trace.Span, reader io.ReadCloser, target interface{}) (string, bool) {
+	_, stage := telemetry.StartStage(c.Request.Context(), "parse request body")
 	byteArray, err := io.ReadAll(reader)
 	jsonBodyString := string(byteArray[:])
-	err = json.NewDecoder(strings.NewReader(jsonBodyString)).Decode(&requestBody)
-	span.SetAttributes(attribute.Key("proxy-service.request.body").String(jsonBodyString))
+	err = json.NewDecoder(strings.NewReader(jsonBodyString)).Decode(target)
+	telemetry.EndStage(stage, err != nil)
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "proxy-service.request.body", jsonBodyString)
 
 	if err != nil {
 		errorMessage := fmt.Sprintf("invalid request json body %v", jsonBodyString)
-		buildMalformedRequestJsonErrorResponse(c, span, jsonBodyString, errorMessage, http.StatusBadRequest)
-		span.SetAttributes(attribute.Key("proxy-service.response.body").String(fmt.Sprintf("{\"message\":\"%v\"}", errorMessage)))
+		buildMalformedRequestJsonErrorResponse(c, span, jsonBodyString, errorMessage)
+		telemetry.SetBodyAttribute(c.Request.Context(), span, "proxy-service.response.body", fmt.Sprintf("{\"message\":\"%v\"}", errorMessage))
 		return jsonBodyString, true
 	}
 	return jsonBodyString, false
 }
 
-func buildMalformedRequestJsonErrorResponse(c *gin.Context, span trace.Span, response string, errorMessage string, errorCode int) bool {
+func buildMalformedRequestJsonErrorResponse(c *gin.Context, span trace.Span, response string, errorMessage string) bool {
 	span.SetStatus(codes.Error, errorMessage)
 	span.AddEvent(errorMessage)
-	span.SetAttributes(attribute.Key("proxy-service.request.body").String(response))
-	span.SetAttributes(attribute.Key("proxy-service.response.code").Int(errorCode))
-	c.AbortWithStatusJSON(errorCode, model.ServerError{Message: errorMessage})
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "proxy-service.request.body", response)
+	abortWithProblem(c, span, problemMalformedBody, errorMessage)
 	return true
 }
 
-func buildMalformedResponseJsonErrorResponse(c *gin.Context, span trace.Span, response string, errorMessage string, errorCode int) bool {
+func buildMalformedResponseJsonErrorResponse(c *gin.Context, span trace.Span, response string, errorMessage string) bool {
 	span.SetStatus(codes.Error, errorMessage)
 	span.AddEvent(errorMessage)
-	span.SetAttributes(attribute.Key("album-store.response.body").String(response))
-	span.SetAttributes(attribute.Key("proxy-service.response.body").String(fmt.Sprintf(`{"message":"%v"}`, errorMessage)))
-	span.SetAttributes(attribute.Key("proxy-service.response.code").Int(errorCode))
-	c.AbortWithStatusJSON(errorCode, model.ServerError{Message: errorMessage})
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "album-store.response.body", response)
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "proxy-service.response.body", fmt.Sprintf(`{"message":"%v"}`, errorMessage))
+	abortWithProblem(c, span, problemUpstreamBadResponse, errorMessage)
 	return true
 }
 
@@ -266,54 +302,98 @@ func handleResponseHasError(c *gin.Context, err error, methodName string, span t
 		errorMessage := fmt.Sprintf("error contacting album-store %s %v", methodName, err)
 		span.AddEvent(errorMessage)
 		span.SetStatus(codes.Error, errorMessage)
-		span.SetAttributes(attribute.Key("proxy-service.response.body").String(fmt.Sprintf(`{"message":"%v"}`, errorMessage)))
-		span.SetAttributes(attribute.Key("proxy-service.response.code").Int(http.StatusInternalServerError))
-		c.AbortWithStatusJSON(http.StatusInternalServerError, model.ServerError{Message: errorMessage})
+		telemetry.SetBodyAttribute(c.Request.Context(), span, "proxy-service.response.body", fmt.Sprintf(`{"message":"%v"}`, errorMessage))
+		abortWithProblem(c, span, problemUpstreamUnavailable, errorMessage)
 		return true
 	}
 	return false
 }
 
-func handleResponseCodeHasError(c *gin.Context, responseCode int, methodName string, span trace.Span) bool {
-	if responseCode != http.StatusOK && responseCode != http.StatusCreated {
-		errorMessage := fmt.Sprintf("album-store returned error %s", methodName)
-		span.AddEvent(errorMessage)
-		span.SetStatus(codes.Error, errorMessage)
-		span.SetAttributes(attribute.Key("proxy-service.response.code").Int(responseCode))
-		c.AbortWithStatusJSON(responseCode, model.ServerError{Message: errorMessage})
+// handleResponseCodeHasError passes an album-store error on with album-store's status and error code.
+func handleResponseCodeHasError(c *gin.Context, resp *http.Response, albumStoreResponseBodyJson interface{}, methodName string, span trace.Span) bool {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		jsonBody, _ := json.Marshal(albumStoreResponseBodyJson)
+		writeUpstreamProblem(c, span, client.NewResponseError(resp.StatusCode, resp.Header, jsonBody), methodName)
 		return true
 	}
 	return false
 }
 
+// handleAlbumStoreError writes the error response for an error from the album-store client.
+func handleAlbumStoreError(c *gin.Context, err error, methodName string, span trace.Span) bool {
+	var responseError *client.ResponseError
+	var malformedError *client.MalformedResponseError
+	if err == nil {
+		return false
+	}
+	log := telemetry.RequestLogger(c)
+	log.Warn().Err(err).Msg(fmt.Sprintf("album-store request %s failed", methodName))
+	switch {
+	case errors.As(err, &malformedError):
+		buildMalformedResponseJsonErrorResponse(c, span, string(malformedError.Body), "error from album-store Malformed JSON returned")
+	case errors.As(err, &responseError):
+		telemetry.SetBodyAttribute(c.Request.Context(), span, "proxy-service.response.body", string(responseError.Body))
+		writeUpstreamProblem(c, span, responseError, methodName)
+	default:
+		handleResponseHasError(c, err, methodName, span)
+	}
+	return true
+}
+
+func writeUpstreamProblem(c *gin.Context, span trace.Span, responseError *client.ResponseError, methodName string) {
+	errorMessage := fmt.Sprintf("album-store returned error %s", methodName)
+	span.AddEvent(errorMessage)
+	span.SetStatus(codes.Error, errorMessage)
+	problem := upstreamProblem(responseError)
+	if !useProblemDetails(c) {
+		problem.Detail = errorMessage
+	}
+	writeProblem(c, span, problem)
+}
+
 func buildErrorInvalidRequestParameters(c *gin.Context, err error, id string, span trace.Span) bool {
 	if err != nil {
 		errorMessage := fmt.Sprintf("%s [%s] %s", "error invalid ID", id, "requested")
 		span.SetStatus(codes.Error, errorMessage)
 		span.AddEvent(errorMessage)
-		span.SetAttributes(attribute.Key("proxy-service.response.code").Int(http.StatusBadRequest))
-		span.SetAttributes(attribute.Key("proxy-service.response.body").String(fmt.Sprintf(`{"message":"%v"}`, errorMessage)))
-		c.AbortWithStatusJSON(http.StatusBadRequest, model.ServerError{Message: errorMessage})
+		telemetry.SetBodyAttribute(c.Request.Context(), span, "proxy-service.response.body", fmt.Sprintf(`{"message":"%v"}`, errorMessage))
+		abortWithProblem(c, span, problemInvalidID, errorMessage)
 		return true
 	}
 	return false
 }
 
-func setupRouter() *gin.Engine {
-	router := gin.Default()
-	router.Use(otelgin.Middleware(serviceName)) // add OpenTelemetry to Gin
+func setupRouter(log zerolog.Logger) *gin.Engine {
+	router := gin.New()
+	// gin.New rather than gin.Default, whose logger and recovery are not correlated with the request trace
+	router.Use(telemetry.Recovery())
+	router.Use(otelgin.Middleware(serviceName, otelgin.WithTracerProvider(telemetry.TracerProvider()))) // add OpenTelemetry to Gin
+	router.Use(telemetry.HttpSemconv())
+	router.Use(telemetry.RequestMetrics(nil))
+	router.Use(telemetry.RequestLogging(log))
+	router.Use(telemetry.BodyCaptureDecision())
+	router.Use(openAPIValidation())
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/v3/api-docs", getOpenAPIDocument)
 	router.GET("/albums", getAlbums)
 	router.GET("/albums/:id", getAlbumByID)
 	router.POST("/albums", postAlbum)
+	router.POST("/carts", createCart)
+	router.GET("/carts/:id", getCartByID)
+	router.POST("/carts/:id/lines", addCartLine)
+	router.DELETE("/carts/:id/lines/:albumId", removeCartLine)
+	router.POST("/orders", postOrder)
+	router.GET("/orders/:id", getOrderByID)
+	router.PATCH("/orders/:id", patchOrder)
 	router.GET("/status", status)
 	router.GET("/metrics", metrics)
 	return router
 }
 
 const (
-	serviceName  = "proxy-service"
-	startAddress = "0.0.0.0:9070"
+	serviceName           = "proxy-service"
+	startAddress          = "0.0.0.0:9070"
+	albumStoreServiceName = "album-store"
 )
 
 var version = "No-Version"
@@ -321,21 +401,42 @@ var gitHash = "No-Hash"
 var albumStoreURL = "http://localhost:9080"
 
 func main() {
-	proxyLog := zerolog.New(os.Stderr).With().Timestamp().Logger()
-	logInfo := zerolog.New(os.Stdout).With().Timestamp().Logger()
+	proxyLog := telemetry.NewLogger(os.Stderr)
+	logInfo := telemetry.NewLogger(os.Stdout)
 
 	logInfo.Info().Msg(fmt.Sprintf("version: %v-%v", version, gitHash))
-	shutdownTraceProvider, err := initOtelProvider(serviceName, version, gitHash, proxyLog)
+	telemetry.SpanConvention = telemetry.AttributeConventionFromEnv()
+	logInfo.Info().Msg(fmt.Sprintf("span attribute convention: %v", telemetry.SpanConvention))
+	shutdownOtelProviders, err := telemetry.InitOtelProvider(version, gitHash, proxyLog)
 	if err != nil {
 		proxyLog.Err(err)
 	}
+	// loggers from here on also export over OTLP when OTEL_LOGS_EXPORTER=otlp
+	proxyLog = telemetry.NewLogger(os.Stderr)
+	logInfo = telemetry.NewLogger(os.Stdout)
+	if telemetry.BodyCapture, err = telemetry.BodyCapturePolicyFromEnv(); err != nil {
+		proxyLog.Fatal().Msg(fmt.Sprintf("Env variable %v", err))
+	}
+	logInfo.Info().Msg(fmt.Sprintf("request and response bodies on spans: %v", telemetry.BodyCapture))
 
 	albumStoreUrlEnv := os.Getenv("ALBUM_STORE_URL")
 	if albumStoreURL != "" {
 		albumStoreURL = albumStoreUrlEnv
 	}
 
-	router := setupRouter()
+	strictResponseValidation = os.Getenv("OPENAPI_STRICT") == "true"
+	logInfo.Info().Msg(fmt.Sprintf("responses validated against the OpenAPI document: %v", strictResponseValidation))
+
+	switch errorFormat := os.Getenv("ERROR_FORMAT"); errorFormat {
+	case "", "legacy":
+	case "problem":
+		problemDetailsErrors = true
+	default:
+		proxyLog.Fatal().Msg(fmt.Sprintf("Env variable ERROR_FORMAT=%v must be legacy or problem", errorFormat))
+	}
+	logInfo.Info().Msg(fmt.Sprintf("errors sent as application/problem+json for all requests: %v", problemDetailsErrors))
+
+	router := setupRouter(logInfo)
 	//serve requests until termination signal is sent.
 
 	srv := &http.Server{
@@ -343,7 +444,7 @@ func main() {
 		Handler: h2c.NewHandler(router, &http2.Server{}),
 	}
 
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		// service connections
@@ -357,11 +458,11 @@ func main() {
 	ctxServer, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer cancel()
 
-	logInfo.Info().Msg("OpenTelemetry TraceProvider flushing & shutting down")
-	if err := shutdownTraceProvider(ctxServer); err != nil {
+	logInfo.Info().Msg("OpenTelemetry TraceProvider, MeterProvider & LoggerProvider flushing & shutting down")
+	if err := shutdownOtelProviders(ctxServer); err != nil {
 		proxyLog.Err(err)
 	}
-	logInfo.Info().Msg("OpenTelemetry TraceProvider exited")
+	logInfo.Info().Msg("OpenTelemetry TraceProvider, MeterProvider & LoggerProvider exited")
 
 	if err := srv.Shutdown(ctxServer); err != nil {
 		proxyLog.Err(err)
@@ -392,73 +493,17 @@ func Post(ctx context.Context, targetURL, contentType string, body io.Reader) (r
 	return DefaultClient.Do(req)
 }
 
-// Set up the context for this Application in Open Telemetry
-// application name, application version, k8s namespace , k8s instance name (horizontal scaling)
-func setupOtelResource(serviceName string, version string, gitHash string, ctx context.Context, namespace *string, instanceName *string) (*resource.Resource, error) {
-
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceNameKey.String(serviceName),
-			semconv.ServiceVersionKey.String(version+"-"+gitHash),
-			semconv.ServiceNamespaceKey.String(*namespace),
-			semconv.ServiceInstanceIDKey.String(*instanceName),
-		),
-	)
-	return res, err
-}
-
-// InitOtelProvider - Initializes an OTLP exporter, and configures the corresponding trace and metric providers.
-func initOtelProvider(serviceName string, version string, gitHash string, log zerolog.Logger) (func(context.Context) error, error) {
-	ctx := context.Background()
-
-	namespace := os.Getenv("NAMESPACE")
-	instanceName := os.Getenv("INSTANCE_NAME")
-	otelLocation := os.Getenv("OTEL_LOCATION")
-	if instanceName == "" || otelLocation == "" || namespace == "" {
-		log.Fatal().Msg(fmt.Sprintf("Env variables not assigned NAMESPACE=%v, INSTANCE_NAME=%v, OTEL_LOCATION=%v", namespace, instanceName, otelLocation))
-	}
-
-	otelResource, err := setupOtelResource(serviceName, version, gitHash, ctx, &namespace, &instanceName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
-	}
-
-	otelTraceExporter, err := setupOtelHttpTrace(ctx, &otelLocation)
+// Send is a convenient replacement for http.NewRequest and Do that also copies the given headers onto the request.
+func Send(ctx context.Context, method, targetURL, contentType string, body io.Reader, header http.Header) (resp *http.Response, err error) {
+	req, err := http.NewRequestWithContext(ctx, method, targetURL, body)
 	if err != nil {
 		return nil, err
 	}
-
-	traceProvider := setupOtelTraceProvider(otelTraceExporter, otelResource)
-	return traceProvider.Shutdown, nil //return shutdown signal so the application can trigger shutting itself down
-}
-
-func setupOtelTraceProvider(traceExporter *otlptrace.Exporter, otelResource *resource.Resource) *sdktrace.TracerProvider {
-	// Register the trace exporter with a TracerProvider, using a batch span processor to aggregate spans before export.
-	batchSpanProcessor := sdktrace.NewBatchSpanProcessor(traceExporter)
-	tracerProvider := sdktrace.NewTracerProvider(
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
-		sdktrace.WithResource(otelResource),
-		sdktrace.WithSpanProcessor(batchSpanProcessor),
-	)
-	otel.SetTracerProvider(tracerProvider)
-	otel.SetTextMapPropagator(propagation.TraceContext{}) // set global propagator to tracecontext (the default is no-op).
-	return tracerProvider
-}
-
-func setupOtelHttpTrace(ctx context.Context, otelLocation *string) (*otlptrace.Exporter, error) {
-	// insecure transport here DO NOT USE IN PROD
-	client := otlptracehttp.NewClient(
-		otlptracehttp.WithInsecure(),
-		otlptracehttp.WithEndpoint(*otelLocation),
-		otlptracehttp.WithCompression(otlptracehttp.GzipCompression),
-	)
-	err := client.Start(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to start http client: %w", err)
+	for name, values := range header {
+		req.Header[name] = values
 	}
-	traceExporter, err := otlptrace.New(ctx, client)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
 	}
-	return traceExporter, nil
+	return DefaultClient.Do(req)
 }
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel"
@@ -12,6 +14,7 @@ import (
 	"go.opentelemetry.io/otel/codes"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -46,15 +49,38 @@ var (
 	MockResponseFunc func(req *http.Request) (*http.Response, error)
 )
 
-func setupTestRouter() (*httptest.ResponseRecorder, *tracetest.SpanRecorder, *gin.Engine) {
+func setupTestRouter(t testing.TB) (*httptest.ResponseRecorder, *tracetest.SpanRecorder, *gin.Engine) {
 	spanRecorder := tracetest.NewSpanRecorder()
 	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
-	router := setupRouter()
+	// dual-emit so the tests cover the proxy-service.* and the HTTP semantic convention keys
+	spanConvention := telemetry.SpanConvention
+	t.Cleanup(func() { telemetry.SpanConvention = spanConvention })
+	telemetry.SpanConvention = telemetry.DualConvention
+	router := setupRouter(zerolog.New(os.Stdout).With().Timestamp().Logger())
 	testRecorder := httptest.NewRecorder()
 	router.Use(otelgin.Middleware("test-otel"))
 	return testRecorder, spanRecorder, router
 }
 
+// requestSpans are the ended server spans, leaving out the stage spans of the handlers.
+func requestSpans(spanRecorder *tracetest.SpanRecorder) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range spanRecorder.Ended() {
+		if span.SpanKind() == trace.SpanKindServer {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, len(spans))
+	for index, span := range spans {
+		names[index] = span.Name()
+	}
+	return names
+}
+
 func makeKeyMap(attributes []attribute.KeyValue) map[attribute.Key]attribute.Value {
 	var attributeMap = make(map[attribute.Key]attribute.Value)
 	for _, keyValue := range attributes {
@@ -64,10 +90,10 @@ func makeKeyMap(attributes []attribute.KeyValue) map[attribute.Key]attribute.Val
 }
 
 func Test_getAllAlbums_Success(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
-	responseBody := `[{"artist":"Black Sabbath","id":10,"price":66.6,"title":"The Ozzman Cometh"}]`
+	responseBody := `[{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}]`
 	body := io.NopCloser(bytes.NewReader([]byte(responseBody)))
 
 	//inject a success message from the server and return a json blob that represents an album
@@ -85,7 +111,7 @@ func Test_getAllAlbums_Success(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Ok, finishedSpans[0].Status().Code)
@@ -95,6 +121,7 @@ func Test_getAllAlbums_Success(t *testing.T) {
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "200", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "200", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, responseBody, attributeMap["proxy-service.response.body"].Emit())
 
 	assert.Equal(t, "200", attributeMap["album-store.response.code"].Emit())
@@ -104,7 +131,7 @@ func Test_getAllAlbums_Success(t *testing.T) {
 }
 
 func Test_getAllAlbums_Failure_Album_Returns_Error(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
 	//inject in failure message to respond with that we could not get to the album-store
@@ -119,17 +146,19 @@ func Test_getAllAlbums_Failure_Album_Returns_Error(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
-	assert.Equal(t, "error contacting album-store getAlbums ERROR FROM WEB SERVER", finishedSpans[0].Status().Description)
+	// otelgin ends the span with the error status of a 5xx response and no description, the message is in the event
+	assert.Equal(t, "", finishedSpans[0].Status().Description)
 
 	assert.Equal(t, 1, len(finishedSpans[0].Events()))
 	assert.Equal(t, "error contacting album-store getAlbums ERROR FROM WEB SERVER", finishedSpans[0].Events()[0].Name)
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "500", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "500", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, `{"message":"error contacting album-store getAlbums ERROR FROM WEB SERVER"}`, attributeMap["proxy-service.response.body"].Emit())
 
 	assert.Equal(t, "unknown", attributeMap["album-store.response.code"].Emit())
@@ -139,7 +168,7 @@ func Test_getAllAlbums_Failure_Album_Returns_Error(t *testing.T) {
 }
 
 func Test_getAllAlbums_Failure_Malformed_Response(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
 	//inject in failure message to respond with that we could not get to the album-store
@@ -161,17 +190,18 @@ func Test_getAllAlbums_Failure_Malformed_Response(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
-	assert.Equal(t, "error from album-store Malformed JSON returned", finishedSpans[0].Status().Description)
+	assert.Equal(t, "", finishedSpans[0].Status().Description)
 
 	assert.Equal(t, 1, len(finishedSpans[0].Events()))
 	assert.Equal(t, "error from album-store Malformed JSON returned", finishedSpans[0].Events()[0].Name)
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "500", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "500", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, `{"message":"error from album-store Malformed JSON returned"}`, attributeMap["proxy-service.response.body"].Emit())
 
 	assert.Equal(t, "200", attributeMap["album-store.response.code"].Emit())
@@ -181,7 +211,7 @@ func Test_getAllAlbums_Failure_Malformed_Response(t *testing.T) {
 }
 
 func Test_getAllAlbums_Failure_Bad_Request(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
 	responseBody := `{"Wu-Tang":"is for the children"}`
@@ -202,7 +232,7 @@ func Test_getAllAlbums_Failure_Bad_Request(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
@@ -213,6 +243,7 @@ func Test_getAllAlbums_Failure_Bad_Request(t *testing.T) {
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "400", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "400", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, responseBody, attributeMap["proxy-service.response.body"].Emit())
 
 	assert.Equal(t, "400", attributeMap["album-store.response.code"].Emit())
@@ -222,10 +253,10 @@ func Test_getAllAlbums_Failure_Bad_Request(t *testing.T) {
 }
 
 func Test_getAlbumById_Success(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
-	responseBody := `{"artist":"Black Sabbath","id":10,"price":66.6,"title":"The Ozzman Cometh"}`
+	responseBody := `{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}`
 	body := io.NopCloser(bytes.NewReader([]byte(responseBody)))
 
 	//inject a success message from the server and return a json blob that represents an album
@@ -243,7 +274,7 @@ func Test_getAlbumById_Success(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Ok, finishedSpans[0].Status().Code)
@@ -253,6 +284,10 @@ func Test_getAlbumById_Success(t *testing.T) {
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "200", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "200", attributeMap["http.response.status_code"].Emit())
+	assert.Equal(t, "ID=1", attributeMap["proxy-service.request.parameters"].Emit())
+	assert.Equal(t, "/albums/1", attributeMap["url.path"].Emit())
+	assert.Equal(t, "GET", attributeMap["http.request.method"].Emit())
 	assert.Equal(t, responseBody, attributeMap["proxy-service.response.body"].Emit())
 
 	assert.Equal(t, "200", attributeMap["album-store.response.code"].Emit())
@@ -262,7 +297,7 @@ func Test_getAlbumById_Success(t *testing.T) {
 }
 
 func Test_getAlbumById_Failure_Bad_Request(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
 	responseBody := `{"Wu-Tang":"is for the children"}`
@@ -283,7 +318,7 @@ func Test_getAlbumById_Failure_Bad_Request(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
@@ -294,6 +329,7 @@ func Test_getAlbumById_Failure_Bad_Request(t *testing.T) {
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "400", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "400", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, responseBody, attributeMap["proxy-service.response.body"].Emit())
 
 	assert.Equal(t, "400", attributeMap["album-store.response.code"].Emit())
@@ -303,7 +339,7 @@ func Test_getAlbumById_Failure_Bad_Request(t *testing.T) {
 }
 
 func Test_getAlbumById_Failure_Album_Returns_Error(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
 	//inject in failure message to respond with that we could not get to the album-store
@@ -318,17 +354,18 @@ func Test_getAlbumById_Failure_Album_Returns_Error(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
-	assert.Equal(t, "error contacting album-store getAlbumById ERROR FROM WEB SERVER", finishedSpans[0].Status().Description)
+	assert.Equal(t, "", finishedSpans[0].Status().Description)
 
 	assert.Equal(t, 1, len(finishedSpans[0].Events()))
 	assert.Equal(t, "error contacting album-store getAlbumById ERROR FROM WEB SERVER", finishedSpans[0].Events()[0].Name)
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "500", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "500", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, `{"message":"error contacting album-store getAlbumById ERROR FROM WEB SERVER"}`, attributeMap["proxy-service.response.body"].Emit())
 
 	assert.Equal(t, "unknown", attributeMap["album-store.response.code"].Emit())
@@ -338,7 +375,7 @@ func Test_getAlbumById_Failure_Album_Returns_Error(t *testing.T) {
 }
 
 func Test_getAlbumById_Failure_Album_BadId(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
 	//inject in failure message to respond with that we could not get to the album-store
@@ -353,7 +390,7 @@ func Test_getAlbumById_Failure_Album_BadId(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
@@ -373,7 +410,7 @@ func Test_getAlbumById_Failure_Album_BadId(t *testing.T) {
 }
 
 func Test_getAlbumById_Failure_Malformed_Response(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
 	//inject in failure message to respond with that we could not get to the album-store
@@ -395,17 +432,18 @@ func Test_getAlbumById_Failure_Malformed_Response(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
-	assert.Equal(t, "error from album-store Malformed JSON returned", finishedSpans[0].Status().Description)
+	assert.Equal(t, "", finishedSpans[0].Status().Description)
 
 	assert.Equal(t, 1, len(finishedSpans[0].Events()))
 	assert.Equal(t, "error from album-store Malformed JSON returned", finishedSpans[0].Events()[0].Name)
 
 	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
 	assert.Equal(t, "500", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "500", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, `{"message":"error from album-store Malformed JSON returned"}`, attributeMap["proxy-service.response.body"].Emit())
 
 	assert.Equal(t, "200", attributeMap["album-store.response.code"].Emit())
@@ -415,13 +453,13 @@ func Test_getAlbumById_Failure_Malformed_Response(t *testing.T) {
 }
 
 func Test_postAlbums_Success(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
 	requestBody := `{"artist":"Black Sabbath","id":10,"price":66.6,"title":"The Ozzman Cometh"}`
 	requestBodyReader := io.NopCloser(bytes.NewReader([]byte(requestBody)))
 
-	responseBody := `{"artist":"Black Sabbath","id":10,"price":66.6,"title":"The Ozzman Cometh"}`
+	responseBody := `{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}`
 	responseBodyReader := io.NopCloser(bytes.NewReader([]byte(responseBody)))
 
 	//inject a success message from the server and return a json blob that represents an album
@@ -439,7 +477,7 @@ func Test_postAlbums_Success(t *testing.T) {
 
 	assert.Equal(t, http.StatusCreated, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Ok, finishedSpans[0].Status().Code)
@@ -451,6 +489,7 @@ func Test_postAlbums_Success(t *testing.T) {
 	assert.Equal(t, requestBody, attributeMap["proxy-service.request.body"].Emit())
 
 	assert.Equal(t, "201", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "201", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, responseBody, attributeMap["proxy-service.response.body"].Emit())
 
 	assert.Equal(t, "201", attributeMap["album-store.response.code"].Emit())
@@ -459,8 +498,115 @@ func Test_postAlbums_Success(t *testing.T) {
 	assert.Equal(t, responseBody, returnedBody)
 }
 
+func Test_postAlbums_Stage_Spans(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	DefaultClient = &MockClient{}
+
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}`))),
+		}, nil
+	}
+
+	requestBody := `{"artist":"Black Sabbath","id":10,"price":66.6,"title":"The Ozzman Cometh"}`
+	req := httptest.NewRequest(http.MethodPost, "/albums", bytes.NewReader([]byte(requestBody)))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusCreated, testRecorder.Code)
+	finishedSpans := spanRecorder.Ended()
+	assert.Equal(t, []string{"parse request body", "serialize response", "/albums"}, spanNames(finishedSpans))
+	requestSpan := finishedSpans[2]
+	for _, stage := range finishedSpans[:2] {
+		assert.Equal(t, requestSpan.SpanContext().SpanID(), stage.Parent().SpanID(), stage.Name())
+		assert.Equal(t, codes.Ok, stage.Status().Code, stage.Name())
+	}
+	// otelgin ends the request span, after recording the status code written
+	attributeMap := makeKeyMap(requestSpan.Attributes())
+	assert.Equal(t, "/albums", attributeMap["http.route"].Emit())
+	assert.Equal(t, "201", attributeMap["http.status_code"].Emit())
+}
+
+func Test_postAlbums_Malformed_Request_Body_Stage_Spans(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/albums", bytes.NewReader([]byte(`{"id":10`)))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	finishedSpans := spanRecorder.Ended()
+	assert.Equal(t, []string{"parse request body", "/albums"}, spanNames(finishedSpans))
+	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
+}
+
+func Test_postAlbums_Forwards_Idempotency_Key(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+	DefaultClient = &MockClient{}
+
+	requestBody := `{"artist":"Black Sabbath","id":10,"price":66.6,"title":"The Ozzman Cometh"}`
+	responseBody := `{"artist":"Black Sabbath","id":10,"price":66.6,"title":"The Ozzman Cometh"}`
+
+	var idempotencyKey string
+	MockResponseFunc = func(req *http.Request) (*http.Response, error) {
+		idempotencyKey = req.Header.Get("Idempotency-Key")
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       io.NopCloser(bytes.NewReader([]byte(responseBody))),
+		}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/albums", bytes.NewReader([]byte(requestBody)))
+	req.Header.Set("Idempotency-Key", "retry-1")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusCreated, testRecorder.Code)
+	assert.Equal(t, "retry-1", idempotencyKey)
+}
+
+func Test_postAlbums_Forwards_Raw_Json_Body(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+	DefaultClient = &MockClient{}
+
+	requestBody := `{"id":10,"title":"The Ozzman Cometh","title":"Ozzman","artist":"Black Sabbath","price":66.6,"label":"Epic"}`
+	responseBody := `{"errors":[{"field":"title","message":"is a duplicate key"},{"field":"label","message":"is not a known field"}],"message":""}`
+
+	var forwardedBody string
+	MockResponseFunc = func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		forwardedBody = string(body)
+		return &http.Response{
+			StatusCode: http.StatusBadRequest,
+			Body:       io.NopCloser(bytes.NewReader([]byte(responseBody))),
+		}, nil
+	}
+
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodPost, "/albums", bytes.NewReader([]byte(requestBody))))
+
+	assert.Equal(t, requestBody, forwardedBody)
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":[{"field":"title","message":"is a duplicate key"},{"field":"label","message":"is not a known field"}],"message":"album-store returned error postAlbum"}`, testRecorder.Body.String())
+}
+
+func Test_postAlbums_Failure_Non_Json_Error_Response(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+	DefaultClient = &MockClient{}
+
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       io.NopCloser(bytes.NewReader([]byte("<html>upstream unavailable</html>"))),
+		}, nil
+	}
+
+	requestBody := `{"artist":"Black Sabbath","id":10,"price":66.6,"title":"The Ozzman Cometh"}`
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodPost, "/albums", bytes.NewReader([]byte(requestBody))))
+
+	assert.Equal(t, http.StatusServiceUnavailable, testRecorder.Code)
+	assert.Equal(t, `{"errors":null,"message":"album-store returned error postAlbum"}`, testRecorder.Body.String())
+}
+
 func Test_postAlbums_Failure_Album_Empty_Request_Body(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
 	requestBody := ``
@@ -480,7 +626,7 @@ func Test_postAlbums_Failure_Album_Empty_Request_Body(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
@@ -493,6 +639,7 @@ func Test_postAlbums_Failure_Album_Empty_Request_Body(t *testing.T) {
 	assert.Equal(t, "", attributeMap["proxy-service.request.body"].Emit())
 
 	assert.Equal(t, "400", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "400", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, `{"message":"invalid request json body "}`, attributeMap["proxy-service.response.body"].Emit())
 
 	assert.Equal(t, "unknown", attributeMap["album-store.response.code"].Emit())
@@ -501,7 +648,7 @@ func Test_postAlbums_Failure_Album_Empty_Request_Body(t *testing.T) {
 }
 
 func Test_postAlbums_Failure_Album_Malformed_Request_Body(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
 	requestBody := `{"title":"Ozzman Cometh"`
@@ -519,7 +666,7 @@ func Test_postAlbums_Failure_Album_Malformed_Request_Body(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
@@ -532,6 +679,7 @@ func Test_postAlbums_Failure_Album_Malformed_Request_Body(t *testing.T) {
 	assert.Equal(t, requestBody, attributeMap["proxy-service.request.body"].Emit())
 
 	assert.Equal(t, "400", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "400", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, `{"message":"invalid request json body {"title":"Ozzman Cometh""}`, attributeMap["proxy-service.response.body"].Emit())
 
 	assert.Equal(t, "unknown", attributeMap["album-store.response.code"].Emit())
@@ -541,7 +689,7 @@ func Test_postAlbums_Failure_Album_Malformed_Request_Body(t *testing.T) {
 }
 
 func Test_postAlbums_Failure_Album_Returns_Error(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
 	requestBody := `{"artist":"Black Sabbath","id":10,"price":66.6,"title":"The Ozzman Cometh"}`
@@ -559,11 +707,11 @@ func Test_postAlbums_Failure_Album_Returns_Error(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
-	assert.Equal(t, "error contacting album-store postAlbum ERROR FROM WEB SERVER", finishedSpans[0].Status().Description)
+	assert.Equal(t, "", finishedSpans[0].Status().Description)
 
 	assert.Equal(t, 1, len(finishedSpans[0].Events()))
 	assert.Equal(t, "error contacting album-store postAlbum ERROR FROM WEB SERVER", finishedSpans[0].Events()[0].Name)
@@ -572,6 +720,7 @@ func Test_postAlbums_Failure_Album_Returns_Error(t *testing.T) {
 	assert.Equal(t, requestBody, attributeMap["proxy-service.request.body"].Emit())
 
 	assert.Equal(t, "500", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "500", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, `{"message":"error contacting album-store postAlbum ERROR FROM WEB SERVER"}`, attributeMap["proxy-service.response.body"].Emit())
 
 	assert.Equal(t, "unknown", attributeMap["album-store.response.code"].Emit())
@@ -581,7 +730,7 @@ func Test_postAlbums_Failure_Album_Returns_Error(t *testing.T) {
 }
 
 func Test_postAlbums_Failure_Malformed_Response(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
 	requestBody := `{"artist":"Black Sabbath","id":10,"price":66.6,"title":"The Ozzman Cometh"}`
@@ -606,11 +755,11 @@ func Test_postAlbums_Failure_Malformed_Response(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
-	assert.Equal(t, "error from album-store Malformed JSON returned", finishedSpans[0].Status().Description)
+	assert.Equal(t, "", finishedSpans[0].Status().Description)
 
 	assert.Equal(t, 1, len(finishedSpans[0].Events()))
 	assert.Equal(t, "error from album-store Malformed JSON returned", finishedSpans[0].Events()[0].Name)
@@ -619,6 +768,7 @@ func Test_postAlbums_Failure_Malformed_Response(t *testing.T) {
 	assert.Equal(t, requestBody, attributeMap["proxy-service.request.body"].Emit())
 
 	assert.Equal(t, "500", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "500", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, `{"message":"error from album-store Malformed JSON returned"}`, attributeMap["proxy-service.response.body"].Emit())
 
 	assert.Equal(t, "201", attributeMap["album-store.response.code"].Emit())
@@ -628,7 +778,7 @@ func Test_postAlbums_Failure_Malformed_Response(t *testing.T) {
 }
 
 func Test_postAlbums_Failure_Bad_Request(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 	DefaultClient = &MockClient{}
 
 	requestBody := `{"Wu-Tang":"is for the children"}`
@@ -653,7 +803,7 @@ func Test_postAlbums_Failure_Bad_Request(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
@@ -666,6 +816,7 @@ func Test_postAlbums_Failure_Bad_Request(t *testing.T) {
 	assert.Equal(t, requestBody, attributeMap["proxy-service.request.body"].Emit())
 
 	assert.Equal(t, "400", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "400", attributeMap["http.response.status_code"].Emit())
 	assert.Equal(t, responseBody, attributeMap["proxy-service.response.body"].Emit())
 
 	assert.Equal(t, "400", attributeMap["album-store.response.code"].Emit())
@@ -675,7 +826,7 @@ func Test_postAlbums_Failure_Bad_Request(t *testing.T) {
 }
 
 func Test_getSwagger(t *testing.T) {
-	testRecorder, _, router := setupTestRouter()
+	testRecorder, _, router := setupTestRouter(t)
 
 	req := httptest.NewRequest(http.MethodGet, "/swagger/index.html", nil)
 	router.ServeHTTP(testRecorder, req)
@@ -686,7 +837,7 @@ func Test_getSwagger(t *testing.T) {
 }
 
 func Test_getStatus(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 
 	req := httptest.NewRequest(http.MethodGet, "/status", nil)
 	router.ServeHTTP(testRecorder, req)
@@ -696,7 +847,7 @@ func Test_getStatus(t *testing.T) {
 	assert.Equal(t, http.StatusOK, testRecorder.Code)
 	assert.Equal(t, `{"status":"OK"}`, responseBodyString)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Ok, finishedSpans[0].Status().Code)
@@ -706,7 +857,7 @@ func Test_getStatus(t *testing.T) {
 }
 
 func Test_getMetrics(t *testing.T) {
-	testRecorder, spanRecorder, router := setupTestRouter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
 
 	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
 	router.ServeHTTP(testRecorder, req)
@@ -716,7 +867,7 @@ func Test_getMetrics(t *testing.T) {
 	assert.Equal(t, http.StatusOK, testRecorder.Code)
 	assert.Contains(t, responseBodyString, `go_gc_duration_seconds`)
 
-	finishedSpans := spanRecorder.Ended()
+	finishedSpans := requestSpans(spanRecorder)
 	assert.Len(t, finishedSpans, 1)
 
 	assert.Equal(t, codes.Ok, finishedSpans[0].Status().Code)
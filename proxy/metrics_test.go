@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/global"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// setupTestMeter sets a MeterProvider with a reader the test can collect from, before the router creates its instruments.
+func setupTestMeter() sdkmetric.Reader {
+	reader := sdkmetric.NewManualReader()
+	global.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	return reader
+}
+
+func collectMetric(t *testing.T, reader sdkmetric.Reader, name string) metricdata.Aggregation {
+	var resourceMetrics metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &resourceMetrics))
+	for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+		for _, metrics := range scopeMetrics.Metrics {
+			if metrics.Name == name {
+				return metrics.Data
+			}
+		}
+	}
+	t.Fatalf("metric %s not recorded", name)
+	return nil
+}
+
+func Test_requestMetrics(t *testing.T) {
+	reader := setupTestMeter()
+	_, _, router := setupTestRouter(t)
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`{"id":1,"title":"Blue Train","artist":"John Coltrane","price":56.99}`))),
+		}, nil
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/albums/1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/albums/X", nil))
+
+	requests := collectMetric(t, reader, "http.server.requests").(metricdata.Sum[int64])
+	assert.Len(t, requests.DataPoints, 2)
+	okay := attribute.NewSet(
+		attribute.Key("http.method").String(http.MethodGet),
+		attribute.Key("http.route").String("/albums/:id"),
+		attribute.Key("http.status_code").Int(http.StatusOK),
+	)
+	invalid := attribute.NewSet(
+		attribute.Key("http.method").String(http.MethodGet),
+		attribute.Key("http.route").String("/albums/:id"),
+		attribute.Key("http.status_code").Int(http.StatusBadRequest),
+	)
+	for _, dataPoint := range requests.DataPoints {
+		switch {
+		case dataPoint.Attributes.Equals(&okay), dataPoint.Attributes.Equals(&invalid):
+			assert.Equal(t, int64(1), dataPoint.Value)
+		default:
+			assert.Fail(t, "unexpected attributes", dataPoint.Attributes.Encoded(attribute.DefaultEncoder()))
+		}
+	}
+
+	duration := collectMetric(t, reader, "http.server.duration").(metricdata.Histogram[float64])
+	assert.Len(t, duration.DataPoints, 2)
+}
+
+func Test_requestMetrics_Errors(t *testing.T) {
+	reader := setupTestMeter()
+	_, _, router := setupTestRouter(t)
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("ERROR FROM WEB SERVER")
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/albums", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	requestErrors := collectMetric(t, reader, "http.server.errors").(metricdata.Sum[int64])
+	assert.Len(t, requestErrors.DataPoints, 1)
+	assert.Equal(t, int64(1), requestErrors.DataPoints[0].Value)
+	route, _ := requestErrors.DataPoints[0].Attributes.Value("http.route")
+	assert.Equal(t, "/albums", route.AsString())
+}
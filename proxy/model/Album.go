@@ -1,8 +1,8 @@
 package model
 
 type Album struct {
-	ID     int     `json:"id" binding:"min=1,max=10000"`
-	Title  string  `json:"title" binding:"required,min=2,max=1000"`
-	Artist string  `json:"artist" binding:"required,min=2,max=1000"`
-	Price  float64 `json:"price" binding:"required,min=0.0,max=10000.00"`
+	ID     int     `json:"id" yaml:"id" xml:"id" binding:"min=1,max=10000"`
+	Title  string  `json:"title" yaml:"title" xml:"title" binding:"required,min=2,max=1000"`
+	Artist string  `json:"artist" yaml:"artist" xml:"artist" binding:"required,min=2,max=1000"`
+	Price  float64 `json:"price" yaml:"price" xml:"price" binding:"required,min=0.0,max=10000.00"`
 }
@@ -0,0 +1,20 @@
+package model
+
+type CartLine struct {
+	AlbumID   int     `json:"albumId"`
+	Title     string  `json:"title"`
+	Quantity  int     `json:"quantity"`
+	Price     float64 `json:"price"`
+	LineTotal float64 `json:"lineTotal"`
+}
+
+type Cart struct {
+	ID    int        `json:"id"`
+	Lines []CartLine `json:"lines"`
+	Total float64    `json:"total"`
+}
+
+type CartLineRequest struct {
+	AlbumID  int `json:"albumId" binding:"required,min=1,max=10000"`
+	Quantity int `json:"quantity" binding:"required,min=1,max=100"`
+}
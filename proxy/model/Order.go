@@ -0,0 +1,43 @@
+package model
+
+type OrderStatus string
+
+const (
+	OrderPending   OrderStatus = "pending"
+	OrderPaid      OrderStatus = "paid"
+	OrderShipped   OrderStatus = "shipped"
+	OrderCancelled OrderStatus = "cancelled"
+)
+
+// orderTransitions lists the states an order may move to from its current state.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending: {OrderPaid, OrderCancelled},
+	OrderPaid:    {OrderShipped, OrderCancelled},
+}
+
+// CanTransitionTo reports whether the order state machine allows moving from s to next.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+type Order struct {
+	ID        int         `json:"id"`
+	CartID    int         `json:"cartId"`
+	Lines     []CartLine  `json:"lines"`
+	Total     float64     `json:"total"`
+	Status    OrderStatus `json:"status"`
+	PaymentID string      `json:"paymentId,omitempty"`
+}
+
+type OrderRequest struct {
+	CartID int `json:"cartId" binding:"required,min=1"`
+}
+
+type OrderStatusRequest struct {
+	Status OrderStatus `json:"status" binding:"required,oneof=shipped cancelled"`
+}
@@ -6,6 +6,11 @@ type BindingErrorMsg struct {
 }
 
 type ServerError struct {
-	BindingErrors []*BindingErrorMsg `json:"errors"`
+	BindingErrors []*BindingErrorMsg `json:"errors" extensions:"x-nullable"`
 	Message       string             `json:"message"`
 }
+
+// Error lets a ServerError from album-store be returned as an error, see client.ResponseError.
+func (serverError *ServerError) Error() string {
+	return serverError.Message
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"github.com/mcarr-and/go-gin-otelcollector/proxy-service/albumpb"
+	"github.com/mcarr-and/go-gin-otelcollector/proxy-service/model"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"github.com/ugorji/go/codec"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// albumFormat is a representation album resources can be sent and received in, chosen by the Accept and Content-Type headers.
+// The proxy always talks JSON to album-store and converts at the edge, validation is left to album-store.
+type albumFormat struct {
+	name      string
+	mimeTypes []string
+	decode    func(body []byte, album *model.Album) error
+}
+
+// albumXML and albumListXML give the XML documents lower case root elements.
+type albumXML struct {
+	XMLName xml.Name `xml:"album"`
+	model.Album
+}
+
+type albumListXML struct {
+	XMLName xml.Name      `xml:"albums"`
+	Albums  []model.Album `xml:"album"`
+}
+
+// albumFormats are in order of preference, JSON first so requests without Accept or Content-Type keep getting JSON.
+var albumFormats = []albumFormat{
+	{name: "json", mimeTypes: []string{binding.MIMEJSON},
+		decode: func(body []byte, album *model.Album) error { return json.Unmarshal(body, album) }},
+	{name: "protobuf", mimeTypes: []string{binding.MIMEPROTOBUF, "application/protobuf"},
+		decode: decodeProtobufAlbum},
+	{name: "msgpack", mimeTypes: []string{binding.MIMEMSGPACK2, binding.MIMEMSGPACK},
+		decode: func(body []byte, album *model.Album) error {
+			return codec.NewDecoderBytes(body, new(codec.MsgpackHandle)).Decode(album)
+		}},
+	{name: "xml", mimeTypes: []string{binding.MIMEXML, binding.MIMEXML2},
+		decode: func(body []byte, album *model.Album) error { return xml.Unmarshal(body, album) }},
+	{name: "yaml", mimeTypes: []string{binding.MIMEYAML, "application/yaml", "text/yaml"},
+		decode: func(body []byte, album *model.Album) error { return yaml.Unmarshal(body, album) }},
+}
+
+var jsonAlbumFormat = albumFormats[0]
+
+func decodeProtobufAlbum(body []byte, album *model.Album) error {
+	var protoAlbum albumpb.Album
+	if err := proto.Unmarshal(body, &protoAlbum); err != nil {
+		return err
+	}
+	*album = model.Album{ID: int(protoAlbum.GetId()), Title: protoAlbum.GetTitle(), Artist: protoAlbum.GetArtist(), Price: protoAlbum.GetPrice()}
+	return nil
+}
+
+func albumToProto(album model.Album) *albumpb.Album {
+	return &albumpb.Album{Id: int32(album.ID), Title: album.Title, Artist: album.Artist, Price: album.Price}
+}
+
+func albumMimeTypes() []string {
+	var mimeTypes []string
+	for _, format := range albumFormats {
+		mimeTypes = append(mimeTypes, format.mimeTypes...)
+	}
+	return mimeTypes
+}
+
+func albumFormatForMimeType(mimeType string) (albumFormat, bool) {
+	for _, format := range albumFormats {
+		for _, formatMimeType := range format.mimeTypes {
+			if strings.EqualFold(mimeType, formatMimeType) {
+				return format, true
+			}
+		}
+	}
+	return albumFormat{}, false
+}
+
+func buildUnsupportedFormatErrorResponse(c *gin.Context, span trace.Span, errorMessage string, problem problemType) {
+	span.SetStatus(codes.Error, errorMessage)
+	span.AddEvent(errorMessage)
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "proxy-service.response.body", fmt.Sprintf(`{"message":"%v"}`, errorMessage))
+	abortWithProblem(c, span, problem, errorMessage)
+}
+
+// negotiateAlbumFormat picks the response format from the Accept header, writing a 406 when none of the album formats are acceptable.
+func negotiateAlbumFormat(c *gin.Context, span trace.Span) (albumFormat, bool) {
+	format, found := albumFormatForMimeType(c.NegotiateFormat(albumMimeTypes()...))
+	if !found {
+		errorMessage := fmt.Sprintf("error Accept [%s] not supported, use one of %s", c.GetHeader("Accept"), strings.Join(albumMimeTypes(), ", "))
+		buildUnsupportedFormatErrorResponse(c, span, errorMessage, problemNotAcceptable)
+		return albumFormat{}, true
+	}
+	span.SetAttributes(attribute.Key("proxy-service.response.format").String(format.name))
+	return format, false
+}
+
+// requestAlbumFormat picks the request body format from the Content-Type header, writing a 415 when it is not an album format.
+// A missing Content-Type is read as JSON.
+func requestAlbumFormat(c *gin.Context, span trace.Span) (albumFormat, bool) {
+	contentType := c.ContentType()
+	if contentType == "" {
+		contentType = binding.MIMEJSON
+	}
+	format, found := albumFormatForMimeType(contentType)
+	if !found {
+		errorMessage := fmt.Sprintf("error Content-Type [%s] not supported, use one of %s", contentType, strings.Join(albumMimeTypes(), ", "))
+		buildUnsupportedFormatErrorResponse(c, span, errorMessage, problemUnsupportedMediaType)
+		return albumFormat{}, true
+	}
+	span.SetAttributes(attribute.Key("proxy-service.request.format").String(format.name))
+	return format, false
+}
+
+// processFormattedRequestBody decodes an album sent in a format other than JSON, recording it on the span as the JSON sent to album-store.
+func processFormattedRequestBody(c *gin.Context, span trace.Span, format albumFormat, body []byte) (model.Album, bool) {
+	_, stage := telemetry.StartStage(c.Request.Context(), "parse request body")
+	var album model.Album
+	err := format.decode(body, &album)
+	telemetry.EndStage(stage, err != nil)
+	if err != nil {
+		errorMessage := fmt.Sprintf("invalid request %s body", format.name)
+		buildMalformedRequestJsonErrorResponse(c, span, "", errorMessage)
+		telemetry.SetBodyAttribute(c.Request.Context(), span, "proxy-service.response.body", fmt.Sprintf("{\"message\":\"%v\"}", errorMessage))
+		return album, true
+	}
+	jsonBody, _ := json.Marshal(album)
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "proxy-service.request.body", string(jsonBody))
+	return album, false
+}
+
+// renderAlbums writes a model.Album or []model.Album from album-store in the negotiated format.
+func renderAlbums(c *gin.Context, statusCode int, format albumFormat, albums interface{}) {
+	_, stage := telemetry.StartStage(c.Request.Context(), "serialize response")
+	defer telemetry.EndStage(stage, false)
+	if format.name == jsonAlbumFormat.name {
+		c.JSON(statusCode, albums)
+		return
+	}
+	switch albums := albums.(type) {
+	case []model.Album:
+		switch format.name {
+		case "protobuf":
+			protoAlbums := &albumpb.ListAlbumsResponse{Albums: make([]*albumpb.Album, len(albums))}
+			for index, album := range albums {
+				protoAlbums.Albums[index] = albumToProto(album)
+			}
+			c.ProtoBuf(statusCode, protoAlbums)
+		case "msgpack":
+			c.Render(statusCode, render.MsgPack{Data: albums})
+		case "xml":
+			c.XML(statusCode, albumListXML{Albums: albums})
+		case "yaml":
+			c.YAML(statusCode, albums)
+		}
+	case model.Album:
+		switch format.name {
+		case "protobuf":
+			c.ProtoBuf(statusCode, albumToProto(albums))
+		case "msgpack":
+			c.Render(statusCode, render.MsgPack{Data: albums})
+		case "xml":
+			c.XML(statusCode, albumXML{Album: albums})
+		case "yaml":
+			c.YAML(statusCode, albums)
+		}
+	}
+}
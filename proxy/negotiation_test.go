@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/mcarr-and/go-gin-otelcollector/proxy-service/albumpb"
+	"github.com/mcarr-and/go-gin-otelcollector/proxy-service/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+)
+
+func mockAlbumStoreResponse(statusCode int, responseBody string) {
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       io.NopCloser(strings.NewReader(responseBody)),
+		}, nil
+	}
+}
+
+func Test_getAllAlbums_Protobuf(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	mockAlbumStoreResponse(http.StatusOK, `[{"artist":"Black Sabbath","id":10,"price":66.6,"title":"The Ozzman Cometh"}]`)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+	req.Header.Set("Accept", binding.MIMEPROTOBUF)
+	router.ServeHTTP(testRecorder, req)
+
+	var response albumpb.ListAlbumsResponse
+	assert.NoError(t, proto.Unmarshal(testRecorder.Body.Bytes(), &response))
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, 1, len(response.GetAlbums()))
+	assert.Equal(t, "The Ozzman Cometh", response.GetAlbums()[0].GetTitle())
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "protobuf", attributeMap["proxy-service.response.format"].Emit())
+}
+
+func Test_getAlbumById_XML(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+	mockAlbumStoreResponse(http.StatusOK, `{"artist":"Black Sabbath","id":10,"price":66.6,"title":"The Ozzman Cometh"}`)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/10", nil)
+	req.Header.Set("Accept", binding.MIMEXML)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, `<album><id>10</id><title>The Ozzman Cometh</title><artist>Black Sabbath</artist><price>66.6</price></album>`, testRecorder.Body.String())
+}
+
+func Test_getAlbums_NotAcceptable(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	calledAlbumStore := false
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		calledAlbumStore = true
+		return nil, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+	req.Header.Set("Accept", "text/csv")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusNotAcceptable, testRecorder.Code)
+	assert.Contains(t, testRecorder.Body.String(), `"message":"error Accept [text/csv] not supported`)
+	assert.False(t, calledAlbumStore)
+
+	finishedSpans := requestSpans(spanRecorder)
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "406", attributeMap["proxy-service.response.code"].Emit())
+}
+
+func Test_postAlbums_MsgPack_Forwarded_As_Json(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	var forwardedBody, forwardedContentType string
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		forwardedBody = string(body)
+		forwardedContentType = req.Header.Get("Content-Type")
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       io.NopCloser(bytes.NewReader(body)),
+		}, nil
+	}
+
+	var msgpackBody []byte
+	_ = codec.NewEncoderBytes(&msgpackBody, new(codec.MsgpackHandle)).Encode(model.Album{ID: 10, Title: "The Ozzman Cometh", Artist: "Black Sabbath", Price: 66.60})
+	req := httptest.NewRequest(http.MethodPost, "/albums", bytes.NewReader(msgpackBody))
+	req.Header.Set("Content-Type", binding.MIMEMSGPACK2)
+	req.Header.Set("Accept", binding.MIMEYAML)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusCreated, testRecorder.Code)
+	assert.Equal(t, `{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","price":66.6}`, forwardedBody)
+	assert.Equal(t, binding.MIMEJSON, forwardedContentType)
+	assert.Equal(t, "id: 10\ntitle: The Ozzman Cometh\nartist: Black Sabbath\nprice: 66.6\n", testRecorder.Body.String())
+
+	finishedSpans := requestSpans(spanRecorder)
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "msgpack", attributeMap["proxy-service.request.format"].Emit())
+	assert.Equal(t, "yaml", attributeMap["proxy-service.response.format"].Emit())
+}
+
+func Test_postAlbums_Malformed_YAML(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader("id: [10"))
+	req.Header.Set("Content-Type", binding.MIMEYAML)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":null,"message":"invalid request yaml body"}`, testRecorder.Body.String())
+}
+
+func Test_postAlbums_Unreadable_Body(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		assert.Fail(t, "a body that could not be read must not be sent to album-store")
+		return nil, errors.New("unexpected call")
+	}
+
+	body := io.MultiReader(strings.NewReader("id: 10\n"), iotest.ErrReader(errors.New("client disconnected")))
+	req := httptest.NewRequest(http.MethodPost, "/albums", body)
+	req.Header.Set("Content-Type", binding.MIMEYAML)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":null,"message":"request body could not be read: client disconnected"}`, testRecorder.Body.String())
+}
+
+func Test_postAlbums_UnsupportedMediaType(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader(`10,The Ozzman Cometh,Black Sabbath,66.60`))
+	req.Header.Set("Content-Type", "text/csv")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, testRecorder.Code)
+	assert.Contains(t, testRecorder.Body.String(), `"message":"error Content-Type [text/csv] not supported`)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/mcarr-and/go-gin-otelcollector/proxy-service/model"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// undocumentedRoutes are served but deliberately left out of the OpenAPI document.
+var undocumentedRoutes = map[string]bool{
+	"GET /swagger/*any": true,
+}
+
+func openAPIPath(ginPath string) string {
+	return ginPathParam.ReplaceAllString(ginPath, "{$1}")
+}
+
+func Test_getOpenAPIDocument(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/v3/api-docs", nil))
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	document, err := openapi3.NewLoader().LoadFromData(testRecorder.Body.Bytes())
+	assert.NoError(t, err)
+	assert.NoError(t, document.Validate(context.Background()))
+	assert.Equal(t, "3.0.3", document.OpenAPI)
+	assert.Equal(t, "http://localhost:9070/", document.Servers[0].URL)
+	assert.Equal(t, "/v3/api-docs", requestSpans(spanRecorder)[0].Name())
+}
+
+func Test_openAPIDocument_MatchesRouter(t *testing.T) {
+	_, _, router := setupTestRouter(t)
+
+	routes := map[string]bool{}
+	for _, route := range router.Routes() {
+		key := route.Method + " " + route.Path
+		if undocumentedRoutes[key] {
+			continue
+		}
+		path := openAPIPath(route.Path)
+		routes[route.Method+" "+path] = true
+		pathItem := openAPIDocument.Paths.Find(path)
+		if !assert.NotNil(t, pathItem, "route %s is not documented", key) {
+			continue
+		}
+		operation := pathItem.GetOperation(route.Method)
+		if !assert.NotNil(t, operation, "route %s is not documented", key) {
+			continue
+		}
+		for _, match := range ginPathParam.FindAllStringSubmatch(route.Path, -1) {
+			parameter := operation.Parameters.GetByInAndName(openapi3.ParameterInPath, match[1])
+			assert.NotNil(t, parameter, "route %s does not document path parameter %s", key, match[1])
+		}
+	}
+	for path, pathItem := range openAPIDocument.Paths {
+		for method := range pathItem.Operations() {
+			assert.True(t, routes[strings.ToUpper(method)+" "+path], "documented %s %s is not a route", method, path)
+		}
+	}
+}
+
+func Test_openAPIValidation_Request_BelowMinimum(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/0", nil)
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	router.ServeHTTP(testRecorder, req)
+
+	var problem model.Problem
+	assert.NoError(t, json.Unmarshal(testRecorder.Body.Bytes(), &problem))
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, "request-invalid", problem.Code)
+	assert.Equal(t, []*model.BindingErrorMsg{{Field: "id", Message: "number must be at least 1"}}, problem.BindingErrors)
+}
+
+func Test_openAPIValidation_StrictResponse_Matches(t *testing.T) {
+	strictResponseValidation = true
+	defer func() { strictResponseValidation = false }()
+	reader := setupTestMeter()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, int64(0), responseViolations(t, reader))
+	assert.Len(t, requestSpans(spanRecorder), 1)
+}
+
+func Test_openAPIValidation_StrictResponse_Drift(t *testing.T) {
+	strictResponseValidation = true
+	defer func() { strictResponseValidation = false }()
+	reader := setupTestMeter()
+	spanRecorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	router := gin.New()
+	router.Use(otelgin.Middleware("test-otel"))
+	router.Use(openAPIValidation())
+	router.GET("/albums", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"albums": "not an array"})
+	})
+	testRecorder := httptest.NewRecorder()
+
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/albums", nil))
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, `{"albums":"not an array"}`, testRecorder.Body.String())
+	violations := collectMetric(t, reader, "proxy-service.openapi.response_violations").(metricdata.Sum[int64])
+	assert.Len(t, violations.DataPoints, 1)
+	assert.Equal(t, int64(1), violations.DataPoints[0].Value)
+	assert.Equal(t, attribute.NewSet(
+		attribute.Key("http.method").String(http.MethodGet),
+		attribute.Key("http.route").String("/albums"),
+		attribute.Key("http.status_code").Int(http.StatusOK),
+	), violations.DataPoints[0].Attributes)
+	finishedSpans := spanRecorder.Ended()
+	assert.Len(t, finishedSpans, 2)
+	assert.Equal(t, "OpenAPI response validation", finishedSpans[0].Name())
+	assert.Equal(t, codes.Error, finishedSpans[0].Status().Code)
+	assert.Equal(t, "Response does not match the OpenAPI document", finishedSpans[0].Events()[0].Name)
+	eventAttributes := makeKeyMap(finishedSpans[0].Events()[0].Attributes)
+	assert.Equal(t, "GET /albums", eventAttributes["proxy-service.openapi.route"].Emit())
+	assert.Contains(t, eventAttributes["proxy-service.openapi.violation"].Emit(), "response body doesn't match schema")
+	assert.Equal(t, finishedSpans[1].SpanContext().SpanID(), finishedSpans[0].Parent().SpanID())
+}
+
+func Test_openAPIValidation_Request_Problem(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/X", nil)
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	router.ServeHTTP(testRecorder, req)
+
+	var problem model.Problem
+	assert.NoError(t, json.Unmarshal(testRecorder.Body.Bytes(), &problem))
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, "request-invalid", problem.Code)
+	assert.Equal(t, "Request does not match the OpenAPI document", problem.Detail)
+	assert.Equal(t, []*model.BindingErrorMsg{{Field: "id", Message: "X is an invalid integer"}}, problem.BindingErrors)
+}
+
+// responseViolations totals the response violations recorded by reader, 0 when none were recorded.
+func responseViolations(t *testing.T, reader sdkmetric.Reader) int64 {
+	var resourceMetrics metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &resourceMetrics))
+	total := int64(0)
+	for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+		for _, metrics := range scopeMetrics.Metrics {
+			if metrics.Name == "proxy-service.openapi.response_violations" {
+				for _, dataPoint := range metrics.Data.(metricdata.Sum[int64]).DataPoints {
+					total += dataPoint.Value
+				}
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mcarr-and/go-gin-otelcollector/proxy-service/client"
+	"github.com/mcarr-and/go-gin-otelcollector/proxy-service/model"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	problemTypeBaseURI = "/problems/"
+)
+
+// problemType is an entry in the proxy error catalog. The code is stable for clients to match on,
+// legacyStatus is the status sent when errors use the legacy model.ServerError body.
+// Errors from album-store keep album-store's code, see handleResponseCodeHasError.
+type problemType struct {
+	code         string
+	title        string
+	status       int
+	legacyStatus int
+}
+
+var (
+	problemInvalidID            = problemType{code: "invalid-id", title: "Invalid id", status: http.StatusBadRequest, legacyStatus: http.StatusBadRequest}
+	problemRequestInvalid       = problemType{code: "request-invalid", title: "Request does not match the OpenAPI document", status: http.StatusBadRequest, legacyStatus: http.StatusBadRequest}
+	problemMalformedBody        = problemType{code: "malformed-body", title: "Malformed request body", status: http.StatusBadRequest, legacyStatus: http.StatusBadRequest}
+	problemNotAcceptable        = problemType{code: "not-acceptable", title: "Response format not supported", status: http.StatusNotAcceptable, legacyStatus: http.StatusNotAcceptable}
+	problemUnsupportedMediaType = problemType{code: "unsupported-media-type", title: "Request format not supported", status: http.StatusUnsupportedMediaType, legacyStatus: http.StatusUnsupportedMediaType}
+	problemUpstreamUnavailable  = problemType{code: "upstream-unavailable", title: "album-store could not be reached", status: http.StatusBadGateway, legacyStatus: http.StatusInternalServerError}
+	problemUpstreamBadResponse  = problemType{code: "upstream-bad-response", title: "album-store sent an unreadable response", status: http.StatusBadGateway, legacyStatus: http.StatusInternalServerError}
+	problemUpstreamError        = problemType{code: "upstream-error", title: "album-store returned an error", status: http.StatusBadGateway, legacyStatus: http.StatusBadGateway}
+)
+
+// problemCatalog lists every error code proxy-service creates itself.
+var problemCatalog = []problemType{
+	problemInvalidID, problemRequestInvalid, problemMalformedBody, problemNotAcceptable, problemUnsupportedMediaType,
+	problemUpstreamUnavailable, problemUpstreamBadResponse, problemUpstreamError,
+}
+
+// problemDetailsErrors switches every response to application/problem+json, set with ERROR_FORMAT=problem.
+// Otherwise existing clients keep the legacy model.ServerError body and only requests accepting application/problem+json get problems.
+var problemDetailsErrors = false
+
+func useProblemDetails(c *gin.Context) bool {
+	return problemDetailsErrors || strings.Contains(c.GetHeader("Accept"), problemContentType)
+}
+
+// abortWithProblem writes the error as problem details or as the legacy model.ServerError, recording the code and status on the span.
+func abortWithProblem(c *gin.Context, span trace.Span, problem problemType, detail string) {
+	statusCode := problem.legacyStatus
+	if useProblemDetails(c) {
+		statusCode = problem.status
+	}
+	writeProblem(c, span, model.Problem{Type: problemTypeBaseURI + problem.code, Title: problem.title, Status: statusCode, Detail: detail, Code: problem.code})
+}
+
+// writeProblem sends problem, falling back to a legacy model.ServerError with the detail as message when the client has not asked for problems.
+// album-store's field validation messages are passed on unchanged either way.
+func writeProblem(c *gin.Context, span trace.Span, problem model.Problem) {
+	span.SetAttributes(attribute.Key("proxy-service.error.code").String(problem.Code))
+	telemetry.SetResponseCode(span, problem.Status)
+	logProblem(c, problem)
+	if !useProblemDetails(c) {
+		c.AbortWithStatusJSON(problem.Status, model.ServerError{Message: problem.Detail, BindingErrors: problem.BindingErrors})
+		return
+	}
+	problem.Instance = c.Request.URL.Path
+	if span.SpanContext().HasTraceID() {
+		problem.TraceID = span.SpanContext().TraceID().String()
+	}
+	problemJson, _ := json.Marshal(problem)
+	telemetry.SetBodyAttribute(c.Request.Context(), span, "proxy-service.response.body", string(problemJson))
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// upstreamProblem reads an album-store error as a problem, keeping album-store's code, title and binding errors.
+// A legacy album-store error is reported as upstream-error with album-store's status and message.
+func upstreamProblem(responseError *client.ResponseError) model.Problem {
+	if responseError.Problem != nil {
+		problem := *responseError.Problem
+		problem.Status = responseError.StatusCode
+		return problem
+	}
+	return model.Problem{
+		Type:          problemTypeBaseURI + problemUpstreamError.code,
+		Title:         problemUpstreamError.title,
+		Status:        responseError.StatusCode,
+		Detail:        responseError.ServerError.Message,
+		Code:          problemUpstreamError.code,
+		BindingErrors: responseError.ServerError.BindingErrors,
+	}
+}
+
+// logProblem logs an error response with the request logger, as an error when it is the server's fault.
+func logProblem(c *gin.Context, problem model.Problem) {
+	log := telemetry.RequestLogger(c)
+	event := log.Warn()
+	if problem.Status >= http.StatusInternalServerError {
+		event = log.Error()
+	}
+	event.Int("status", problem.Status).Str("code", problem.Code).Msg(problem.Detail)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mcarr-and/go-gin-otelcollector/proxy-service/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getAlbumById_Upstream_Problem_Keeps_Code(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	var forwardedAccept string
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(req *http.Request) (*http.Response, error) {
+		forwardedAccept = req.Header.Get("Accept")
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Header:     http.Header{"Content-Type": []string{"application/problem+json"}},
+			Body: io.NopCloser(strings.NewReader(`{"type":"/problems/album-not-found","title":"Album not found","status":404,` +
+				`"detail":"Album [666] not found","instance":"/albums/666","code":"album-not-found","trace_id":"0af7651916cd43dd8448eb211c80319c"}`)),
+		}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/666", nil)
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	router.ServeHTTP(testRecorder, req)
+
+	var problem model.Problem
+	assert.NoError(t, json.Unmarshal(testRecorder.Body.Bytes(), &problem))
+	assert.Equal(t, "application/json, application/problem+json", forwardedAccept)
+	assert.Equal(t, http.StatusNotFound, testRecorder.Code)
+	assert.Equal(t, "application/problem+json", testRecorder.Header().Get("Content-Type"))
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, model.Problem{
+		Type:     "/problems/album-not-found",
+		Title:    "Album not found",
+		Status:   http.StatusNotFound,
+		Detail:   "Album [666] not found",
+		Instance: "/albums/666",
+		Code:     "album-not-found",
+		TraceID:  finishedSpans[0].SpanContext().TraceID().String(),
+	}, problem)
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "album-not-found", attributeMap["proxy-service.error.code"].Emit())
+}
+
+func Test_postAlbums_Upstream_Problem_Legacy_Client(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	var forwardedAccept string
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(req *http.Request) (*http.Response, error) {
+		forwardedAccept = req.Header.Get("Accept")
+		return &http.Response{
+			StatusCode: http.StatusBadRequest,
+			Header:     http.Header{"Content-Type": []string{"application/problem+json"}},
+			Body: io.NopCloser(strings.NewReader(`{"type":"/problems/validation-failed","title":"Field validation failed","status":400,` +
+				`"code":"validation-failed","errors":[{"field":"title","message":"below minimum value"}]}`)),
+		}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader(`{"id": 10, "title": "T", "artist": "Black Sabbath", "price": 66.60}`))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, "", forwardedAccept)
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":[{"field":"title","message":"below minimum value"}],"message":"album-store returned error postAlbum"}`, testRecorder.Body.String())
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	assert.Equal(t, "validation-failed", attributeMap["proxy-service.error.code"].Emit())
+}
+
+func Test_postAlbums_Upstream_Legacy_Error_As_Problem(t *testing.T) {
+	problemDetailsErrors = true
+	defer func() { problemDetailsErrors = false }()
+	testRecorder, _, router := setupTestRouter(t)
+	mockAlbumStoreResponse(http.StatusBadRequest, `{"errors":[{"field":"title","message":"below minimum value"}],"message":""}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader(`{"id": 10, "title": "T", "artist": "Black Sabbath", "price": 66.60}`))
+	router.ServeHTTP(testRecorder, req)
+
+	var problem model.Problem
+	assert.NoError(t, json.Unmarshal(testRecorder.Body.Bytes(), &problem))
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, "upstream-error", problem.Code)
+	assert.Equal(t, []*model.BindingErrorMsg{{Field: "title", Message: "below minimum value"}}, problem.BindingErrors)
+}
+
+func Test_postAlbums_Forwards_Accept_Language(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+	var forwardedAcceptLanguage string
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(req *http.Request) (*http.Response, error) {
+		forwardedAcceptLanguage = req.Header.Get("Accept-Language")
+		return &http.Response{
+			StatusCode: http.StatusBadRequest,
+			Body:       io.NopCloser(strings.NewReader(`{"errors":[{"field":"title","message":"doit contenir au moins 2 caractères"}],"message":""}`)),
+		}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/albums", strings.NewReader(`{"id": 10, "title": "T", "artist": "Black Sabbath", "price": 66.60}`))
+	req.Header.Set("Accept-Language", "fr-CA,fr;q=0.9")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, "fr-CA,fr;q=0.9", forwardedAcceptLanguage)
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":[{"field":"title","message":"doit contenir au moins 2 caractères"}],"message":"album-store returned error postAlbum"}`, testRecorder.Body.String())
+}
+
+func Test_getAlbums_Upstream_Unavailable_Problem(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("ERROR FROM WEB SERVER")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+	req.Header.Set("Accept", "application/problem+json, application/json")
+	router.ServeHTTP(testRecorder, req)
+
+	var problem model.Problem
+	assert.NoError(t, json.Unmarshal(testRecorder.Body.Bytes(), &problem))
+	assert.Equal(t, http.StatusBadGateway, testRecorder.Code)
+	assert.Equal(t, "upstream-unavailable", problem.Code)
+	assert.Equal(t, "error contacting album-store getAlbums ERROR FROM WEB SERVER", problem.Detail)
+}
+
+func Test_problemCatalog_StableCodes(t *testing.T) {
+	codes := map[string]bool{}
+	for _, problem := range problemCatalog {
+		assert.False(t, codes[problem.code], "duplicate code %s", problem.code)
+		codes[problem.code] = true
+		assert.NotEmpty(t, problem.title)
+		assert.GreaterOrEqual(t, problem.status, 400)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func Test_propagator_Forwards_B3_And_Baggage_To_Album_Store(t *testing.T) {
+	propagator, _ := telemetry.NewPropagator("tracecontext,baggage,b3")
+	otel.SetTextMapPropagator(propagator)
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	var albumStoreHeaders http.Header
+	albumStore := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		albumStoreHeaders = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer albumStore.Close()
+	defer func(url string) { albumStoreURL = url }(albumStoreURL)
+	albumStoreURL = albumStore.URL
+
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	defaultClient := DefaultClient
+	t.Cleanup(func() { DefaultClient = defaultClient })
+	DefaultClient = &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+
+	req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+	req.Header.Set("b3", "0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-1")
+	req.Header.Set("baggage", "customer=abc")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", finishedSpans[0].SpanContext().TraceID().String())
+	assert.Regexp(t, "^0af7651916cd43dd8448eb211c80319c-[0-9a-f]{16}-1$", albumStoreHeaders.Get("b3"))
+	assert.Regexp(t, "^00-0af7651916cd43dd8448eb211c80319c-[0-9a-f]{16}-01$", albumStoreHeaders.Get("traceparent"))
+	assert.Equal(t, "customer=abc", albumStoreHeaders.Get("baggage"))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_attributeConvention_Standard(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	telemetry.SpanConvention = telemetry.StandardConvention
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(`{"id":2,"title":"Jeru","artist":"Gerry Mulligan","price":17.99}`)))}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/2?format=json", nil)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	assert.Equal(t, "200", attributeMap["http.response.status_code"].Emit())
+	assert.Equal(t, "GET", attributeMap["http.request.method"].Emit())
+	assert.Equal(t, "/albums/2", attributeMap["url.path"].Emit())
+	assert.Equal(t, "format=json", attributeMap["url.query"].Emit())
+	assert.Equal(t, "http", attributeMap["url.scheme"].Emit())
+	assert.NotContains(t, attributeMap, "proxy-service.response.code")
+	assert.NotContains(t, attributeMap, "proxy-service.request.parameters")
+	assert.NotContains(t, attributeMap, "album-store.response.code")
+	assert.Equal(t, "https://opentelemetry.io/schemas/1.23.0", requestSpans(spanRecorder)[0].InstrumentationScope().SchemaURL)
+}
+
+func Test_attributeConvention_Legacy(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	telemetry.SpanConvention = telemetry.LegacyConvention
+	DefaultClient = &MockClient{}
+	MockResponseFunc = func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(`{"id":2,"title":"Jeru","artist":"Gerry Mulligan","price":17.99}`)))}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/2", nil)
+	router.ServeHTTP(testRecorder, req)
+
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	assert.Equal(t, "200", attributeMap["proxy-service.response.code"].Emit())
+	assert.Equal(t, "ID=2", attributeMap["proxy-service.request.parameters"].Emit())
+	assert.Equal(t, "200", attributeMap["album-store.response.code"].Emit())
+	assert.NotContains(t, attributeMap, "http.response.status_code")
+	assert.NotContains(t, attributeMap, "url.path")
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_routeSampler(t *testing.T) {
+	resetAlbums()
+	sampler, _ := telemetry.NewRouteSampler("/status=0,/albums/:id=1", sdktrace.NeverSample())
+	spanRecorder := tracetest.NewSpanRecorder()
+	tracerProvider := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(tracerProvider) })
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler), sdktrace.WithSpanProcessor(spanRecorder)))
+	router := setupRouter(zerolog.New(os.Stdout))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/status", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/albums", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/albums/1", nil))
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, "/albums/:id", finishedSpans[0].Name())
+}
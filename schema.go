@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	schemaContentType = "application/schema+json"
+	jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// fieldMetadata is a model field's JSON name and the constraints from its binding tag.
+type fieldMetadata struct {
+	jsonName string
+	jsonType string
+	required bool
+	minimum  *float64
+	maximum  *float64
+	enum     []string
+}
+
+// modelMetadata describes a request model, fields are in struct order and looked up by Go field name.
+type modelMetadata struct {
+	name   string
+	fields []*fieldMetadata
+	byName map[string]*fieldMetadata
+}
+
+// modelRegistry holds the metadata of every model bound from a request body, keyed by struct type.
+// It is built when the service starts so a model missing a json tag stops the service at boot rather than failing mid-request.
+var modelRegistry = newModelRegistry(model.Album{}, model.AlbumV2{}, model.CartLineRequest{}, model.OrderRequest{}, model.OrderStatusRequest{}, graphqlRequest{})
+
+func newModelRegistry(models ...interface{}) map[reflect.Type]*modelMetadata {
+	registry := map[reflect.Type]*modelMetadata{}
+	for _, target := range models {
+		metadata, err := newModelMetadata(reflect.TypeOf(target))
+		if err != nil {
+			panic(err)
+		}
+		registry[reflect.TypeOf(target)] = metadata
+	}
+	return registry
+}
+
+func newModelMetadata(modelType reflect.Type) (*modelMetadata, error) {
+	metadata := &modelMetadata{name: modelType.Name(), byName: map[string]*fieldMetadata{}}
+	for index := 0; index < modelType.NumField(); index++ {
+		structField := modelType.Field(index)
+		jsonTag, okay := structField.Tag.Lookup("json")
+		jsonName := strings.Split(jsonTag, ",")[0]
+		if !okay || jsonName == "" || jsonName == "-" {
+			return nil, fmt.Errorf("no json tag on struct model.%s %s, expecting `json:\"title\" ...`", modelType.Name(), structField.Name)
+		}
+		field := &fieldMetadata{jsonName: jsonName, jsonType: jsonSchemaType(structField.Type.Kind())}
+		if err := field.addConstraints(structField.Tag.Get("binding")); err != nil {
+			return nil, fmt.Errorf("binding tag on struct model.%s %s: %w", modelType.Name(), structField.Name, err)
+		}
+		metadata.fields = append(metadata.fields, field)
+		metadata.byName[structField.Name] = field
+	}
+	return metadata, nil
+}
+
+// addConstraints reads the validator tags. A min above zero also makes the field required because the validator rejects the zero value
+// of a missing field, tags the schema cannot express are left to the server.
+func (field *fieldMetadata) addConstraints(bindingTag string) error {
+	if bindingTag == "" {
+		return nil
+	}
+	for _, rule := range strings.Split(bindingTag, ",") {
+		tag, param, _ := strings.Cut(rule, "=")
+		switch tag {
+		case "required":
+			field.required = true
+		case "min", "max":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return fmt.Errorf("%s=%s is not a number", tag, param)
+			}
+			if tag == "min" {
+				field.minimum = &limit
+				field.required = field.required || limit > 0
+			} else {
+				field.maximum = &limit
+			}
+		case "oneof":
+			field.enum = strings.Fields(param)
+		}
+	}
+	return nil
+}
+
+// excludesZero reports whether the validator rejects a value the minimum allows: required rejects the zero value of a number,
+// so a required number with min=0 must be above zero.
+func (field *fieldMetadata) excludesZero() bool {
+	return field.required && field.minimum != nil && *field.minimum == 0 &&
+		(field.jsonType == "number" || field.jsonType == "integer")
+}
+
+func jsonSchemaType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// metadataFor returns the registered metadata for a model or a pointer to one.
+func metadataFor(target interface{}) (*modelMetadata, bool) {
+	modelType := reflect.TypeOf(target)
+	if modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	metadata, okay := modelRegistry[modelType]
+	return metadata, okay
+}
+
+// jsonName is the JSON name of a Go field, the Go name is used for a field the registry does not know.
+func (metadata *modelMetadata) jsonName(fieldName string) string {
+	if field, okay := metadata.byName[fieldName]; okay {
+		return field.jsonName
+	}
+	return fieldName
+}
+
+// byJsonName finds a field by its JSON name.
+func (metadata *modelMetadata) byJsonName(name string) (*fieldMetadata, bool) {
+	for _, field := range metadata.fields {
+		if field.jsonName == name {
+			return field, true
+		}
+	}
+	return nil, false
+}
+
+// hasJsonName reports whether name is a JSON field of the model, ignoring case as encoding/json does.
+func (metadata *modelMetadata) hasJsonName(name string) bool {
+	for _, field := range metadata.fields {
+		if strings.EqualFold(field.jsonName, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonSchema renders the model as a JSON Schema, min and max are lengths for strings, item counts for arrays and values for numbers.
+// A required number with min=0 gets exclusiveMinimum because required rejects 0.
+func (metadata *modelMetadata) jsonSchema(id string) gin.H {
+	properties := gin.H{}
+	required := []string{}
+	for _, field := range metadata.fields {
+		property := gin.H{"type": field.jsonType}
+		minimumKey, maximumKey := "minimum", "maximum"
+		switch field.jsonType {
+		case "string":
+			minimumKey, maximumKey = "minLength", "maxLength"
+		case "array":
+			minimumKey, maximumKey = "minItems", "maxItems"
+		}
+		if field.excludesZero() {
+			minimumKey = "exclusiveMinimum"
+		}
+		if field.minimum != nil {
+			property[minimumKey] = *field.minimum
+		}
+		if field.maximum != nil {
+			property[maximumKey] = *field.maximum
+		}
+		if field.enum != nil {
+			property["enum"] = field.enum
+		}
+		properties[field.jsonName] = property
+		if field.required {
+			required = append(required, field.jsonName)
+		}
+	}
+	return gin.H{
+		"$schema":    jsonSchemaDialect,
+		"$id":        id,
+		"title":      metadata.name,
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// GetAlbumSchema godoc
+// @Summary JSON Schema for Album
+// @Schemes
+// @Description get the JSON Schema of an album, derived from the validation rules, to validate an album before sending it
+// @Tags albums
+// @Produce application/schema+json
+// @Success 200 {object} object
+// @Router /schemas/album [get]
+func getAlbumSchema(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	metadata, _ := metadataFor(model.Album{})
+	span.SetStatus(codes.Ok, "")
+	telemetry.SetResponseCode(span, http.StatusOK)
+	c.Header("Content-Type", schemaContentType)
+	c.JSON(http.StatusOK, metadata.jsonSchema(c.Request.URL.Path))
+}
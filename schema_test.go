@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func Test_getAlbumSchema(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/schemas/album", nil))
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, "application/schema+json", testRecorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id": "/schemas/album",
+		"title": "Album",
+		"type": "object",
+		"properties": {
+			"id": {"type": "integer", "minimum": 1, "maximum": 10000},
+			"title": {"type": "string", "minLength": 2, "maxLength": 1000},
+			"artist": {"type": "string", "minLength": 2, "maxLength": 1000},
+			"price": {"type": "number", "exclusiveMinimum": 0, "maximum": 10000}
+		},
+		"required": ["id", "title", "artist", "price"]
+	}`, testRecorder.Body.String())
+
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Len(t, finishedSpans, 1)
+	assert.Equal(t, "/schemas/album", finishedSpans[0].Name())
+	assert.Equal(t, codes.Ok, finishedSpans[0].Status().Code)
+}
+
+func Test_modelRegistry_OrderStatusRequest(t *testing.T) {
+	metadata, okay := metadataFor(&model.OrderStatusRequest{})
+
+	assert.True(t, okay)
+	assert.Equal(t, "status", metadata.jsonName("Status"))
+	assert.Equal(t, []string{"shipped", "cancelled"}, metadata.jsonSchema("/schemas/order-status")["properties"].(gin.H)["status"].(gin.H)["enum"])
+}
+
+func Test_newModelMetadata_MissingJsonTag(t *testing.T) {
+	type noJsonTag struct {
+		Title string `binding:"required"`
+	}
+
+	_, err := newModelMetadata(reflect.TypeOf(noJsonTag{}))
+
+	assert.EqualError(t, err, "no json tag on struct model.noJsonTag Title, expecting `json:\"title\" ...`")
+	assert.Panics(t, func() { newModelRegistry(noJsonTag{}) })
+}
+
+func Test_newModelMetadata_InvalidLimit(t *testing.T) {
+	type badLimit struct {
+		Title string `json:"title" binding:"min=two"`
+	}
+
+	_, err := newModelMetadata(reflect.TypeOf(badLimit{}))
+
+	assert.EqualError(t, err, "binding tag on struct model.badLimit Title: min=two is not a number")
+}
+
+func Test_buildBindingErrorMessages_UnregisteredModel(t *testing.T) {
+	type contact struct {
+		Email string `json:"email" binding:"required"`
+	}
+	var validationErrors validator.ValidationErrors
+	validationErrors, _ = binding.Validator.ValidateStruct(&contact{}).(validator.ValidationErrors)
+
+	assert.Equal(t, []*model.BindingErrorMsg{{Field: "Email", Message: "is required"}},
+		buildBindingErrorMessages(validationErrors, &contact{}, translatorForLanguages("")))
+}
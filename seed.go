@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"gopkg.in/yaml.v3"
+)
+
+// seedAlbums is the catalog the store starts with and returns to on resetAlbums.
+var seedAlbums = defaultSeedAlbums()
+
+func defaultSeedAlbums() []model.Album {
+	return []model.Album{
+		{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 56.99},
+		{ID: 2, Title: "Jeru", Artist: "Gerry Mulligan", Price: 17.99},
+		{ID: 3, Title: "Sarah Vaughan and Clifford Brown", Artist: "Sarah Vaughan", Price: 39.99},
+	}
+}
+
+// loadSeedAlbums reads albums from a .json, .yaml or .yml file, validating each album with its binding rules.
+func loadSeedAlbums(path string) ([]model.Album, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed file: %w", err)
+	}
+	var seeds []model.Album
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(content, &seeds)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &seeds)
+	default:
+		return nil, fmt.Errorf("seed file %s must be .json, .yaml or .yml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse seed file %s: %w", path, err)
+	}
+	albumIDs := make(map[int]bool, len(seeds))
+	for index, album := range seeds {
+		if err := binding.Validator.ValidateStruct(album); err != nil {
+			return nil, fmt.Errorf("seed album %d is invalid: %w", index, err)
+		}
+		if albumIDs[album.ID] {
+			return nil, fmt.Errorf("seed album %d has duplicate id %d", index, album.ID)
+		}
+		albumIDs[album.ID] = true
+	}
+	return seeds, nil
+}
+
+var (
+	generatedAdjectives = []string{"Blue", "Midnight", "Electric", "Silent", "Golden", "Broken", "Velvet", "Crimson", "Lonely", "Wild", "Hidden", "Burning", "Frozen", "Sweet", "Restless"}
+	generatedNouns      = []string{"Train", "River", "Horizon", "Echoes", "Garden", "Highway", "Dreams", "Machine", "Sessions", "Skyline", "Harbor", "Letters", "Fire", "Moon", "Streets"}
+	generatedFirstNames = []string{"Miles", "Nina", "Thelonious", "Ella", "Charles", "Billie", "Ornette", "Sarah", "Herbie", "Alice", "Wayne", "Dinah", "Chet", "Etta", "Dexter"}
+	generatedLastNames  = []string{"Davis", "Simone", "Monk", "Fitzgerald", "Mingus", "Holiday", "Coleman", "Vaughan", "Hancock", "Coltrane", "Shorter", "Washington", "Baker", "James", "Gordon"}
+)
+
+// generateAlbums builds count valid albums with ids 1..count. The same seed always produces the same catalog.
+func generateAlbums(count int, seed int64) []model.Album {
+	random := rand.New(rand.NewSource(seed))
+	pick := func(values []string) string {
+		return values[random.Intn(len(values))]
+	}
+	generated := make([]model.Album, count)
+	for index := range generated {
+		generated[index] = model.Album{
+			ID:     index + 1,
+			Title:  fmt.Sprintf("%s %s", pick(generatedAdjectives), pick(generatedNouns)),
+			Artist: fmt.Sprintf("%s %s", pick(generatedFirstNames), pick(generatedLastNames)),
+			Price:  math.Round((4.99+random.Float64()*95)*100) / 100,
+		}
+	}
+	return generated
+}
+
+// runGenerate is the `generate` subcommand, writing a generated catalog usable as a seed file.
+func runGenerate(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	count := flags.Int("count", 100, "number of albums to generate (1-10000)")
+	seed := flags.Int64("seed", 1, "random seed, the same seed always generates the same albums")
+	format := flags.String("format", "json", "output format json or yaml")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *count < 1 || *count > 10000 {
+		return fmt.Errorf("count %d must be between 1 and 10000", *count)
+	}
+	generated := generateAlbums(*count, *seed)
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(generated)
+	case "yaml":
+		return yaml.NewEncoder(out).Encode(generated)
+	default:
+		return fmt.Errorf("format %s must be json or yaml", *format)
+	}
+}
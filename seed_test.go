@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeSeedFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		assert.FailNow(t, "failed to write seed file", err.Error())
+	}
+	return path
+}
+
+func Test_loadSeedAlbums_JSON(t *testing.T) {
+	path := writeSeedFile(t, "albums.json", `[{"id": 7, "title": "Kind of Blue", "artist": "Miles Davis", "price": 21.99}]`)
+
+	seeds, err := loadSeedAlbums(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Album{{ID: 7, Title: "Kind of Blue", Artist: "Miles Davis", Price: 21.99}}, seeds)
+}
+
+func Test_loadSeedAlbums_YAML(t *testing.T) {
+	path := writeSeedFile(t, "albums.yaml", "- id: 7\n  title: Kind of Blue\n  artist: Miles Davis\n  price: 21.99\n")
+
+	seeds, err := loadSeedAlbums(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Album{{ID: 7, Title: "Kind of Blue", Artist: "Miles Davis", Price: 21.99}}, seeds)
+}
+
+func Test_loadSeedAlbums_Invalid(t *testing.T) {
+	_, err := loadSeedAlbums(writeSeedFile(t, "albums.json", `[{"id": 7, "title": "K", "artist": "Miles Davis", "price": 21.99}]`))
+	assert.ErrorContains(t, err, "seed album 0 is invalid")
+
+	_, err = loadSeedAlbums(writeSeedFile(t, "albums.json", `[{"id": 7, "title": "Kind of Blue", "artist": "Miles Davis", "price": 21.99},{"id": 7, "title": "Kind of Blue", "artist": "Miles Davis", "price": 21.99}]`))
+	assert.ErrorContains(t, err, "seed album 1 has duplicate id 7")
+
+	_, err = loadSeedAlbums(writeSeedFile(t, "albums.csv", `7,Kind of Blue,Miles Davis,21.99`))
+	assert.ErrorContains(t, err, "must be .json, .yaml or .yml")
+
+	_, err = loadSeedAlbums(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorContains(t, err, "failed to read seed file")
+}
+
+func Test_generateAlbums_Deterministic(t *testing.T) {
+	generated := generateAlbums(250, 42)
+
+	assert.Equal(t, generated, generateAlbums(250, 42))
+	assert.NotEqual(t, generated, generateAlbums(250, 43))
+	assert.Equal(t, 250, len(generated))
+	for index, album := range generated {
+		assert.Equal(t, index+1, album.ID)
+		assert.NoError(t, binding.Validator.ValidateStruct(album))
+	}
+}
+
+func Test_runGenerate(t *testing.T) {
+	var out bytes.Buffer
+	var generated []model.Album
+
+	err := runGenerate([]string{"-count", "5", "-seed", "7"}, &out)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &generated))
+	assert.Equal(t, generateAlbums(5, 7), generated)
+
+	assert.Error(t, runGenerate([]string{"-count", "0"}, &out))
+	assert.Error(t, runGenerate([]string{"-format", "xml"}, &out))
+}
+
+func Test_getAllAlbums_GeneratedSeed(t *testing.T) {
+	seedAlbums = generateAlbums(500, 1)
+	resetAlbums()
+	defer func() {
+		seedAlbums = defaultSeedAlbums()
+		resetAlbums()
+	}()
+	testRecorder, _, router := setupTestRouter(t)
+
+	var albums []model.Album
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/albums", nil))
+	if err := json.Unmarshal(testRecorder.Body.Bytes(), &albums); err != nil {
+		assert.Fail(t, "json unmarshal fail", "should be []Albums ", albums)
+	}
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, 500, len(albums))
+}
+
+func Benchmark_getAllAlbums_Generated(b *testing.B) {
+	seedAlbums = generateAlbums(1000, 1)
+	resetAlbums()
+	defer func() {
+		seedAlbums = defaultSeedAlbums()
+		resetAlbums()
+	}()
+	testRecorder, _, router := setupTestRouter(b)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(testRecorder, req)
+		testRecorder.Body.Reset() //get requests need resets else the returned body is concatenated
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mcarr-and/go-gin-otelcollector/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_attributeConvention_Standard(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	telemetry.SpanConvention = telemetry.StandardConvention
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/2?fields=title", nil)
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	assert.Equal(t, "200", attributeMap["http.response.status_code"].Emit())
+	assert.Equal(t, "GET", attributeMap["http.request.method"].Emit())
+	assert.Equal(t, "/albums/2", attributeMap["url.path"].Emit())
+	assert.Equal(t, "fields=title", attributeMap["url.query"].Emit())
+	assert.Equal(t, "http", attributeMap["url.scheme"].Emit())
+	assert.NotContains(t, attributeMap, "album-store.response.code")
+	assert.NotContains(t, attributeMap, "album-store.request.parameters")
+	assert.Equal(t, "https://opentelemetry.io/schemas/1.23.0", requestSpans(spanRecorder)[0].InstrumentationScope().SchemaURL)
+}
+
+func Test_attributeConvention_Legacy(t *testing.T) {
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+	telemetry.SpanConvention = telemetry.LegacyConvention
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/2", nil)
+	router.ServeHTTP(testRecorder, req)
+
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	assert.Equal(t, "200", attributeMap["album-store.response.code"].Emit())
+	assert.Equal(t, "ID=2", attributeMap["album-store.request.parameters"].Emit())
+	assert.NotContains(t, attributeMap, "http.response.status_code")
+	assert.NotContains(t, attributeMap, "url.path")
+}
@@ -0,0 +1,253 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultBodyCaptureMaxSize = 4096
+	redactedValue             = "[REDACTED]"
+)
+
+// BodyCapture is the policy for the request and response bodies recorded on spans, nil records every body in full.
+var BodyCapture *BodyCapturePolicy
+
+// bodyCaptureKey holds the capture decision of the request in the request context.
+type bodyCaptureKey struct{}
+
+// BodyCapturePolicy decides which bodies are recorded on spans, and how much of them.
+// A body is captured at the ratio of its route's rule, or ratio, by trace ID so the services of a trace capture the same traces.
+// Captured JSON bodies have the values at the redact paths replaced, and are cut to maxSize bytes when maxSize is above 0.
+type BodyCapturePolicy struct {
+	ratio   sdktrace.Sampler
+	routes  map[string]sdktrace.Sampler
+	maxSize int
+	redact  []jsonPath
+}
+
+// BodyCapturePolicyFromEnv reads BODY_CAPTURE_RATIO, the fraction of traces with bodies captured, default 1,
+// BODY_CAPTURE_ROUTES, comma separated route=ratio rules, BODY_CAPTURE_MAX_SIZE, in bytes, default 4096 and 0 for no limit,
+// and BODY_CAPTURE_REDACT, the comma separated JSON paths of values to redact.
+func BodyCapturePolicyFromEnv() (*BodyCapturePolicy, error) {
+	ratio := 1.0
+	if ratioEnv := os.Getenv("BODY_CAPTURE_RATIO"); ratioEnv != "" {
+		var err error
+		if ratio, err = parseSamplingRatio(ratioEnv); err != nil {
+			return nil, fmt.Errorf("BODY_CAPTURE_RATIO=%v %w", ratioEnv, err)
+		}
+	}
+	maxSize := defaultBodyCaptureMaxSize
+	if maxSizeEnv := os.Getenv("BODY_CAPTURE_MAX_SIZE"); maxSizeEnv != "" {
+		var err error
+		if maxSize, err = strconv.Atoi(maxSizeEnv); err != nil || maxSize < 0 {
+			return nil, fmt.Errorf("BODY_CAPTURE_MAX_SIZE=%v is not a size in bytes", maxSizeEnv)
+		}
+	}
+	return NewBodyCapturePolicy(ratio, os.Getenv("BODY_CAPTURE_ROUTES"), maxSize, os.Getenv("BODY_CAPTURE_REDACT"))
+}
+
+// NewBodyCapturePolicy captures bodies at ratio, or the ratio of the route=ratio rules in routeRules, cut to maxSize and redacted at the comma separated redactPaths.
+func NewBodyCapturePolicy(ratio float64, routeRules string, maxSize int, redactPaths string) (*BodyCapturePolicy, error) {
+	policy := &BodyCapturePolicy{ratio: sdktrace.TraceIDRatioBased(ratio), routes: map[string]sdktrace.Sampler{}, maxSize: maxSize}
+	for _, rule := range strings.Split(routeRules, ",") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		route, routeRatio, found := strings.Cut(rule, "=")
+		if !found {
+			return nil, fmt.Errorf("BODY_CAPTURE_ROUTES rule %v must be route=ratio", rule)
+		}
+		fraction, err := parseSamplingRatio(routeRatio)
+		if err != nil {
+			return nil, fmt.Errorf("BODY_CAPTURE_ROUTES rule %v %w", rule, err)
+		}
+		policy.routes[strings.TrimSpace(route)] = sdktrace.TraceIDRatioBased(fraction)
+	}
+	for _, path := range strings.Split(redactPaths, ",") {
+		if strings.TrimSpace(path) == "" {
+			continue
+		}
+		parsed, err := parseJsonPath(strings.TrimSpace(path))
+		if err != nil {
+			return nil, fmt.Errorf("BODY_CAPTURE_REDACT path %v %w", path, err)
+		}
+		policy.redact = append(policy.redact, parsed)
+	}
+	return policy, nil
+}
+
+func (policy *BodyCapturePolicy) String() string {
+	rules := make([]string, 0, len(policy.routes))
+	for route, routeSampler := range policy.routes {
+		rules = append(rules, route+"="+routeSampler.Description())
+	}
+	sort.Strings(rules)
+	redact := make([]string, len(policy.redact))
+	for index, path := range policy.redact {
+		redact[index] = path.String()
+	}
+	return fmt.Sprintf("BodyCapture{%s;routes:%s;maxSize:%d;redact:%s}", policy.ratio.Description(), strings.Join(rules, ","), policy.maxSize, strings.Join(redact, ","))
+}
+
+// captures is true when the bodies of a request to route in the trace are recorded.
+func (policy *BodyCapturePolicy) captures(route string, traceID trace.TraceID) bool {
+	sampler, found := policy.routes[route]
+	if !found {
+		sampler = policy.ratio
+	}
+	return sampler.ShouldSample(sdktrace.SamplingParameters{TraceID: traceID}).Decision == sdktrace.RecordAndSample
+}
+
+// apply redacts and truncates body. A body that is not JSON can not be redacted, so it is replaced when there are paths to redact.
+func (policy *BodyCapturePolicy) apply(body string) string {
+	if len(policy.redact) > 0 && body != "" {
+		body = policy.redactJson(body)
+	}
+	if policy.maxSize > 0 && len(body) > policy.maxSize {
+		cut := policy.maxSize
+		for cut > 0 && !utf8.RuneStart(body[cut]) {
+			cut--
+		}
+		body = fmt.Sprintf("%s...[truncated %d bytes]", body[:cut], len(body)-cut)
+	}
+	return body
+}
+
+func (policy *BodyCapturePolicy) redactJson(body string) string {
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return fmt.Sprintf("[REDACTED %d bytes, not JSON]", len(body))
+	}
+	redacted := false
+	for _, path := range policy.redact {
+		document = path.redact(document, &redacted)
+	}
+	if !redacted {
+		return body
+	}
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(document)
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
+
+// BodyCaptureDecision decides once per request whether its bodies are recorded, it must come after otelgin so the request span has been started.
+func BodyCaptureDecision() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if BodyCapture != nil {
+			ctx := c.Request.Context()
+			captures := BodyCapture.captures(c.FullPath(), trace.SpanContextFromContext(ctx).TraceID())
+			c.Request = c.Request.WithContext(context.WithValue(ctx, bodyCaptureKey{}, captures))
+		}
+		c.Next()
+	}
+}
+
+// SetBodyAttribute records body on span under key as the BodyCapture policy allows.
+// Requests without a decision from BodyCaptureDecision are decided by the trace ID alone.
+func SetBodyAttribute(ctx context.Context, span trace.Span, key string, body string) {
+	if BodyCapture == nil {
+		span.SetAttributes(attribute.Key(key).String(body))
+		return
+	}
+	captures, decided := ctx.Value(bodyCaptureKey{}).(bool)
+	if !decided {
+		captures = BodyCapture.captures("", span.SpanContext().TraceID())
+	}
+	if captures {
+		span.SetAttributes(attribute.Key(key).String(BodyCapture.apply(body)))
+	}
+}
+
+// jsonPath is a parsed path such as $.card.number, $.lines[*].price or $..password,
+// a step of "*" matches every key or element, and a recursive step matches at any depth.
+type jsonPath struct {
+	raw   string
+	steps []jsonPathStep
+}
+
+type jsonPathStep struct {
+	name      string
+	recursive bool
+}
+
+func parseJsonPath(path string) (jsonPath, error) {
+	parsed := jsonPath{raw: path}
+	rest, found := strings.CutPrefix(path, "$")
+	if !found || rest == "" {
+		return parsed, fmt.Errorf("must start with $ and name a field")
+	}
+	rest = strings.ReplaceAll(rest, "[*]", ".*")
+	for rest != "" {
+		var step jsonPathStep
+		if strings.HasPrefix(rest, "..") {
+			step.recursive, rest = true, rest[2:]
+		} else if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+		} else {
+			return parsed, fmt.Errorf("must separate fields with .")
+		}
+		end := strings.IndexByte(rest, '.')
+		if end < 0 {
+			end = len(rest)
+		}
+		step.name, rest = rest[:end], rest[end:]
+		if step.name == "" || strings.ContainsAny(step.name, "[]") {
+			return parsed, fmt.Errorf("must name fields, or * for all fields and elements")
+		}
+		parsed.steps = append(parsed.steps, step)
+	}
+	return parsed, nil
+}
+
+func (path jsonPath) String() string {
+	return path.raw
+}
+
+// redact replaces the values of document at path, setting redacted when one was found.
+func (path jsonPath) redact(document interface{}, redacted *bool) interface{} {
+	return redactSteps(document, path.steps, redacted)
+}
+
+func redactSteps(value interface{}, steps []jsonPathStep, redacted *bool) interface{} {
+	if len(steps) == 0 {
+		*redacted = true
+		return redactedValue
+	}
+	step := steps[0]
+	switch node := value.(type) {
+	case map[string]interface{}:
+		for key, child := range node {
+			if step.name == "*" || step.name == key {
+				node[key] = redactSteps(child, steps[1:], redacted)
+			} else if step.recursive {
+				node[key] = redactSteps(child, steps, redacted)
+			}
+		}
+	case []interface{}:
+		for index, child := range node {
+			if step.name == "*" {
+				node[index] = redactSteps(child, steps[1:], redacted)
+			} else if step.recursive {
+				node[index] = redactSteps(child, steps, redacted)
+			}
+		}
+	}
+	return value
+}
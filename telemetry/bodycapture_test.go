@@ -0,0 +1,64 @@
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newBodyCapturePolicy_Invalid(t *testing.T) {
+	_, err := NewBodyCapturePolicy(1, "/albums", 0, "")
+	assert.EqualError(t, err, "BODY_CAPTURE_ROUTES rule /albums must be route=ratio")
+
+	_, err = NewBodyCapturePolicy(1, "/albums=2", 0, "")
+	assert.EqualError(t, err, "BODY_CAPTURE_ROUTES rule /albums=2 is not a ratio between 0 and 1")
+
+	_, err = NewBodyCapturePolicy(1, "", 0, "password")
+	assert.EqualError(t, err, "BODY_CAPTURE_REDACT path password must start with $ and name a field")
+}
+
+func Test_bodyCapturePolicyFromEnv(t *testing.T) {
+	t.Setenv("BODY_CAPTURE_RATIO", "0.5")
+	t.Setenv("BODY_CAPTURE_ROUTES", "/albums=0,/orders=1")
+	t.Setenv("BODY_CAPTURE_MAX_SIZE", "")
+	t.Setenv("BODY_CAPTURE_REDACT", "$..price")
+
+	policy, err := BodyCapturePolicyFromEnv()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "BodyCapture{TraceIDRatioBased{0.5};routes:/albums=TraceIDRatioBased{0},/orders=AlwaysOnSampler;maxSize:4096;redact:$..price}", policy.String())
+
+	t.Setenv("BODY_CAPTURE_MAX_SIZE", "-1")
+	_, err = BodyCapturePolicyFromEnv()
+	assert.EqualError(t, err, "BODY_CAPTURE_MAX_SIZE=-1 is not a size in bytes")
+}
+
+func Test_bodyCapturePolicy_apply_Redact(t *testing.T) {
+	for _, test := range []struct {
+		path     string
+		body     string
+		expected string
+	}{
+		{path: "$.price", body: `{"id":10,"price":66.60}`, expected: `{"id":10,"price":"[REDACTED]"}`},
+		{path: "$.card.number", body: `{"card":{"number":"4111","name":"Ozzy"}}`, expected: `{"card":{"name":"Ozzy","number":"[REDACTED]"}}`},
+		{path: "$.lines[*].price", body: `{"lines":[{"price":1},{"price":2}]}`, expected: `{"lines":[{"price":"[REDACTED]"},{"price":"[REDACTED]"}]}`},
+		{path: "$..price", body: `[{"id":1,"price":1},{"id":2,"price":2}]`, expected: `[{"id":1,"price":"[REDACTED]"},{"id":2,"price":"[REDACTED]"}]`},
+		{path: "$.password", body: `{"id": 10, "title": "<b>"}`, expected: `{"id": 10, "title": "<b>"}`},
+		{path: "$.password", body: `<album><id>10</id></album>`, expected: `[REDACTED 26 bytes, not JSON]`},
+	} {
+		policy, err := NewBodyCapturePolicy(1, "", 0, test.path)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, policy.apply(test.body), test.path)
+	}
+}
+
+func Test_bodyCapturePolicy_apply_Truncate(t *testing.T) {
+	policy, _ := NewBodyCapturePolicy(1, "", 10, "")
+
+	assert.Equal(t, `{"id":10}`, policy.apply(`{"id":10}`))
+	assert.Equal(t, `{"title":"...[truncated 12 bytes]`, policy.apply(`{"title":"Motörhead"}`))
+
+	// 14 bytes ends inside the two bytes of ö, which is left out whole
+	policy.maxSize = 14
+	assert.Equal(t, `{"title":"Mot...[truncated 9 bytes]`, policy.apply(`{"title":"Motörhead"}`))
+}
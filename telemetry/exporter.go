@@ -0,0 +1,321 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	otlpProtocolGrpc = "grpc"
+	otlpProtocolHttp = "http/protobuf"
+
+	otlpSignalTraces  = "TRACES"
+	otlpSignalMetrics = "METRICS"
+	otlpSignalLogs    = "LOGS"
+)
+
+// otlpSignalPaths are the paths the OTLP/HTTP receiver takes each signal on, added to OTEL_EXPORTER_OTLP_ENDPOINT.
+var otlpSignalPaths = map[string]string{
+	otlpSignalTraces:  "/v1/traces",
+	otlpSignalMetrics: "/v1/metrics",
+	otlpSignalLogs:    "/v1/logs",
+}
+
+// otlpExporterConfig is where and how a signal is exported to the collector.
+type otlpExporterConfig struct {
+	protocol  string
+	endpoint  string // host:port
+	urlPath   string // OTLP/HTTP only
+	insecure  bool
+	tlsConfig *tls.Config
+	headers   map[string]string
+	timeout   time.Duration
+	gzip      bool
+	retry     otlpRetryConfig
+}
+
+// otlpRetryConfig retries failed exports with exponential backoff from initialInterval up to maxInterval, giving up after maxElapsedTime.
+type otlpRetryConfig struct {
+	enabled         bool
+	initialInterval time.Duration
+	maxInterval     time.Duration
+	maxElapsedTime  time.Duration
+}
+
+// otlpExporterConfigFromEnv reads the OTEL_EXPORTER_OTLP_* variables of the OpenTelemetry SDK environment variable spec for signal,
+// the OTEL_EXPORTER_OTLP_<signal>_* variable winning over the one for all signals.
+// Without an endpoint the signal is sent over insecure OTLP/HTTP to otelLocation, a host:port.
+func otlpExporterConfigFromEnv(signal string, otelLocation string) (otlpExporterConfig, error) {
+	env := func(name string) string {
+		if value := os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_" + name); value != "" {
+			return value
+		}
+		return os.Getenv("OTEL_EXPORTER_OTLP_" + name)
+	}
+	config := otlpExporterConfig{protocol: env("PROTOCOL"), headers: map[string]string{}}
+	if config.protocol == "" {
+		config.protocol = otlpProtocolHttp
+	}
+	if config.protocol != otlpProtocolGrpc && config.protocol != otlpProtocolHttp {
+		return config, fmt.Errorf("OTEL_EXPORTER_OTLP_PROTOCOL=%v must be %v or %v", config.protocol, otlpProtocolGrpc, otlpProtocolHttp)
+	}
+
+	var err error
+	if err = config.setEndpoint(signal, otelLocation, env("INSECURE")); err != nil {
+		return config, err
+	}
+	if !config.insecure {
+		if config.tlsConfig, err = otlpTLSConfig(env("CERTIFICATE"), env("CLIENT_CERTIFICATE"), env("CLIENT_KEY")); err != nil {
+			return config, err
+		}
+	}
+	if headers := env("HEADERS"); headers != "" {
+		if config.headers, err = otlpHeaders(headers); err != nil {
+			return config, err
+		}
+	}
+	config.timeout = 10 * time.Second
+	if timeout := env("TIMEOUT"); timeout != "" {
+		milliseconds, err := strconv.Atoi(timeout)
+		if err != nil || milliseconds <= 0 {
+			return config, fmt.Errorf("OTEL_EXPORTER_OTLP_TIMEOUT=%v is not a positive number of milliseconds", timeout)
+		}
+		config.timeout = time.Duration(milliseconds) * time.Millisecond
+	}
+	switch compression := env("COMPRESSION"); compression {
+	case "", "gzip":
+		config.gzip = true
+	case "none":
+	default:
+		return config, fmt.Errorf("OTEL_EXPORTER_OTLP_COMPRESSION=%v must be gzip or none", compression)
+	}
+	config.retry, err = otlpRetryConfigFromEnv(env)
+	return config, err
+}
+
+// setEndpoint takes the endpoint from OTEL_EXPORTER_OTLP_<signal>_ENDPOINT, a URL used as is,
+// or from OTEL_EXPORTER_OTLP_ENDPOINT, a base URL the signal's path is added to for OTLP/HTTP.
+// An http URL is insecure, an https URL uses TLS, an endpoint without scheme uses TLS unless OTEL_EXPORTER_OTLP_INSECURE=true.
+func (config *otlpExporterConfig) setEndpoint(signal string, otelLocation string, insecure string) error {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_ENDPOINT")
+	signalPath := ""
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		signalPath = otlpSignalPaths[signal]
+	}
+	if endpoint == "" {
+		if otelLocation == "" {
+			return fmt.Errorf("OTEL_LOCATION or OTEL_EXPORTER_OTLP_ENDPOINT must be set")
+		}
+		// insecure transport here DO NOT USE IN PROD
+		config.endpoint, config.urlPath, config.insecure = otelLocation, otlpSignalPaths[signal], true
+		return nil
+	}
+	if !strings.Contains(endpoint, "://") {
+		config.endpoint, config.urlPath, config.insecure = endpoint, otlpSignalPaths[signal], insecure == "true"
+		return nil
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || (endpointURL.Scheme != "http" && endpointURL.Scheme != "https") || endpointURL.Host == "" {
+		return fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT=%v is not an http or https URL", endpoint)
+	}
+	config.endpoint, config.insecure = endpointURL.Host, endpointURL.Scheme == "http"
+	config.urlPath = strings.TrimSuffix(endpointURL.Path, "/") + signalPath
+	if config.urlPath == "" {
+		config.urlPath = "/"
+	}
+	return nil
+}
+
+// otlpTLSConfig trusts the CA certificates in the PEM file caFile as well as the system ones,
+// and presents the client certificate in certFile with the key in keyFile for mTLS. Empty files are not used.
+func otlpTLSConfig(caFile string, certFile string, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_CERTIFICATE=%v can not be read: %w", caFile, err)
+		}
+		tlsConfig.RootCAs, err = x509.SystemCertPool()
+		if err != nil {
+			tlsConfig.RootCAs = x509.NewCertPool()
+		}
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_CERTIFICATE=%v has no PEM certificates", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		clientCertificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE=%v and OTEL_EXPORTER_OTLP_CLIENT_KEY=%v can not be loaded: %w", certFile, keyFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCertificate}
+	}
+	return tlsConfig, nil
+}
+
+// otlpHeaders parses comma separated key=value pairs, values are URL encoded.
+func otlpHeaders(headers string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, header := range strings.Split(headers, ",") {
+		key, value, found := strings.Cut(header, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_HEADERS header %v must be key=value", key)
+		}
+		decoded, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_HEADERS header %v value is not URL encoded", key)
+		}
+		parsed[key] = decoded
+	}
+	return parsed, nil
+}
+
+// otlpRetryConfigFromEnv reads OTEL_EXPORTER_OTLP_RETRY_*, which are not in the spec, defaulting to the exporters' own retry settings.
+func otlpRetryConfigFromEnv(env func(string) string) (otlpRetryConfig, error) {
+	retry := otlpRetryConfig{enabled: true, initialInterval: 5 * time.Second, maxInterval: 30 * time.Second, maxElapsedTime: time.Minute}
+	if enabled := env("RETRY_ENABLED"); enabled != "" {
+		var err error
+		if retry.enabled, err = strconv.ParseBool(enabled); err != nil {
+			return retry, fmt.Errorf("OTEL_EXPORTER_OTLP_RETRY_ENABLED=%v must be true or false", enabled)
+		}
+	}
+	for name, duration := range map[string]*time.Duration{
+		"RETRY_INITIAL_INTERVAL": &retry.initialInterval,
+		"RETRY_MAX_INTERVAL":     &retry.maxInterval,
+		"RETRY_MAX_ELAPSED_TIME": &retry.maxElapsedTime,
+	} {
+		if value := env(name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				return retry, fmt.Errorf("OTEL_EXPORTER_OTLP_%v=%v is not a positive duration", name, value)
+			}
+			*duration = parsed
+		}
+	}
+	return retry, nil
+}
+
+// String describes the config for the startup log, header values are left out as they often hold credentials.
+func (config otlpExporterConfig) String() string {
+	scheme := "https"
+	if config.insecure {
+		scheme = "http"
+	}
+	headerNames := make([]string, 0, len(config.headers))
+	for name := range config.headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	description := fmt.Sprintf("%v %v://%v", config.protocol, scheme, config.endpoint)
+	if config.protocol == otlpProtocolHttp {
+		description += config.urlPath
+	}
+	if config.tlsConfig != nil && len(config.tlsConfig.Certificates) > 0 {
+		description += " mTLS"
+	}
+	return fmt.Sprintf("%v headers:%v timeout:%v gzip:%v retry:%v", description, headerNames, config.timeout, config.gzip, config.retry.enabled)
+}
+
+func setupOtelTraceExporter(ctx context.Context, config otlpExporterConfig) (*otlptrace.Exporter, error) {
+	var traceExporter *otlptrace.Exporter
+	var err error
+	if config.protocol == otlpProtocolGrpc {
+		options := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(config.endpoint),
+			otlptracegrpc.WithHeaders(config.headers),
+			otlptracegrpc.WithTimeout(config.timeout),
+			otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{Enabled: config.retry.enabled, InitialInterval: config.retry.initialInterval, MaxInterval: config.retry.maxInterval, MaxElapsedTime: config.retry.maxElapsedTime}),
+		}
+		if config.insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		} else {
+			options = append(options, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(config.tlsConfig)))
+		}
+		if config.gzip {
+			options = append(options, otlptracegrpc.WithCompressor("gzip"))
+		}
+		traceExporter, err = otlptracegrpc.New(ctx, options...)
+	} else {
+		options := []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(config.endpoint),
+			otlptracehttp.WithURLPath(config.urlPath),
+			otlptracehttp.WithHeaders(config.headers),
+			otlptracehttp.WithTimeout(config.timeout),
+			otlptracehttp.WithRetry(otlptracehttp.RetryConfig{Enabled: config.retry.enabled, InitialInterval: config.retry.initialInterval, MaxInterval: config.retry.maxInterval, MaxElapsedTime: config.retry.maxElapsedTime}),
+		}
+		if config.insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		} else {
+			options = append(options, otlptracehttp.WithTLSClientConfig(config.tlsConfig))
+		}
+		if config.gzip {
+			options = append(options, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		}
+		traceExporter, err = otlptracehttp.New(ctx, options...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	return traceExporter, nil
+}
+
+func setupOtelMetricExporter(ctx context.Context, config otlpExporterConfig) (sdkmetric.Exporter, error) {
+	var metricExporter sdkmetric.Exporter
+	var err error
+	if config.protocol == otlpProtocolGrpc {
+		options := []otlpmetricgrpc.Option{
+			otlpmetricgrpc.WithEndpoint(config.endpoint),
+			otlpmetricgrpc.WithHeaders(config.headers),
+			otlpmetricgrpc.WithTimeout(config.timeout),
+			otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig{Enabled: config.retry.enabled, InitialInterval: config.retry.initialInterval, MaxInterval: config.retry.maxInterval, MaxElapsedTime: config.retry.maxElapsedTime}),
+		}
+		if config.insecure {
+			options = append(options, otlpmetricgrpc.WithInsecure())
+		} else {
+			options = append(options, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(config.tlsConfig)))
+		}
+		if config.gzip {
+			options = append(options, otlpmetricgrpc.WithCompressor("gzip"))
+		}
+		metricExporter, err = otlpmetricgrpc.New(ctx, options...)
+	} else {
+		options := []otlpmetrichttp.Option{
+			otlpmetrichttp.WithEndpoint(config.endpoint),
+			otlpmetrichttp.WithURLPath(config.urlPath),
+			otlpmetrichttp.WithHeaders(config.headers),
+			otlpmetrichttp.WithTimeout(config.timeout),
+			otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{Enabled: config.retry.enabled, InitialInterval: config.retry.initialInterval, MaxInterval: config.retry.maxInterval, MaxElapsedTime: config.retry.maxElapsedTime}),
+		}
+		if config.insecure {
+			options = append(options, otlpmetrichttp.WithInsecure())
+		} else {
+			options = append(options, otlpmetrichttp.WithTLSClientConfig(config.tlsConfig))
+		}
+		if config.gzip {
+			options = append(options, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+		}
+		metricExporter, err = otlpmetrichttp.New(ctx, options...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+	}
+	return metricExporter, nil
+}
@@ -0,0 +1,287 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// testCertificates are a self-signed CA with a server certificate for 127.0.0.1 and a client certificate, written as PEM files.
+type testCertificates struct {
+	caFile         string
+	clientCertFile string
+	clientKeyFile  string
+	serverTLS      *tls.Config
+}
+
+func newTestCertificates(t *testing.T) testCertificates {
+	directory := t.TempDir()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	assert.NoError(t, err)
+	caCertificate, _ := x509.ParseCertificate(caDER)
+	certificates := testCertificates{caFile: filepath.Join(directory, "ca.pem")}
+	writePEM(t, certificates.caFile, "CERTIFICATE", caDER)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) tls.Certificate {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "127.0.0.1"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCertificate, &key.PublicKey, caKey)
+		assert.NoError(t, err)
+		keyDER, _ := x509.MarshalECPrivateKey(key)
+		if usage == x509.ExtKeyUsageClientAuth {
+			certificates.clientCertFile, certificates.clientKeyFile = filepath.Join(directory, "client.pem"), filepath.Join(directory, "client-key.pem")
+			writePEM(t, certificates.clientCertFile, "CERTIFICATE", der)
+			writePEM(t, certificates.clientKeyFile, "EC PRIVATE KEY", keyDER)
+		}
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+	caPool := x509.NewCertPool()
+	caPool.AddCert(caCertificate)
+	certificates.serverTLS = &tls.Config{
+		Certificates: []tls.Certificate{issue(2, x509.ExtKeyUsageServerAuth)},
+		ClientCAs:    caPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	issue(3, x509.ExtKeyUsageClientAuth)
+	return certificates
+}
+
+func writePEM(t *testing.T, file string, blockType string, der []byte) {
+	assert.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
+
+// setTLSEnv points the exporters at endpoint, trusting the test CA and presenting the client certificate.
+func setTLSEnv(t *testing.T, certificates testCertificates, protocol string, endpoint string) {
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", protocol)
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", endpoint)
+	t.Setenv("OTEL_EXPORTER_OTLP_CERTIFICATE", certificates.caFile)
+	t.Setenv("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE", certificates.clientCertFile)
+	t.Setenv("OTEL_EXPORTER_OTLP_CLIENT_KEY", certificates.clientKeyFile)
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=secret%20key")
+	t.Setenv("OTEL_EXPORTER_OTLP_RETRY_ENABLED", "false")
+}
+
+func Test_otlpExporterConfigFromEnv_OTEL_LOCATION(t *testing.T) {
+	exporterConfig, err := otlpExporterConfigFromEnv(otlpSignalTraces, "otel-collector:4318")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "http/protobuf http://otel-collector:4318/v1/traces headers:[] timeout:10s gzip:true retry:true", exporterConfig.String())
+	assert.Nil(t, exporterConfig.tlsConfig)
+}
+
+func Test_otlpExporterConfigFromEnv(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "https://collector.example.com:4318/otlp/")
+	t.Setenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT", "http://metrics.example.com:9090/ingest")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=secret%20key, tenant=albums")
+	t.Setenv("OTEL_EXPORTER_OTLP_TIMEOUT", "2500")
+	t.Setenv("OTEL_EXPORTER_OTLP_METRICS_COMPRESSION", "none")
+	t.Setenv("OTEL_EXPORTER_OTLP_RETRY_MAX_ELAPSED_TIME", "10s")
+
+	traces, err := otlpExporterConfigFromEnv(otlpSignalTraces, "otel-collector:4318")
+	assert.NoError(t, err)
+	assert.Equal(t, "http/protobuf https://collector.example.com:4318/otlp/v1/traces headers:[api-key tenant] timeout:2.5s gzip:true retry:true", traces.String())
+	assert.Equal(t, map[string]string{"api-key": "secret key", "tenant": "albums"}, traces.headers)
+	assert.Equal(t, 10*time.Second, traces.retry.maxElapsedTime)
+	assert.NotNil(t, traces.tlsConfig)
+
+	metrics, err := otlpExporterConfigFromEnv(otlpSignalMetrics, "otel-collector:4318")
+	assert.NoError(t, err)
+	assert.Equal(t, "http/protobuf http://metrics.example.com:9090/ingest headers:[api-key tenant] timeout:2.5s gzip:false retry:true", metrics.String())
+}
+
+func Test_otlpExporterConfigFromEnv_Grpc_Without_Scheme(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "otel-collector:4317")
+	t.Setenv("OTEL_EXPORTER_OTLP_INSECURE", "true")
+
+	exporterConfig, err := otlpExporterConfigFromEnv(otlpSignalLogs, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "grpc http://otel-collector:4317 headers:[] timeout:10s gzip:true retry:true", exporterConfig.String())
+}
+
+func Test_otlpExporterConfigFromEnv_Invalid(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		value string
+		err   string
+	}{
+		{name: "OTEL_EXPORTER_OTLP_PROTOCOL", value: "http/json", err: "OTEL_EXPORTER_OTLP_PROTOCOL=http/json must be grpc or http/protobuf"},
+		{name: "OTEL_EXPORTER_OTLP_ENDPOINT", value: "ftp://collector", err: "OTEL_EXPORTER_OTLP_ENDPOINT=ftp://collector is not an http or https URL"},
+		{name: "OTEL_EXPORTER_OTLP_HEADERS", value: "api-key", err: "OTEL_EXPORTER_OTLP_HEADERS header api-key must be key=value"},
+		{name: "OTEL_EXPORTER_OTLP_TIMEOUT", value: "10s", err: "OTEL_EXPORTER_OTLP_TIMEOUT=10s is not a positive number of milliseconds"},
+		{name: "OTEL_EXPORTER_OTLP_COMPRESSION", value: "zstd", err: "OTEL_EXPORTER_OTLP_COMPRESSION=zstd must be gzip or none"},
+		{name: "OTEL_EXPORTER_OTLP_RETRY_ENABLED", value: "sometimes", err: "OTEL_EXPORTER_OTLP_RETRY_ENABLED=sometimes must be true or false"},
+		{name: "OTEL_EXPORTER_OTLP_TRACES_RETRY_MAX_INTERVAL", value: "-1s", err: "OTEL_EXPORTER_OTLP_RETRY_MAX_INTERVAL=-1s is not a positive duration"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(test.name, test.value)
+			_, err := otlpExporterConfigFromEnv(otlpSignalTraces, "otel-collector:4318")
+			assert.EqualError(t, err, test.err)
+		})
+	}
+	_, err := otlpExporterConfigFromEnv(otlpSignalTraces, "")
+	assert.EqualError(t, err, "OTEL_LOCATION or OTEL_EXPORTER_OTLP_ENDPOINT must be set")
+}
+
+func Test_otlpExporterConfigFromEnv_Certificate_Not_Found(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "https://collector.example.com:4318")
+	t.Setenv("OTEL_EXPORTER_OTLP_CERTIFICATE", filepath.Join(t.TempDir(), "missing.pem"))
+
+	_, err := otlpExporterConfigFromEnv(otlpSignalTraces, "")
+
+	assert.ErrorContains(t, err, "missing.pem can not be read")
+}
+
+func Test_setupOtelTraceExporter_Http_mTLS(t *testing.T) {
+	certificates := newTestCertificates(t)
+	received := make(chan *http.Request, 1)
+	collector := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	collector.TLS = certificates.serverTLS
+	collector.StartTLS()
+	defer collector.Close()
+	setTLSEnv(t, certificates, otlpProtocolHttp, collector.URL)
+
+	exporterConfig, err := otlpExporterConfigFromEnv(otlpSignalTraces, "")
+	assert.NoError(t, err)
+	traceExporter, err := setupOtelTraceExporter(context.Background(), exporterConfig)
+	assert.NoError(t, err)
+	assert.NoError(t, traceExporter.ExportSpans(context.Background(), tracetest.SpanStubs{{Name: "/albums GET"}}.Snapshots()))
+
+	request := <-received
+	assert.Equal(t, "/v1/traces", request.URL.Path)
+	assert.Equal(t, "secret key", request.Header.Get("api-key"))
+	assert.Equal(t, "gzip", request.Header.Get("Content-Encoding"))
+	assert.Len(t, request.TLS.PeerCertificates, 1)
+	assert.NoError(t, traceExporter.Shutdown(context.Background()))
+}
+
+func Test_setupOtelTraceExporter_Http_mTLS_Without_Client_Certificate(t *testing.T) {
+	certificates := newTestCertificates(t)
+	collector := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	collector.TLS = certificates.serverTLS
+	collector.StartTLS()
+	defer collector.Close()
+	setTLSEnv(t, certificates, otlpProtocolHttp, collector.URL)
+	t.Setenv("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_CLIENT_KEY", "")
+
+	exporterConfig, err := otlpExporterConfigFromEnv(otlpSignalTraces, "")
+	assert.NoError(t, err)
+	traceExporter, err := setupOtelTraceExporter(context.Background(), exporterConfig)
+	assert.NoError(t, err)
+
+	assert.Error(t, traceExporter.ExportSpans(context.Background(), tracetest.SpanStubs{{Name: "/albums GET"}}.Snapshots()))
+}
+
+type testTraceCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+	received chan metadata.MD
+}
+
+func (collector testTraceCollector) Export(ctx context.Context, _ *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	incoming, _ := metadata.FromIncomingContext(ctx)
+	collector.received <- incoming
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+type testLogsCollector struct {
+	collogspb.UnimplementedLogsServiceServer
+	received chan *collogspb.ExportLogsServiceRequest
+}
+
+func (collector testLogsCollector) Export(_ context.Context, request *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	collector.received <- request
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+// startGrpcCollector serves the OTLP trace and logs services over mTLS on a local port.
+func startGrpcCollector(t *testing.T, certificates testCertificates) (string, testTraceCollector, testLogsCollector) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	grpcServer := grpc.NewServer(grpc.Creds(credentials.NewTLS(certificates.serverTLS)))
+	traceCollector := testTraceCollector{received: make(chan metadata.MD, 1)}
+	logsCollector := testLogsCollector{received: make(chan *collogspb.ExportLogsServiceRequest, 1)}
+	coltracepb.RegisterTraceServiceServer(grpcServer, traceCollector)
+	collogspb.RegisterLogsServiceServer(grpcServer, logsCollector)
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(grpcServer.Stop)
+	return "https://" + listener.Addr().String(), traceCollector, logsCollector
+}
+
+func Test_setupOtelTraceExporter_Grpc_mTLS(t *testing.T) {
+	certificates := newTestCertificates(t)
+	endpoint, traceCollector, _ := startGrpcCollector(t, certificates)
+	setTLSEnv(t, certificates, otlpProtocolGrpc, endpoint)
+
+	exporterConfig, err := otlpExporterConfigFromEnv(otlpSignalTraces, "")
+	assert.NoError(t, err)
+	traceExporter, err := setupOtelTraceExporter(context.Background(), exporterConfig)
+	assert.NoError(t, err)
+	assert.NoError(t, traceExporter.ExportSpans(context.Background(), tracetest.SpanStubs{{Name: "/albums GET"}}.Snapshots()))
+
+	incoming := <-traceCollector.received
+	assert.Equal(t, []string{"secret key"}, incoming.Get("api-key"))
+	assert.NoError(t, traceExporter.Shutdown(context.Background()))
+}
+
+func Test_setupOtelLoggerProvider_Grpc_mTLS(t *testing.T) {
+	certificates := newTestCertificates(t)
+	endpoint, _, logsCollector := startGrpcCollector(t, certificates)
+	setTLSEnv(t, certificates, otlpProtocolGrpc, endpoint)
+
+	exporterConfig, err := otlpExporterConfigFromEnv(otlpSignalLogs, "")
+	assert.NoError(t, err)
+	provider, err := setupOtelLoggerProvider(exporterConfig, resource.NewSchemaless(attribute.Key("service.name").String(ServiceName)))
+	assert.NoError(t, err)
+	log := zerolog.New(provider)
+	log.Info().Msg("catalog loaded")
+	assert.NoError(t, provider.Shutdown(context.Background()))
+
+	request := <-logsCollector.received
+	assert.Equal(t, "catalog loaded", request.ResourceLogs[0].ScopeLogs[0].LogRecords[0].Body.GetStringValue())
+}
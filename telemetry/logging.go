@@ -0,0 +1,327 @@
+package telemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+const requestLoggerKey = "telemetry.logger"
+
+// NewLogger writes JSON log lines to out, and to loggerProvider when logs are exported over OTLP.
+func NewLogger(out io.Writer) zerolog.Logger {
+	if loggerProvider != nil {
+		out = zerolog.MultiLevelWriter(out, loggerProvider)
+	}
+	return zerolog.New(out).With().Timestamp().Logger()
+}
+
+// WithTraceContext adds the trace_id and span_id of the span in ctx to every event of log, so log lines can be found from a trace.
+func WithTraceContext(ctx context.Context, log zerolog.Logger) zerolog.Logger {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return log
+	}
+	return log.With().Str("trace_id", spanContext.TraceID().String()).Str("span_id", spanContext.SpanID().String()).Logger()
+}
+
+// RequestLogging puts a logger for the request, carrying its trace_id and span_id, in the Gin context,
+// and logs the handled request with it in place of Gin's uncorrelated access log.
+// It must come after otelgin so the request span has been started.
+func RequestLogging(log zerolog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestLog := WithTraceContext(c.Request.Context(), log)
+		c.Set(requestLoggerKey, requestLog)
+		c.Next()
+		event := requestLog.Info()
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			event = requestLog.Error()
+		}
+		event.Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Str("route", c.FullPath()).
+			Int("status", c.Writer.Status()).
+			Dur("latency", time.Since(start)).
+			Str("client_ip", c.ClientIP()).
+			Msg("request handled")
+	}
+}
+
+// Recovery turns a panic in a handler into a 500, logging it with the request logger in place of Gin's uncorrelated recovery log.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		RequestLogger(c).Error().Interface("panic", recovered).Msg("request panicked")
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+// RequestLogger is the request-scoped logger set by RequestLogging, a logger without trace context when there is none.
+func RequestLogger(c *gin.Context) *zerolog.Logger {
+	log, okay := c.Get(requestLoggerKey)
+	if !okay {
+		log = WithTraceContext(c.Request.Context(), NewLogger(io.Discard))
+	}
+	requestLog := log.(zerolog.Logger)
+	return &requestLog
+}
+
+// loggerProvider exports log lines over OTLP when OTEL_LOGS_EXPORTER=otlp, nil otherwise.
+var loggerProvider *otlpLoggerProvider
+
+const (
+	logExportInterval  = time.Second
+	logExportBatchSize = 512
+)
+
+// otlpLoggerProvider is a zerolog writer that batches JSON log lines as OTLP log records and sends them to the collector.
+// The OpenTelemetry Go SDK used here has no logs signal, so records are built from the OTLP protobuf directly.
+type otlpLoggerProvider struct {
+	resource *resourcepb.Resource
+	send     func(context.Context, *collogspb.ExportLogsServiceRequest) error
+	close    func() error
+	records  []*logspb.LogRecord
+	mutex    sync.Mutex
+	flush    chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+// setupOtelLoggerProvider starts exporting log lines every logExportInterval, or as soon as logExportBatchSize lines are waiting.
+// Failed exports are not retried, the lines are still written to stdout & stderr.
+func setupOtelLoggerProvider(exporterConfig otlpExporterConfig, otelResource *resource.Resource) (*otlpLoggerProvider, error) {
+	provider := &otlpLoggerProvider{
+		resource: &resourcepb.Resource{Attributes: otlpAttributes(otelResource.Attributes())},
+		flush:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	if exporterConfig.protocol == otlpProtocolGrpc {
+		if err := provider.sendOverGrpc(exporterConfig); err != nil {
+			return nil, err
+		}
+	} else {
+		provider.sendOverHttp(exporterConfig)
+	}
+	go provider.run()
+	return provider, nil
+}
+
+func (provider *otlpLoggerProvider) sendOverHttp(exporterConfig otlpExporterConfig) {
+	scheme := "https"
+	if exporterConfig.insecure {
+		scheme = "http"
+	}
+	endpoint := fmt.Sprintf("%s://%s%s", scheme, exporterConfig.endpoint, exporterConfig.urlPath)
+	httpClient := &http.Client{Timeout: exporterConfig.timeout, Transport: &http.Transport{TLSClientConfig: exporterConfig.tlsConfig}}
+	provider.close = func() error {
+		httpClient.CloseIdleConnections()
+		return nil
+	}
+	provider.send = func(ctx context.Context, exportRequest *collogspb.ExportLogsServiceRequest) error {
+		body, err := proto.Marshal(exportRequest)
+		if err != nil {
+			return fmt.Errorf("failed to marshal log records: %w", err)
+		}
+		if exporterConfig.gzip {
+			var compressed bytes.Buffer
+			gzipWriter := gzip.NewWriter(&compressed)
+			_, _ = gzipWriter.Write(body)
+			_ = gzipWriter.Close()
+			body = compressed.Bytes()
+		}
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to create log export request: %w", err)
+		}
+		for name, value := range exporterConfig.headers {
+			request.Header.Set(name, value)
+		}
+		request.Header.Set("Content-Type", "application/x-protobuf")
+		if exporterConfig.gzip {
+			request.Header.Set("Content-Encoding", "gzip")
+		}
+		response, err := httpClient.Do(request)
+		if err != nil {
+			return fmt.Errorf("failed to export logs: %w", err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode >= http.StatusMultipleChoices {
+			return fmt.Errorf("failed to export logs: collector returned %s", response.Status)
+		}
+		return nil
+	}
+}
+
+func (provider *otlpLoggerProvider) sendOverGrpc(exporterConfig otlpExporterConfig) error {
+	transportCredentials := insecure.NewCredentials()
+	if !exporterConfig.insecure {
+		transportCredentials = credentials.NewTLS(exporterConfig.tlsConfig)
+	}
+	connection, err := grpc.Dial(exporterConfig.endpoint, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return fmt.Errorf("failed to create log exporter: %w", err)
+	}
+	logsClient := collogspb.NewLogsServiceClient(connection)
+	var callOptions []grpc.CallOption
+	if exporterConfig.gzip {
+		callOptions = append(callOptions, grpc.UseCompressor(grpcgzip.Name))
+	}
+	provider.close = connection.Close
+	provider.send = func(ctx context.Context, exportRequest *collogspb.ExportLogsServiceRequest) error {
+		ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(ctx, metadata.New(exporterConfig.headers)), exporterConfig.timeout)
+		defer cancel()
+		if _, err := logsClient.Export(ctx, exportRequest, callOptions...); err != nil {
+			return fmt.Errorf("failed to export logs: %w", err)
+		}
+		return nil
+	}
+	return nil
+}
+
+func (provider *otlpLoggerProvider) run() {
+	defer close(provider.stopped)
+	ticker := time.NewTicker(logExportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-provider.flush:
+		case <-provider.done:
+			return
+		}
+		if err := provider.export(context.Background()); err != nil {
+			otel.Handle(err)
+		}
+	}
+}
+
+// Write takes one zerolog JSON line, lines that are not JSON are sent as the body of the record.
+func (provider *otlpLoggerProvider) Write(line []byte) (int, error) {
+	record := otlpLogRecord(line)
+	provider.mutex.Lock()
+	provider.records = append(provider.records, record)
+	full := len(provider.records) >= logExportBatchSize
+	provider.mutex.Unlock()
+	if full {
+		select {
+		case provider.flush <- struct{}{}:
+		default:
+		}
+	}
+	return len(line), nil
+}
+
+// Shutdown stops the export loop and sends the log lines still waiting, nothing to do when logs are not exported.
+func (provider *otlpLoggerProvider) Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	close(provider.done)
+	<-provider.stopped
+	return errors.Join(provider.export(ctx), provider.close())
+}
+
+func (provider *otlpLoggerProvider) export(ctx context.Context) error {
+	provider.mutex.Lock()
+	records := provider.records
+	provider.records = nil
+	provider.mutex.Unlock()
+	if len(records) == 0 {
+		return nil
+	}
+	return provider.send(ctx, &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		Resource:  provider.resource,
+		ScopeLogs: []*logspb.ScopeLogs{{Scope: &commonpb.InstrumentationScope{Name: ServiceName}, LogRecords: records}},
+	}}})
+}
+
+var otlpSeverities = map[string]logspb.SeverityNumber{
+	zerolog.TraceLevel.String(): logspb.SeverityNumber_SEVERITY_NUMBER_TRACE,
+	zerolog.DebugLevel.String(): logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+	zerolog.InfoLevel.String():  logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+	zerolog.WarnLevel.String():  logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+	zerolog.ErrorLevel.String(): logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+	zerolog.FatalLevel.String(): logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+	zerolog.PanicLevel.String(): logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4,
+}
+
+// otlpLogRecord maps the level, message, time, trace_id and span_id of a zerolog line onto the record, other fields become attributes.
+func otlpLogRecord(line []byte) *logspb.LogRecord {
+	now := uint64(time.Now().UnixNano())
+	record := &logspb.LogRecord{TimeUnixNano: now, ObservedTimeUnixNano: now}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(line, &fields); err != nil {
+		record.Body = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: string(bytes.TrimSpace(line))}}
+		return record
+	}
+	for key, value := range fields {
+		text, isText := value.(string)
+		switch {
+		case key == zerolog.LevelFieldName && isText:
+			record.SeverityText = text
+			record.SeverityNumber = otlpSeverities[text]
+		case key == zerolog.MessageFieldName && isText:
+			record.Body = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: text}}
+		case key == zerolog.TimestampFieldName && isText:
+			if timestamp, err := time.Parse(zerolog.TimeFieldFormat, text); err == nil {
+				record.TimeUnixNano = uint64(timestamp.UnixNano())
+			}
+		case key == "trace_id" && isText:
+			record.TraceId, _ = hex.DecodeString(text)
+		case key == "span_id" && isText:
+			record.SpanId, _ = hex.DecodeString(text)
+		default:
+			record.Attributes = append(record.Attributes, &commonpb.KeyValue{Key: key, Value: otlpValue(value)})
+		}
+	}
+	return record
+}
+
+// otlpValue converts a decoded JSON value, objects and arrays are sent as their JSON.
+func otlpValue(value interface{}) *commonpb.AnyValue {
+	switch typed := value.(type) {
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: typed}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: typed}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: typed}}
+	default:
+		jsonValue, _ := json.Marshal(typed)
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: string(jsonValue)}}
+	}
+}
+
+func otlpAttributes(attributes []attribute.KeyValue) []*commonpb.KeyValue {
+	keyValues := make([]*commonpb.KeyValue, len(attributes))
+	for index, keyValue := range attributes {
+		keyValues[index] = &commonpb.KeyValue{Key: string(keyValue.Key), Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: keyValue.Value.Emit()}}}
+	}
+	return keyValues
+}
@@ -0,0 +1,85 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func Test_otlpLoggerProvider(t *testing.T) {
+	var exported collogspb.ExportLogsServiceRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/logs", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.NoError(t, proto.Unmarshal(body, &exported))
+	}))
+	defer collector.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_COMPRESSION", "none")
+	exporterConfig, err := otlpExporterConfigFromEnv(otlpSignalLogs, strings.TrimPrefix(collector.URL, "http://"))
+	assert.NoError(t, err)
+	provider, err := setupOtelLoggerProvider(exporterConfig, resource.NewSchemaless(attribute.Key("service.name").String(ServiceName)))
+	assert.NoError(t, err)
+
+	log := zerolog.New(provider)
+	log.Warn().Str("trace_id", "0102030405060708090a0b0c0d0e0f10").Str("span_id", "0102030405060708").Int("albums", 3).Msg("catalog loaded")
+	assert.NoError(t, provider.Shutdown(context.Background()))
+
+	assert.Equal(t, "service.name", exported.ResourceLogs[0].Resource.Attributes[0].Key)
+	records := exported.ResourceLogs[0].ScopeLogs[0].LogRecords
+	assert.Len(t, records, 1)
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, records[0].SeverityNumber)
+	assert.Equal(t, "catalog loaded", records[0].Body.GetStringValue())
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, records[0].TraceId)
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8}, records[0].SpanId)
+	assert.Equal(t, "albums", records[0].Attributes[0].Key)
+	assert.Equal(t, float64(3), records[0].Attributes[0].Value.GetDoubleValue())
+}
+
+func Test_otlpLoggerProvider_Export_Error(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+	exportErrors := make(chan error, 1)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		select {
+		case exportErrors <- err:
+		default:
+		}
+	}))
+	t.Cleanup(func() {
+		// the default handler cannot be set back, log to stderr as it does
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) { stdlog.Print(err) }))
+	})
+	t.Setenv("OTEL_EXPORTER_OTLP_COMPRESSION", "none")
+	exporterConfig, err := otlpExporterConfigFromEnv(otlpSignalLogs, strings.TrimPrefix(collector.URL, "http://"))
+	assert.NoError(t, err)
+	provider, err := setupOtelLoggerProvider(exporterConfig, resource.NewSchemaless(attribute.Key("service.name").String(ServiceName)))
+	assert.NoError(t, err)
+	defer provider.Shutdown(context.Background())
+
+	log := zerolog.New(provider)
+	log.Warn().Msg("catalog loaded")
+
+	select {
+	case err := <-exportErrors:
+		assert.ErrorContains(t, err, "503")
+	case <-time.After(3 * logExportInterval):
+		t.Fatal("export error was not reported to otel.Handle")
+	}
+}
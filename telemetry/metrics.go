@@ -0,0 +1,73 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// setupOtelMeterProvider exports metrics over OTLP to the collector and to the default Prometheus registry served on /metrics.
+func setupOtelMeterProvider(ctx context.Context, exporterConfig otlpExporterConfig, otelResource *resource.Resource) (*sdkmetric.MeterProvider, error) {
+	otlpExporter, err := setupOtelMetricExporter(ctx, exporterConfig)
+	if err != nil {
+		return nil, err
+	}
+	prometheusExporter, err := otelprometheus.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prometheus exporter: %w", err)
+	}
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(otelResource),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(otlpExporter)),
+		sdkmetric.WithReader(prometheusExporter),
+	)
+	global.SetMeterProvider(meterProvider)
+	return meterProvider, nil
+}
+
+// RequestMetrics records the rate, errors and duration of requests per route template and status code, and the attributes of labels when not nil.
+// A response with a 5xx status is an error. Instruments come from the global MeterProvider when the router is set up.
+func RequestMetrics(labels func(c *gin.Context) []attribute.KeyValue) gin.HandlerFunc {
+	meter := global.Meter(ServiceName)
+	requests, err := meter.Int64Counter("http.server.requests", metric.WithDescription("Requests handled."))
+	if err != nil {
+		otel.Handle(err)
+	}
+	requestErrors, err := meter.Int64Counter("http.server.errors", metric.WithDescription("Requests answered with a 5xx status."))
+	if err != nil {
+		otel.Handle(err)
+	}
+	duration, err := meter.Float64Histogram("http.server.duration", metric.WithUnit("ms"), metric.WithDescription("Time taken to handle requests."))
+	if err != nil {
+		otel.Handle(err)
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		keyValues := []attribute.KeyValue{
+			attribute.Key("http.method").String(c.Request.Method),
+			attribute.Key("http.route").String(c.FullPath()),
+			attribute.Key("http.status_code").Int(c.Writer.Status()),
+		}
+		if labels != nil {
+			keyValues = append(keyValues, labels(c)...)
+		}
+		attributes := metric.WithAttributes(keyValues...)
+		ctx := c.Request.Context()
+		requests.Add(ctx, 1, attributes)
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			requestErrors.Add(ctx, 1, attributes)
+		}
+		duration.Record(ctx, float64(time.Since(start))/float64(time.Millisecond), attributes)
+	}
+}
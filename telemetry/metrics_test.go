@@ -0,0 +1,57 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/global"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// setupTestMeter sets a MeterProvider with a reader the test can collect from, before instruments are created.
+func setupTestMeter() sdkmetric.Reader {
+	reader := sdkmetric.NewManualReader()
+	global.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	return reader
+}
+
+func collectMetric(t *testing.T, reader sdkmetric.Reader, name string) metricdata.Aggregation {
+	var resourceMetrics metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &resourceMetrics))
+	for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+		for _, metrics := range scopeMetrics.Metrics {
+			if metrics.Name == name {
+				return metrics.Data
+			}
+		}
+	}
+	t.Fatalf("metric %s not recorded", name)
+	return nil
+}
+
+func Test_RequestMetrics_Labels(t *testing.T) {
+	reader := setupTestMeter()
+	router := gin.New()
+	router.Use(RequestMetrics(func(c *gin.Context) []attribute.KeyValue {
+		return []attribute.KeyValue{attribute.Key("test.label").String(c.Param("id"))}
+	}))
+	router.GET("/albums/:id", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/albums/1", nil))
+
+	requestErrors := collectMetric(t, reader, "http.server.errors").(metricdata.Sum[int64])
+	assert.Len(t, requestErrors.DataPoints, 1)
+	expected := attribute.NewSet(
+		attribute.Key("http.method").String(http.MethodGet),
+		attribute.Key("http.route").String("/albums/:id"),
+		attribute.Key("http.status_code").Int(http.StatusInternalServerError),
+		attribute.Key("test.label").String("1"),
+	)
+	assert.True(t, requestErrors.DataPoints[0].Attributes.Equals(&expected), requestErrors.DataPoints[0].Attributes.Encoded(attribute.DefaultEncoder()))
+}
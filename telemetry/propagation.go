@@ -0,0 +1,51 @@
+package telemetry
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// defaultPropagators are the propagators of the OpenTelemetry SDK environment variable spec when OTEL_PROPAGATORS is not set.
+const defaultPropagators = "tracecontext,baggage"
+
+// propagatorFromEnv is the composite of the propagators named in OTEL_PROPAGATORS, used by otelgin on inbound requests
+// and by otelhttp on outbound ones once set as the global propagator.
+func propagatorFromEnv() (propagation.TextMapPropagator, error) {
+	names, found := os.LookupEnv("OTEL_PROPAGATORS")
+	if !found || strings.TrimSpace(names) == "" {
+		names = defaultPropagators
+	}
+	return NewPropagator(names)
+}
+
+// NewPropagator combines the comma separated propagators in names, extracting with each in turn and injecting the headers of all.
+// none is no propagation at all, b3 is the single b3 header and b3multi the X-B3-* headers.
+func NewPropagator(names string) (propagation.TextMapPropagator, error) {
+	var propagators []propagation.TextMapPropagator
+	for _, name := range strings.Split(names, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "tracecontext":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "b3":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case "b3multi":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case "jaeger":
+			propagators = append(propagators, jaeger.Jaeger{})
+		case "none":
+			if strings.TrimSpace(names) != "none" {
+				return nil, fmt.Errorf("OTEL_PROPAGATORS=%v can not combine none with other propagators", names)
+			}
+		default:
+			return nil, fmt.Errorf("OTEL_PROPAGATORS=%v propagator %v must be one of tracecontext, baggage, b3, b3multi, jaeger, none", names, name)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
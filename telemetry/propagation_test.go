@@ -0,0 +1,41 @@
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newPropagator(t *testing.T) {
+	for _, test := range []struct {
+		names  string
+		fields []string
+	}{
+		{names: defaultPropagators, fields: []string{"traceparent", "tracestate", "baggage"}},
+		{names: "b3", fields: []string{"b3"}},
+		{names: "b3multi", fields: []string{"x-b3-traceid", "x-b3-spanid", "x-b3-sampled", "x-b3-flags"}},
+		{names: "jaeger", fields: []string{"uber-trace-id"}},
+		{names: "none", fields: []string{}},
+	} {
+		propagator, err := NewPropagator(test.names)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, test.fields, propagator.Fields(), test.names)
+	}
+}
+
+func Test_newPropagator_Invalid(t *testing.T) {
+	_, err := NewPropagator("tracecontext,xray")
+	assert.EqualError(t, err, "OTEL_PROPAGATORS=tracecontext,xray propagator xray must be one of tracecontext, baggage, b3, b3multi, jaeger, none")
+
+	_, err = NewPropagator("none,b3")
+	assert.EqualError(t, err, "OTEL_PROPAGATORS=none,b3 can not combine none with other propagators")
+}
+
+func Test_propagatorFromEnv_Default(t *testing.T) {
+	t.Setenv("OTEL_PROPAGATORS", "")
+
+	propagator, err := propagatorFromEnv()
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"traceparent", "tracestate", "baggage"}, propagator.Fields())
+}
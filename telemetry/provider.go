@@ -0,0 +1,116 @@
+// Package telemetry sets up OpenTelemetry tracing, metrics and logs for album-store and proxy-service,
+// with the Gin middleware and span helpers both services use.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+// ServiceName names the tracer, meter and log scope of the service and prefixes its legacy span attribute keys, e.g. album-store.response.code.
+// Each service sets it in init.
+var ServiceName = "telemetry"
+
+// Set up the context for this Application in Open Telemetry
+// application name, application version, k8s namespace , k8s instance name (horizontal scaling)
+func setupOtelResource(version string, gitHash string, ctx context.Context, namespace *string, instanceName *string) (*resource.Resource, error) {
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String(ServiceName),
+			semconv.ServiceVersionKey.String(version+"-"+gitHash),
+			semconv.ServiceNamespaceKey.String(*namespace),
+			semconv.ServiceInstanceIDKey.String(*instanceName),
+		),
+		resource.WithSchemaURL(semconv.SchemaURL), // the resource attributes are the semconv 1.17 keys, span attributes have the schema of their scope
+	)
+	return res, err
+}
+
+// InitOtelProvider - Initializes an OTLP exporter, and configures the corresponding trace and metric providers.
+func InitOtelProvider(version string, gitHash string, log zerolog.Logger) (func(context.Context) error, error) {
+	ctx := context.Background()
+
+	namespace := os.Getenv("NAMESPACE")
+	instanceName := os.Getenv("INSTANCE_NAME")
+	otelLocation := os.Getenv("OTEL_LOCATION")
+	if instanceName == "" || namespace == "" || (otelLocation == "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "") {
+		log.Fatal().Msg(fmt.Sprintf("Env variables not assigned NAMESPACE=%v, INSTANCE_NAME=%v, OTEL_LOCATION=%v or OTEL_EXPORTER_OTLP_ENDPOINT", namespace, instanceName, otelLocation))
+	}
+	exporterConfigFromEnv := func(signal string) otlpExporterConfig {
+		exporterConfig, err := otlpExporterConfigFromEnv(signal, otelLocation)
+		if err != nil {
+			log.Fatal().Msg(fmt.Sprintf("Env variable %v", err))
+		}
+		log.Info().Msg(fmt.Sprintf("OpenTelemetry %v exported over %v", strings.ToLower(signal), exporterConfig))
+		return exporterConfig
+	}
+
+	otelResource, err := setupOtelResource(version, gitHash, ctx, &namespace, &instanceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	otelTraceExporter, err := setupOtelTraceExporter(ctx, exporterConfigFromEnv(otlpSignalTraces))
+	if err != nil {
+		return nil, err
+	}
+
+	meterProvider, err := setupOtelMeterProvider(ctx, exporterConfigFromEnv(otlpSignalMetrics), otelResource)
+	if err != nil {
+		return nil, err
+	}
+
+	sampler, err := samplerFromEnv()
+	if err != nil {
+		log.Fatal().Msg(fmt.Sprintf("Env variable %v", err))
+	}
+	log.Info().Msg(fmt.Sprintf("trace sampler: %v", sampler.Description()))
+	// the tail sampling processor counts its decisions, so it is set up after the MeterProvider
+	spanProcessor, err := tailSamplingFromEnv(sdktrace.NewBatchSpanProcessor(otelTraceExporter))
+	if err != nil {
+		log.Fatal().Msg(fmt.Sprintf("Env variable %v", err))
+	}
+	log.Info().Msg(fmt.Sprintf("trace span processor: %v", spanProcessorDescription(spanProcessor)))
+	traceProvider := setupOtelTraceProvider(spanProcessor, otelResource, sampler)
+
+	propagator, err := propagatorFromEnv()
+	if err != nil {
+		log.Fatal().Msg(fmt.Sprintf("Env variable %v", err))
+	}
+	log.Info().Msg(fmt.Sprintf("trace context propagated in headers: %v", propagator.Fields()))
+	otel.SetTextMapPropagator(propagator) // set global propagator, the default is no-op
+
+	switch logsExporter := os.Getenv("OTEL_LOGS_EXPORTER"); logsExporter {
+	case "", "none":
+	case "otlp":
+		if loggerProvider, err = setupOtelLoggerProvider(exporterConfigFromEnv(otlpSignalLogs), otelResource); err != nil {
+			return nil, err
+		}
+	default:
+		log.Fatal().Msg(fmt.Sprintf("Env variable OTEL_LOGS_EXPORTER=%v must be otlp or none", logsExporter))
+	}
+	//return shutdown signal so the application can trigger shutting itself down, flushing spans, metrics and logs
+	return func(ctx context.Context) error {
+		return errors.Join(traceProvider.Shutdown(ctx), meterProvider.Shutdown(ctx), loggerProvider.Shutdown(ctx))
+	}, nil
+}
+
+func setupOtelTraceProvider(spanProcessor sdktrace.SpanProcessor, otelResource *resource.Resource, sampler sdktrace.Sampler) *sdktrace.TracerProvider {
+	// Register the span processor with a TracerProvider, a batch span processor aggregating spans before export, tail sampled when configured.
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(otelResource),
+		sdktrace.WithSpanProcessor(spanProcessor),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	return tracerProvider
+}
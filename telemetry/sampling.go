@@ -0,0 +1,122 @@
+package telemetry
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultRouteSamplingRules keep health checks and scrapes out of the traces when OTEL_TRACES_SAMPLER_ROUTES is not set.
+const defaultRouteSamplingRules = "/status=0,/metrics=0"
+
+// samplerFromEnv builds the sampler set by OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG, as in the OpenTelemetry SDK environment variable spec,
+// wrapped in the per-route rules of OTEL_TRACES_SAMPLER_ROUTES.
+func samplerFromEnv() (sdktrace.Sampler, error) {
+	sampler, err := newSampler(os.Getenv("OTEL_TRACES_SAMPLER"), os.Getenv("OTEL_TRACES_SAMPLER_ARG"))
+	if err != nil {
+		return nil, err
+	}
+	routeRules, found := os.LookupEnv("OTEL_TRACES_SAMPLER_ROUTES")
+	if !found {
+		routeRules = defaultRouteSamplingRules
+	}
+	return NewRouteSampler(routeRules, sampler)
+}
+
+// newSampler is the sampler named, parentbased_always_on when no name is given. ratio is the fraction of traces sampled by the traceidratio samplers, 1 when empty.
+func newSampler(name string, ratio string) (sdktrace.Sampler, error) {
+	fraction := 1.0
+	if ratio != "" {
+		var err error
+		if fraction, err = parseSamplingRatio(ratio); err != nil {
+			return nil, fmt.Errorf("OTEL_TRACES_SAMPLER_ARG=%v %w", ratio, err)
+		}
+	}
+	switch name {
+	case "always_on":
+		return sdktrace.AlwaysSample(), nil
+	case "always_off":
+		return sdktrace.NeverSample(), nil
+	case "traceidratio":
+		return sdktrace.TraceIDRatioBased(fraction), nil
+	case "", "parentbased_always_on":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case "parentbased_always_off":
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case "parentbased_traceidratio":
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(fraction)), nil
+	default:
+		return nil, fmt.Errorf("OTEL_TRACES_SAMPLER=%v must be one of always_on, always_off, traceidratio, parentbased_always_on, parentbased_always_off, parentbased_traceidratio", name)
+	}
+}
+
+func parseSamplingRatio(ratio string) (float64, error) {
+	fraction, err := strconv.ParseFloat(strings.TrimSpace(ratio), 64)
+	if err != nil || fraction < 0 || fraction > 1 {
+		return 0, fmt.Errorf("is not a ratio between 0 and 1")
+	}
+	return fraction, nil
+}
+
+// routeSampler samples request spans of a route with the ratio of the route's rule, whatever the parent decided,
+// spans started inside the service, such as request stages, with their parent's decision, and every other span with fallback.
+// Routes are the Gin route templates otelgin puts in http.route, e.g. /albums/:id.
+type routeSampler struct {
+	routes      map[string]sdktrace.Sampler
+	fallback    sdktrace.Sampler
+	localParent sdktrace.Sampler
+}
+
+// NewRouteSampler parses rules as comma separated route=ratio pairs, fallback is returned as is when there are no rules.
+func NewRouteSampler(rules string, fallback sdktrace.Sampler) (sdktrace.Sampler, error) {
+	routes := map[string]sdktrace.Sampler{}
+	for _, rule := range strings.Split(rules, ",") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		route, ratio, found := strings.Cut(rule, "=")
+		if !found {
+			return nil, fmt.Errorf("OTEL_TRACES_SAMPLER_ROUTES rule %v must be route=ratio", rule)
+		}
+		fraction, err := parseSamplingRatio(ratio)
+		if err != nil {
+			return nil, fmt.Errorf("OTEL_TRACES_SAMPLER_ROUTES rule %v %w", rule, err)
+		}
+		routes[strings.TrimSpace(route)] = sdktrace.TraceIDRatioBased(fraction)
+	}
+	if len(routes) == 0 {
+		return fallback, nil
+	}
+	// a local child of a sampled request span is always sampled, so its stages are not dropped, or exported without their request, by fallback
+	return routeSampler{routes: routes, fallback: fallback, localParent: sdktrace.ParentBased(fallback)}, nil
+}
+
+func (sampler routeSampler) ShouldSample(parameters sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if parent := trace.SpanContextFromContext(parameters.ParentContext); parent.IsValid() && !parent.IsRemote() {
+		return sampler.localParent.ShouldSample(parameters)
+	}
+	for _, keyValue := range parameters.Attributes {
+		if keyValue.Key != semconv.HTTPRouteKey {
+			continue
+		}
+		if routeSampler, found := sampler.routes[keyValue.Value.AsString()]; found {
+			return routeSampler.ShouldSample(parameters)
+		}
+	}
+	return sampler.fallback.ShouldSample(parameters)
+}
+
+func (sampler routeSampler) Description() string {
+	rules := make([]string, 0, len(sampler.routes))
+	for route, routeSampler := range sampler.routes {
+		rules = append(rules, route+"="+routeSampler.Description())
+	}
+	sort.Strings(rules)
+	return fmt.Sprintf("RouteBased{%s;fallback:%s}", strings.Join(rules, ","), sampler.fallback.Description())
+}
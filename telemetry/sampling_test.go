@@ -0,0 +1,80 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_newSampler(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		ratio       string
+		description string
+	}{
+		{name: "", description: "ParentBased{root:AlwaysOnSampler,remoteParentSampled:AlwaysOnSampler,remoteParentNotSampled:AlwaysOffSampler,localParentSampled:AlwaysOnSampler,localParentNotSampled:AlwaysOffSampler}"},
+		{name: "always_on", description: "AlwaysOnSampler"},
+		{name: "always_off", description: "AlwaysOffSampler"},
+		{name: "traceidratio", ratio: "0.25", description: "TraceIDRatioBased{0.25}"},
+		{name: "traceidratio", description: "AlwaysOnSampler"},
+		{name: "parentbased_traceidratio", ratio: "0.1", description: "ParentBased{root:TraceIDRatioBased{0.1},remoteParentSampled:AlwaysOnSampler,remoteParentNotSampled:AlwaysOffSampler,localParentSampled:AlwaysOnSampler,localParentNotSampled:AlwaysOffSampler}"},
+	} {
+		sampler, err := newSampler(test.name, test.ratio)
+		assert.NoError(t, err)
+		assert.Equal(t, test.description, sampler.Description(), test.name)
+	}
+}
+
+func Test_newSampler_Invalid(t *testing.T) {
+	_, err := newSampler("sometimes", "")
+	assert.ErrorContains(t, err, "OTEL_TRACES_SAMPLER=sometimes must be one of")
+
+	_, err = newSampler("traceidratio", "2")
+	assert.EqualError(t, err, "OTEL_TRACES_SAMPLER_ARG=2 is not a ratio between 0 and 1")
+}
+
+func Test_newRouteSampler_Invalid(t *testing.T) {
+	_, err := NewRouteSampler("/status", sdktrace.AlwaysSample())
+	assert.EqualError(t, err, "OTEL_TRACES_SAMPLER_ROUTES rule /status must be route=ratio")
+
+	_, err = NewRouteSampler("/status=all", sdktrace.AlwaysSample())
+	assert.EqualError(t, err, "OTEL_TRACES_SAMPLER_ROUTES rule /status=all is not a ratio between 0 and 1")
+}
+
+func Test_samplerFromEnv_Default_Route_Rules(t *testing.T) {
+	sampler, err := samplerFromEnv()
+
+	assert.NoError(t, err)
+	assert.Contains(t, sampler.Description(), "RouteBased{/metrics=TraceIDRatioBased{0},/status=TraceIDRatioBased{0};fallback:ParentBased{root:AlwaysOnSampler")
+}
+
+func Test_samplerFromEnv_No_Route_Rules(t *testing.T) {
+	t.Setenv("OTEL_TRACES_SAMPLER", "always_on")
+	t.Setenv("OTEL_TRACES_SAMPLER_ROUTES", "")
+
+	sampler, err := samplerFromEnv()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "AlwaysOnSampler", sampler.Description())
+}
+
+func Test_routeSampler_Local_Children_Follow_Parent(t *testing.T) {
+	sampler, _ := NewRouteSampler("/albums/:id=1,/status=0", sdktrace.NeverSample())
+	spanRecorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler), sdktrace.WithSpanProcessor(spanRecorder)).Tracer(ServiceName)
+
+	for _, route := range []string{"/albums/:id", "/status"} {
+		ctx, requestSpan := tracer.Start(context.Background(), route, trace.WithAttributes(semconv.HTTPRouteKey.String(route)))
+		_, stageSpan := tracer.Start(ctx, "find album")
+		assert.Equal(t, requestSpan.SpanContext().IsSampled(), stageSpan.SpanContext().IsSampled(), route)
+		stageSpan.End()
+		requestSpan.End()
+	}
+
+	assert.Len(t, spanRecorder.Ended(), 2)
+}
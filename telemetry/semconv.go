@@ -0,0 +1,160 @@
+package telemetry
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+)
+
+// Stable HTTP semantic convention keys, newer than the semconv packages of the OpenTelemetry Go version used here.
+const (
+	httpSemconvSchemaURL                    = "https://opentelemetry.io/schemas/1.23.0"
+	httpRequestMethodKey      attribute.Key = "http.request.method"
+	httpResponseStatusCodeKey attribute.Key = "http.response.status_code"
+	urlPathKey                attribute.Key = "url.path"
+	urlQueryKey               attribute.Key = "url.query"
+	urlSchemeKey              attribute.Key = "url.scheme"
+)
+
+// AttributeConvention is which keys spans carry, the legacy keys prefixed with ServiceName, the stable HTTP semantic convention keys, or both while dashboards migrate.
+type AttributeConvention struct {
+	standard bool
+	legacy   bool
+}
+
+var (
+	LegacyConvention   = AttributeConvention{legacy: true}
+	StandardConvention = AttributeConvention{standard: true}
+	DualConvention     = AttributeConvention{standard: true, legacy: true}
+)
+
+// SpanConvention is the attribute convention of the request spans.
+var SpanConvention = LegacyConvention
+
+// AttributeConventionFromEnv reads OTEL_SEMCONV_STABILITY_OPT_IN as in the OpenTelemetry HTTP semantic convention migration,
+// http for the stable keys, http/dup for both, and the legacy keys otherwise.
+func AttributeConventionFromEnv() AttributeConvention {
+	convention := LegacyConvention
+	for _, optIn := range strings.Split(os.Getenv("OTEL_SEMCONV_STABILITY_OPT_IN"), ",") {
+		switch strings.TrimSpace(optIn) {
+		case "http/dup":
+			return DualConvention
+		case "http":
+			convention = StandardConvention
+		}
+	}
+	return convention
+}
+
+func (convention AttributeConvention) String() string {
+	switch convention {
+	case DualConvention:
+		return "http/dup"
+	case StandardConvention:
+		return "http"
+	default:
+		return ServiceName
+	}
+}
+
+// schemaURL is the schema of the stable HTTP semantic conventions when spans carry them, empty otherwise.
+// In OTLP the schema of span attributes is the one of their instrumentation scope, so it is given to tracers by TracerProvider.
+func (convention AttributeConvention) schemaURL() string {
+	if convention.standard {
+		return httpSemconvSchemaURL
+	}
+	return ""
+}
+
+// TracerProvider is the global TracerProvider, giving the tracers it creates the schema URL of SpanConvention.
+// Pass it to otelgin, whose request spans carry the keys of the convention. Those spans keep the semconv 1.17 keys
+// otelgin adds itself, e.g. http.method, which a backend translating by schema reads as 1.23.0 keys.
+// otelhttp client spans carry only 1.17 keys, so the proxy's client keeps the global TracerProvider.
+func TracerProvider() trace.TracerProvider {
+	return schemaTracerProvider{}
+}
+
+type schemaTracerProvider struct{}
+
+func (schemaTracerProvider) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	if schemaURL := SpanConvention.schemaURL(); schemaURL != "" {
+		options = append(options, trace.WithSchemaURL(schemaURL))
+	}
+	return otel.GetTracerProvider().Tracer(name, options...)
+}
+
+// Tracer is the tracer of ServiceName for the spans the services start themselves.
+func Tracer() trace.Tracer {
+	return TracerProvider().Tracer(ServiceName)
+}
+
+// ResponseCodeAttributes record the HTTP status code with the keys of the convention.
+func (convention AttributeConvention) ResponseCodeAttributes(statusCode int) []attribute.KeyValue {
+	var attributes []attribute.KeyValue
+	if convention.standard {
+		attributes = append(attributes, httpResponseStatusCodeKey.Int(statusCode))
+	}
+	if convention.legacy {
+		attributes = append(attributes, attribute.Key(ServiceName+".response.code").Int(statusCode))
+	}
+	return attributes
+}
+
+// SetResponseCode records the HTTP status code on span with the keys of SpanConvention.
+func SetResponseCode(span trace.Span, statusCode int) {
+	span.SetAttributes(SpanConvention.ResponseCodeAttributes(statusCode)...)
+}
+
+// SetGrpcStatusCode records the status code of a gRPC call, as rpc.grpc.status_code in the stable convention
+// and by name, e.g. NotFound, under the legacy response code key.
+func SetGrpcStatusCode(span trace.Span, code codes.Code) {
+	if SpanConvention.standard {
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	}
+	if SpanConvention.legacy {
+		span.SetAttributes(attribute.Key(ServiceName + ".response.code").String(code.String()))
+	}
+}
+
+// SetUpstreamResponseCode records the HTTP status code a service called while handling the request answered with, under the legacy key upstream.response.code.
+// The stable convention has no key for it on the server span, the client span otelhttp starts around the call carries it.
+func SetUpstreamResponseCode(span trace.Span, upstream string, statusCode int) {
+	if SpanConvention.legacy {
+		span.SetAttributes(attribute.Key(upstream + ".response.code").Int(statusCode))
+	}
+}
+
+// SetRequestParameters records the path parameters, which url.path carries in the stable convention.
+func SetRequestParameters(span trace.Span, parameters string) {
+	if SpanConvention.legacy {
+		span.SetAttributes(attribute.Key(ServiceName + ".request.parameters").String(parameters))
+	}
+}
+
+// HttpSemconv adds the stable HTTP semantic convention keys to the request span, it must come after otelgin.
+// The status code is the one written, so routes that do not set a response code have it too.
+func HttpSemconv() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !SpanConvention.standard {
+			c.Next()
+			return
+		}
+		scheme := "http"
+		if c.Request.TLS != nil {
+			scheme = "https"
+		}
+		span := trace.SpanFromContext(c.Request.Context())
+		span.SetAttributes(httpRequestMethodKey.String(c.Request.Method), urlPathKey.String(c.Request.URL.Path), urlSchemeKey.String(scheme))
+		if c.Request.URL.RawQuery != "" {
+			span.SetAttributes(urlQueryKey.String(c.Request.URL.RawQuery))
+		}
+		c.Next()
+		span.SetAttributes(httpResponseStatusCodeKey.Int(c.Writer.Status()))
+	}
+}
@@ -0,0 +1,100 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc/codes"
+)
+
+func Test_attributeConventionFromEnv(t *testing.T) {
+	for _, test := range []struct {
+		optIn      string
+		convention AttributeConvention
+	}{
+		{optIn: "", convention: LegacyConvention},
+		{optIn: "database", convention: LegacyConvention},
+		{optIn: "http", convention: StandardConvention},
+		{optIn: "database, http", convention: StandardConvention},
+		{optIn: "http/dup", convention: DualConvention},
+		{optIn: "http,http/dup", convention: DualConvention},
+	} {
+		t.Setenv("OTEL_SEMCONV_STABILITY_OPT_IN", test.optIn)
+		assert.Equal(t, test.convention, AttributeConventionFromEnv(), test.optIn)
+	}
+}
+
+func Test_setupOtelResource_SchemaURL(t *testing.T) {
+	namespace, instanceName := "test", "album-store-1"
+	defer func() { SpanConvention = LegacyConvention }()
+
+	for _, convention := range []AttributeConvention{LegacyConvention, DualConvention} {
+		SpanConvention = convention
+		res, err := setupOtelResource("v1", "abc", context.Background(), &namespace, &instanceName)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://opentelemetry.io/schemas/1.17.0", res.SchemaURL(), convention.String())
+	}
+}
+
+func Test_TracerProvider_SchemaURL(t *testing.T) {
+	tracerProvider := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(tracerProvider) })
+	defer func() { SpanConvention = LegacyConvention }()
+	spanRecorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+
+	for _, convention := range []AttributeConvention{LegacyConvention, StandardConvention, DualConvention} {
+		SpanConvention = convention
+		_, span := Tracer().Start(context.Background(), "/albums")
+		span.End()
+	}
+
+	finishedSpans := spanRecorder.Ended()
+	assert.Equal(t, "", finishedSpans[0].InstrumentationScope().SchemaURL)
+	assert.Equal(t, "https://opentelemetry.io/schemas/1.23.0", finishedSpans[1].InstrumentationScope().SchemaURL)
+	assert.Equal(t, "https://opentelemetry.io/schemas/1.23.0", finishedSpans[2].InstrumentationScope().SchemaURL)
+	assert.Equal(t, ServiceName, finishedSpans[2].InstrumentationScope().Name)
+}
+
+func Test_SetGrpcStatusCode(t *testing.T) {
+	defer func() { SpanConvention = LegacyConvention }()
+	for _, test := range []struct {
+		convention AttributeConvention
+		attributes []attribute.KeyValue
+	}{
+		{convention: LegacyConvention, attributes: []attribute.KeyValue{attribute.Key(ServiceName + ".response.code").String("NotFound")}},
+		{convention: StandardConvention, attributes: []attribute.KeyValue{attribute.Key("rpc.grpc.status_code").Int(5)}},
+		{convention: DualConvention, attributes: []attribute.KeyValue{attribute.Key("rpc.grpc.status_code").Int(5), attribute.Key(ServiceName + ".response.code").String("NotFound")}},
+	} {
+		SpanConvention = test.convention
+		spanRecorder := tracetest.NewSpanRecorder()
+		_, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)).Tracer(ServiceName).Start(context.Background(), "GetAlbum")
+		SetGrpcStatusCode(span, codes.NotFound)
+		span.End()
+		assert.Equal(t, test.attributes, spanRecorder.Ended()[0].Attributes(), test.convention.String())
+	}
+}
+
+func Test_SetUpstreamResponseCode(t *testing.T) {
+	defer func() { SpanConvention = LegacyConvention }()
+	for _, test := range []struct {
+		convention AttributeConvention
+		attributes []attribute.KeyValue
+	}{
+		{convention: LegacyConvention, attributes: []attribute.KeyValue{attribute.Key("album-store.response.code").Int(404)}},
+		{convention: StandardConvention},
+		{convention: DualConvention, attributes: []attribute.KeyValue{attribute.Key("album-store.response.code").Int(404)}},
+	} {
+		SpanConvention = test.convention
+		spanRecorder := tracetest.NewSpanRecorder()
+		_, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)).Tracer(ServiceName).Start(context.Background(), "/albums/:id")
+		SetUpstreamResponseCode(span, "album-store", 404)
+		span.End()
+		assert.Equal(t, test.attributes, spanRecorder.Ended()[0].Attributes(), test.convention.String())
+	}
+}
@@ -0,0 +1,26 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartStage starts a child span of the request span in ctx for one stage of handling the request: parsing, validation, storage or serialization.
+// The context returned carries the stage span, so spans started for the work done in the stage are its children.
+// The request span belongs to the otelgin middleware, which names it after the route and ends it once the response is written,
+// so handlers add their attributes to it but never rename or end it.
+func StartStage(ctx context.Context, name string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name)
+}
+
+// EndStage ends a stage span, with an error status when the stage failed.
+func EndStage(stage trace.Span, failed bool) {
+	if failed {
+		stage.SetStatus(codes.Error, "")
+	} else {
+		stage.SetStatus(codes.Ok, "")
+	}
+	stage.End()
+}
@@ -0,0 +1,273 @@
+package telemetry
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultTailSamplingLatency   = 500 * time.Millisecond
+	defaultTailSamplingWait      = 5 * time.Second
+	defaultTailSamplingMaxTraces = 10000
+)
+
+// tailSamplingFromEnv puts a tailSamplingProcessor in front of next when TAIL_SAMPLING_RATIO is set,
+// with the latency threshold of TAIL_SAMPLING_LATENCY and the time traces are buffered of TAIL_SAMPLING_WAIT. next is returned as is otherwise.
+func tailSamplingFromEnv(next sdktrace.SpanProcessor) (sdktrace.SpanProcessor, error) {
+	ratioEnv := os.Getenv("TAIL_SAMPLING_RATIO")
+	if ratioEnv == "" {
+		return next, nil
+	}
+	ratio, err := parseSamplingRatio(ratioEnv)
+	if err != nil {
+		return nil, fmt.Errorf("TAIL_SAMPLING_RATIO=%v %w", ratioEnv, err)
+	}
+	latency, err := durationFromEnv("TAIL_SAMPLING_LATENCY", defaultTailSamplingLatency)
+	if err != nil {
+		return nil, err
+	}
+	wait, err := durationFromEnv("TAIL_SAMPLING_WAIT", defaultTailSamplingWait)
+	if err != nil {
+		return nil, err
+	}
+	return newTailSamplingProcessor(next, ratio, latency, wait, defaultTailSamplingMaxTraces), nil
+}
+
+// spanProcessorDescription names the span processor for the startup log.
+func spanProcessorDescription(spanProcessor sdktrace.SpanProcessor) string {
+	if stringer, okay := spanProcessor.(fmt.Stringer); okay {
+		return stringer.String()
+	}
+	return "Batch"
+}
+
+func durationFromEnv(name string, defaultDuration time.Duration) (time.Duration, error) {
+	durationEnv := os.Getenv(name)
+	if durationEnv == "" {
+		return defaultDuration, nil
+	}
+	duration, err := time.ParseDuration(durationEnv)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("%v=%v is not a positive duration", name, durationEnv)
+	}
+	return duration, nil
+}
+
+// tailSamplingProcessor buffers the sampled spans of each trace in this service until all its spans have ended, then passes the trace to next
+// when a span has an error status, when the trace took longer than latency, or at ratio otherwise.
+// Traces still open after wait, or pushed out by more than maxTraces open traces, are decided with the spans ended so far.
+// Only spans sampled by the head sampler reach it, so keep head sampling at 1 and sample with ratio here.
+type tailSamplingProcessor struct {
+	next         sdktrace.SpanProcessor
+	ratioSampler sdktrace.Sampler
+	latency      time.Duration
+	wait         time.Duration
+	maxTraces    int
+	traces       map[trace.TraceID]*bufferedTrace
+	order        *list.List // trace IDs of the buffered traces, the oldest first
+	mutex        sync.Mutex
+	decisions    metric.Int64Counter
+	done         chan struct{}
+	stopped      chan struct{}
+	shutdownOnce sync.Once
+}
+
+type bufferedTrace struct {
+	spans     []sdktrace.ReadOnlySpan
+	openSpans int
+	started   time.Time
+	position  *list.Element
+}
+
+// newTailSamplingProcessor counts its decisions with an instrument from the global MeterProvider, which must be set up first.
+func newTailSamplingProcessor(next sdktrace.SpanProcessor, ratio float64, latency time.Duration, wait time.Duration, maxTraces int) *tailSamplingProcessor {
+	decisions, err := global.Meter(ServiceName).Int64Counter("tail_sampling.traces", metric.WithDescription("Traces kept or dropped by tail sampling."))
+	if err != nil {
+		otel.Handle(err)
+	}
+	processor := &tailSamplingProcessor{
+		next:         next,
+		ratioSampler: sdktrace.TraceIDRatioBased(ratio),
+		latency:      latency,
+		wait:         wait,
+		maxTraces:    maxTraces,
+		traces:       map[trace.TraceID]*bufferedTrace{},
+		order:        list.New(),
+		decisions:    decisions,
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+	go processor.run()
+	return processor
+}
+
+func (processor *tailSamplingProcessor) String() string {
+	return fmt.Sprintf("TailSampling{errors,latency>%v,%v;wait:%v}", processor.latency, processor.ratioSampler.Description(), processor.wait)
+}
+
+// run decides traces that have waited longer than wait, which are at the front of the buffer.
+func (processor *tailSamplingProcessor) run() {
+	defer close(processor.stopped)
+	ticker := time.NewTicker(processor.wait / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			processor.decideOldest(func(buffered *bufferedTrace) bool { return time.Since(buffered.started) >= processor.wait })
+		case <-processor.done:
+			return
+		}
+	}
+}
+
+func (processor *tailSamplingProcessor) OnStart(parent context.Context, span sdktrace.ReadWriteSpan) {
+	processor.next.OnStart(parent, span)
+	if !span.SpanContext().IsSampled() {
+		return
+	}
+	processor.mutex.Lock()
+	buffered, found := processor.traces[span.SpanContext().TraceID()]
+	if !found {
+		buffered = processor.addTrace(span.SpanContext().TraceID(), 0)
+	}
+	buffered.openSpans++
+	oldestTraceID, oldest := processor.removeOldestTrace()
+	processor.mutex.Unlock()
+	if oldest != nil {
+		processor.decideTrace(oldestTraceID, oldest)
+	}
+}
+
+// addTrace buffers a new trace behind the others, callers hold the mutex.
+func (processor *tailSamplingProcessor) addTrace(traceID trace.TraceID, openSpans int) *bufferedTrace {
+	buffered := &bufferedTrace{started: time.Now(), openSpans: openSpans, position: processor.order.PushBack(traceID)}
+	processor.traces[traceID] = buffered
+	return buffered
+}
+
+// removeTrace takes a trace out of the buffer, callers hold the mutex.
+func (processor *tailSamplingProcessor) removeTrace(traceID trace.TraceID, buffered *bufferedTrace) {
+	processor.order.Remove(buffered.position)
+	delete(processor.traces, traceID)
+}
+
+// removeOldestTrace takes the trace buffered longest out of the buffer when more than maxTraces are buffered, callers hold the mutex.
+func (processor *tailSamplingProcessor) removeOldestTrace() (trace.TraceID, *bufferedTrace) {
+	if len(processor.traces) <= processor.maxTraces {
+		return trace.TraceID{}, nil
+	}
+	oldestTraceID := processor.order.Front().Value.(trace.TraceID)
+	oldest := processor.traces[oldestTraceID]
+	processor.removeTrace(oldestTraceID, oldest)
+	return oldestTraceID, oldest
+}
+
+func (processor *tailSamplingProcessor) OnEnd(span sdktrace.ReadOnlySpan) {
+	if !span.SpanContext().IsSampled() {
+		return
+	}
+	traceID := span.SpanContext().TraceID()
+	processor.mutex.Lock()
+	buffered, found := processor.traces[traceID]
+	if !found {
+		// a span ending after its trace was decided, or started before the processor, is a trace of its own
+		buffered = processor.addTrace(traceID, 1)
+	}
+	buffered.spans = append(buffered.spans, span)
+	buffered.openSpans--
+	complete := buffered.openSpans <= 0
+	if complete {
+		processor.removeTrace(traceID, buffered)
+	}
+	processor.mutex.Unlock()
+	if complete {
+		processor.decideTrace(traceID, buffered)
+	}
+}
+
+// decideOldest takes buffered traces out of the buffer, oldest first, until one does not match, and decides them in that order.
+func (processor *tailSamplingProcessor) decideOldest(matching func(*bufferedTrace) bool) {
+	var decidedTraceIDs []trace.TraceID
+	var decided []*bufferedTrace
+	processor.mutex.Lock()
+	for position := processor.order.Front(); position != nil; position = processor.order.Front() {
+		traceID := position.Value.(trace.TraceID)
+		buffered := processor.traces[traceID]
+		if !matching(buffered) {
+			break
+		}
+		processor.removeTrace(traceID, buffered)
+		decidedTraceIDs, decided = append(decidedTraceIDs, traceID), append(decided, buffered)
+	}
+	processor.mutex.Unlock()
+	for index, buffered := range decided {
+		processor.decideTrace(decidedTraceIDs[index], buffered)
+	}
+}
+
+// decideTrace passes the spans of a kept trace to next and counts the decision.
+func (processor *tailSamplingProcessor) decideTrace(traceID trace.TraceID, buffered *bufferedTrace) {
+	reason, keep := processor.keep(traceID, buffered.spans)
+	decision := "dropped"
+	if keep {
+		decision = "kept"
+		for _, span := range buffered.spans {
+			processor.next.OnEnd(span)
+		}
+	}
+	processor.decisions.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.Key("tail_sampling.decision").String(decision),
+		attribute.Key("tail_sampling.reason").String(reason),
+	))
+}
+
+// keep is true with the reason when a span has an error status, when the spans took longer than latency from the first start to the last end, or by ratio.
+func (processor *tailSamplingProcessor) keep(traceID trace.TraceID, spans []sdktrace.ReadOnlySpan) (string, bool) {
+	var start, end time.Time
+	for _, span := range spans {
+		if span.Status().Code == codes.Error {
+			return "error", true
+		}
+		if start.IsZero() || span.StartTime().Before(start) {
+			start = span.StartTime()
+		}
+		if span.EndTime().After(end) {
+			end = span.EndTime()
+		}
+	}
+	if end.Sub(start) > processor.latency {
+		return "latency", true
+	}
+	if processor.ratioSampler.ShouldSample(sdktrace.SamplingParameters{TraceID: traceID}).Decision == sdktrace.RecordAndSample {
+		return "ratio", true
+	}
+	return "ratio", false
+}
+
+// ForceFlush decides every buffered trace before flushing next.
+func (processor *tailSamplingProcessor) ForceFlush(ctx context.Context) error {
+	processor.decideOldest(func(*bufferedTrace) bool { return true })
+	return processor.next.ForceFlush(ctx)
+}
+
+// Shutdown decides every buffered trace before shutting down next.
+func (processor *tailSamplingProcessor) Shutdown(ctx context.Context) error {
+	processor.shutdownOnce.Do(func() {
+		close(processor.done)
+		<-processor.stopped
+	})
+	processor.decideOldest(func(*bufferedTrace) bool { return true })
+	return processor.next.Shutdown(ctx)
+}
@@ -0,0 +1,206 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setupTailSampling is a tracer provider sampling every trace at the head, passing spans kept by tail sampling to the span recorder.
+func setupTailSampling(ratio float64, latency time.Duration, wait time.Duration) (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	processor := newTailSamplingProcessor(spanRecorder, ratio, latency, wait, 2)
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor)), spanRecorder
+}
+
+func Test_tailSampling_Keeps_Errors(t *testing.T) {
+	reader := setupTestMeter()
+	tracerProvider, spanRecorder := setupTailSampling(0, time.Hour, time.Hour)
+	tracer := tracerProvider.Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "/albums/:id GET")
+	_, child := tracer.Start(ctx, "album lookup")
+	child.SetStatus(codes.Error, "Album [1666] not found")
+	child.End()
+	assert.Empty(t, spanRecorder.Ended())
+	parent.End()
+	_, fine := tracer.Start(context.Background(), "/albums GET")
+	fine.End()
+
+	assert.Len(t, spanRecorder.Ended(), 2)
+	assert.Equal(t, "album lookup", spanRecorder.Ended()[0].Name())
+	assert.Equal(t, "/albums/:id GET", spanRecorder.Ended()[1].Name())
+	decisions := collectMetric(t, reader, "tail_sampling.traces").(metricdata.Sum[int64])
+	assert.Len(t, decisions.DataPoints, 2)
+	kept := attribute.NewSet(attribute.Key("tail_sampling.decision").String("kept"), attribute.Key("tail_sampling.reason").String("error"))
+	dropped := attribute.NewSet(attribute.Key("tail_sampling.decision").String("dropped"), attribute.Key("tail_sampling.reason").String("ratio"))
+	for _, dataPoint := range decisions.DataPoints {
+		assert.True(t, dataPoint.Attributes.Equals(&kept) || dataPoint.Attributes.Equals(&dropped), dataPoint.Attributes.Encoded(attribute.DefaultEncoder()))
+		assert.Equal(t, int64(1), dataPoint.Value)
+	}
+}
+
+func Test_tailSampling_Keeps_Slow_Traces(t *testing.T) {
+	setupTestMeter()
+	tracerProvider, spanRecorder := setupTailSampling(0, 10*time.Millisecond, time.Hour)
+	tracer := tracerProvider.Tracer("test")
+
+	start := time.Now()
+	_, slow := tracer.Start(context.Background(), "/orders POST", trace.WithTimestamp(start))
+	slow.End(trace.WithTimestamp(start.Add(20 * time.Millisecond)))
+	_, fast := tracer.Start(context.Background(), "/albums GET", trace.WithTimestamp(start))
+	fast.End(trace.WithTimestamp(start.Add(time.Millisecond)))
+
+	assert.Len(t, spanRecorder.Ended(), 1)
+	assert.Equal(t, "/orders POST", spanRecorder.Ended()[0].Name())
+}
+
+func Test_tailSampling_Ratio(t *testing.T) {
+	setupTestMeter()
+	tracerProvider, spanRecorder := setupTailSampling(1, time.Hour, time.Hour)
+
+	_, span := tracerProvider.Tracer("test").Start(context.Background(), "/albums GET")
+	span.End()
+
+	assert.Len(t, spanRecorder.Ended(), 1)
+}
+
+func Test_tailSampling_Decides_Open_Traces(t *testing.T) {
+	setupTestMeter()
+	tracerProvider, spanRecorder := setupTailSampling(0, time.Hour, 20*time.Millisecond)
+	tracer := tracerProvider.Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "/albums GET")
+	_, child := tracer.Start(ctx, "album lookup")
+	child.SetStatus(codes.Error, "failed")
+	child.End()
+
+	assert.Eventually(t, func() bool { return len(spanRecorder.Ended()) == 1 }, time.Second, 5*time.Millisecond)
+	parent.End()
+}
+
+func Test_tailSampling_Decides_Oldest_When_Full(t *testing.T) {
+	setupTestMeter()
+	tracerProvider, spanRecorder := setupTailSampling(0, time.Hour, time.Hour)
+	tracer := tracerProvider.Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "/albums GET")
+	defer parent.End()
+	_, child := tracer.Start(ctx, "album lookup")
+	child.SetStatus(codes.Error, "failed")
+	child.End()
+	_, second := tracer.Start(context.Background(), "second")
+	defer second.End()
+	assert.Empty(t, spanRecorder.Ended())
+
+	_, third := tracer.Start(context.Background(), "third")
+	defer third.End()
+
+	assert.Len(t, spanRecorder.Ended(), 1)
+	assert.Equal(t, "album lookup", spanRecorder.Ended()[0].Name())
+}
+
+func Test_tailSampling_Decides_Oldest_Open_Trace_When_Full(t *testing.T) {
+	setupTestMeter()
+	tracerProvider, spanRecorder := setupTailSampling(0, time.Hour, time.Hour)
+	tracer := tracerProvider.Tracer("test")
+	startFailing := func(name string) trace.Span {
+		ctx, parent := tracer.Start(context.Background(), name)
+		_, child := tracer.Start(ctx, name+" lookup")
+		child.SetStatus(codes.Error, "failed")
+		child.End()
+		return parent
+	}
+
+	first := startFailing("first")
+	_, second := tracer.Start(context.Background(), "second")
+	second.End()
+	third := startFailing("third")
+	defer third.End()
+	first.End()
+	assert.Len(t, spanRecorder.Ended(), 2)
+	_, fourth := tracer.Start(context.Background(), "fourth")
+	defer fourth.End()
+
+	_, fifth := tracer.Start(context.Background(), "fifth")
+	defer fifth.End()
+
+	assert.Len(t, spanRecorder.Ended(), 3)
+	assert.Equal(t, "third lookup", spanRecorder.Ended()[2].Name())
+}
+
+func Test_tailSampling_Shutdown_Decides_Buffered_Traces(t *testing.T) {
+	setupTestMeter()
+	tracerProvider, spanRecorder := setupTailSampling(1, time.Hour, time.Hour)
+	tracer := tracerProvider.Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "/albums GET")
+	_, child := tracer.Start(ctx, "album lookup")
+	child.End()
+	assert.NoError(t, tracerProvider.Shutdown(context.Background()))
+
+	assert.Len(t, spanRecorder.Ended(), 1)
+	parent.End()
+}
+
+func Test_tailSampling_Decides_Expired_Traces_Oldest_First(t *testing.T) {
+	setupTestMeter()
+	spanRecorder := tracetest.NewSpanRecorder()
+	processor := newTailSamplingProcessor(spanRecorder, 1, time.Hour, time.Hour, 10)
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor)).Tracer("test")
+	var parents []trace.Span
+	for _, name := range []string{"first", "second", "third"} {
+		ctx, parent := tracer.Start(context.Background(), name)
+		_, child := tracer.Start(ctx, name+" lookup")
+		child.End()
+		parents = append(parents, parent)
+	}
+	secondStarted := processor.traces[parents[1].SpanContext().TraceID()].started
+
+	processor.decideOldest(func(buffered *bufferedTrace) bool { return !buffered.started.After(secondStarted) })
+
+	assert.Equal(t, []string{"first lookup", "second lookup"}, spanNames(spanRecorder.Ended()))
+	assert.Len(t, processor.traces, 1)
+	assert.NoError(t, processor.ForceFlush(context.Background()))
+	assert.Equal(t, []string{"first lookup", "second lookup", "third lookup"}, spanNames(spanRecorder.Ended()))
+	for _, parent := range parents {
+		parent.End()
+	}
+}
+
+func Test_tailSamplingFromEnv(t *testing.T) {
+	setupTestMeter()
+	next := tracetest.NewSpanRecorder()
+
+	spanProcessor, err := tailSamplingFromEnv(next)
+	assert.NoError(t, err)
+	assert.Equal(t, next, spanProcessor)
+	assert.Equal(t, "Batch", spanProcessorDescription(spanProcessor))
+
+	t.Setenv("TAIL_SAMPLING_RATIO", "0.1")
+	t.Setenv("TAIL_SAMPLING_LATENCY", "250ms")
+	spanProcessor, err = tailSamplingFromEnv(next)
+	assert.NoError(t, err)
+	assert.Equal(t, "TailSampling{errors,latency>250ms,TraceIDRatioBased{0.1};wait:5s}", spanProcessorDescription(spanProcessor))
+	assert.NoError(t, spanProcessor.Shutdown(context.Background()))
+
+	t.Setenv("TAIL_SAMPLING_WAIT", "soon")
+	_, err = tailSamplingFromEnv(next)
+	assert.EqualError(t, err, "TAIL_SAMPLING_WAIT=soon is not a positive duration")
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, len(spans))
+	for index, span := range spans {
+		names[index] = span.Name()
+	}
+	return names
+}
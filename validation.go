@@ -0,0 +1,178 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// validationMessages are the field validation messages for every binding tag used by the models, by locale.
+// min and max have a message per kind of field so the limit reads naturally, {0} is the tag parameter.
+// unknown-field and duplicate-field are for request bodies rejected by strict decoding.
+var validationMessages = map[locales.Translator]map[string]string{
+	en.New(): {
+		"required":        "is required",
+		"min-string":      "must be at least {0} characters",
+		"min-number":      "must be {0} or greater",
+		"min-items":       "must contain at least {0} items",
+		"max-string":      "must be at most {0} characters",
+		"max-number":      "must be {0} or less",
+		"max-items":       "must contain at most {0} items",
+		"oneof":           "must be one of [{0}]",
+		"unknown":         "failed the {0} validation",
+		"unknown-field":   "is not a known field",
+		"duplicate-field": "is a duplicate key",
+	},
+	fr.New(): {
+		"required":        "est obligatoire",
+		"min-string":      "doit contenir au moins {0} caractères",
+		"min-number":      "doit être supérieur ou égal à {0}",
+		"min-items":       "doit contenir au moins {0} éléments",
+		"max-string":      "doit contenir au plus {0} caractères",
+		"max-number":      "doit être inférieur ou égal à {0}",
+		"max-items":       "doit contenir au plus {0} éléments",
+		"oneof":           "doit être l'une des valeurs [{0}]",
+		"unknown":         "a échoué la validation {0}",
+		"unknown-field":   "n'est pas un champ connu",
+		"duplicate-field": "est une clé en double",
+	},
+	de.New(): {
+		"required":        "ist erforderlich",
+		"min-string":      "muss mindestens {0} Zeichen lang sein",
+		"min-number":      "muss {0} oder größer sein",
+		"min-items":       "muss mindestens {0} Elemente enthalten",
+		"max-string":      "darf höchstens {0} Zeichen lang sein",
+		"max-number":      "muss {0} oder kleiner sein",
+		"max-items":       "darf höchstens {0} Elemente enthalten",
+		"oneof":           "muss einer von [{0}] sein",
+		"unknown":         "hat die Prüfung {0} nicht bestanden",
+		"unknown-field":   "ist kein bekanntes Feld",
+		"duplicate-field": "ist ein doppelter Schlüssel",
+	},
+	es.New(): {
+		"required":        "es obligatorio",
+		"min-string":      "debe tener al menos {0} caracteres",
+		"min-number":      "debe ser {0} o mayor",
+		"min-items":       "debe contener al menos {0} elementos",
+		"max-string":      "debe tener como máximo {0} caracteres",
+		"max-number":      "debe ser {0} o menor",
+		"max-items":       "debe contener como máximo {0} elementos",
+		"oneof":           "debe ser uno de [{0}]",
+		"unknown":         "no superó la validación {0}",
+		"unknown-field":   "no es un campo conocido",
+		"duplicate-field": "es una clave duplicada",
+	},
+}
+
+// validationTags are the validator tags with translations, anything else gets the "unknown" message.
+var validationTags = []string{"required", "min", "max", "oneof"}
+
+var validationTranslators = newValidationTranslators()
+
+// newValidationTranslators registers the messages with gin's validator, English is the fallback for unsupported languages.
+func newValidationTranslators() *ut.UniversalTranslator {
+	fallback := en.New()
+	var supported []locales.Translator
+	for locale := range validationMessages {
+		supported = append(supported, locale)
+	}
+	universalTranslator := ut.New(fallback, supported...)
+	validate := binding.Validator.Engine().(*validator.Validate)
+	for locale, messages := range validationMessages {
+		translator, _ := universalTranslator.GetTranslator(locale.Locale())
+		for key, message := range messages {
+			if err := translator.Add(key, message, false); err != nil {
+				panic(err)
+			}
+		}
+		for _, tag := range validationTags {
+			if err := validate.RegisterTranslation(tag, translator, noopRegisterTranslation, translateFieldError); err != nil {
+				panic(err)
+			}
+		}
+	}
+	return universalTranslator
+}
+
+// noopRegisterTranslation is used because the messages are already added to each translator.
+func noopRegisterTranslation(ut.Translator) error {
+	return nil
+}
+
+func translateFieldError(translator ut.Translator, fieldError validator.FieldError) string {
+	key := fieldError.Tag()
+	if key == "min" || key == "max" {
+		switch fieldError.Kind() {
+		case reflect.String:
+			key += "-string"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			key += "-items"
+		default:
+			key += "-number"
+		}
+	}
+	message, err := translator.T(key, fieldError.Param())
+	if err != nil {
+		message, _ = translator.T("unknown", fieldError.Tag())
+	}
+	return message
+}
+
+// translatorForLanguages picks the translator for an Accept-Language header, trying each language and then its base language
+// from the highest q-value down, so en-GB falls back to en. Languages with q=0 are refused. Unsupported languages get English.
+func translatorForLanguages(acceptLanguage string) ut.Translator {
+	type weightedLanguage struct {
+		language string
+		quality  float64
+	}
+	var languages []weightedLanguage
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		language := strings.ToLower(strings.TrimSpace(fields[0]))
+		if language == "" || language == "*" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = parsed
+				}
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		languages = append(languages, weightedLanguage{language: strings.ReplaceAll(language, "-", "_"), quality: quality})
+	}
+	sort.SliceStable(languages, func(i, j int) bool { return languages[i].quality > languages[j].quality })
+
+	var candidates []string
+	for _, weighted := range languages {
+		candidates = append(candidates, weighted.language, strings.SplitN(weighted.language, "_", 2)[0])
+	}
+	translator, _ := validationTranslators.FindTranslator(candidates...)
+	return translator
+}
+
+// validationMessage is the message for one failed validator tag in the translator's language.
+func validationMessage(fieldError validator.FieldError, translator ut.Translator) string {
+	for _, tag := range validationTags {
+		if fieldError.Tag() == tag {
+			return fieldError.Translate(translator)
+		}
+	}
+	message, _ := translator.T("unknown", fieldError.Tag())
+	return message
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/mcarr-and/go-gin-otelcollector/album-store/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func postInvalidAlbum(t *testing.T, acceptLanguage string) []*model.BindingErrorMsg {
+	resetAlbums()
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPost, "/albums", strings.NewReader(`{"id": 20000, "title": "T", "price": 66.60}`))
+	req.Header.Set("Accept-Language", acceptLanguage)
+	router.ServeHTTP(testRecorder, req)
+
+	var serverError model.ServerError
+	if err := json.Unmarshal(testRecorder.Body.Bytes(), &serverError); err != nil {
+		assert.Fail(t, "json unmarshal fail", "should be ServerError ", serverError)
+	}
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	return serverError.BindingErrors
+}
+
+func Test_postAlbum_ValidationMessages_Languages(t *testing.T) {
+	assert.Equal(t, []*model.BindingErrorMsg{
+		{Field: "id", Message: "must be 10000 or less"},
+		{Field: "title", Message: "must be at least 2 characters"},
+		{Field: "artist", Message: "is required"},
+	}, postInvalidAlbum(t, ""))
+
+	assert.Equal(t, []*model.BindingErrorMsg{
+		{Field: "id", Message: "doit être inférieur ou égal à 10000"},
+		{Field: "title", Message: "doit contenir au moins 2 caractères"},
+		{Field: "artist", Message: "est obligatoire"},
+	}, postInvalidAlbum(t, "fr-CA,fr;q=0.9,en;q=0.8"))
+
+	assert.Equal(t, "muss mindestens 2 Zeichen lang sein", postInvalidAlbum(t, "de-DE")[1].Message)
+	assert.Equal(t, "debe tener al menos 2 caracteres", postInvalidAlbum(t, "ja, es;q=0.5")[1].Message)
+	assert.Equal(t, "must be at least 2 characters", postInvalidAlbum(t, "ja")[1].Message)
+}
+
+func Test_patchOrder_ValidationMessage_OneOf(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPatch, "/orders/1", strings.NewReader(`{"status": "paid"}`))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":[{"field":"status","message":"must be one of [shipped cancelled]"}],"message":""}`, testRecorder.Body.String())
+}
+
+func Test_validationMessage_UnknownTag(t *testing.T) {
+	type contact struct {
+		Email string `json:"email" binding:"email"`
+	}
+	err := binding.Validator.ValidateStruct(&contact{Email: "not-an-email"})
+	fieldError := err.(validator.ValidationErrors)[0]
+
+	assert.Equal(t, "failed the email validation", validationMessage(fieldError, translatorForLanguages("")))
+	assert.Equal(t, "a échoué la validation email", validationMessage(fieldError, translatorForLanguages("fr")))
+}
+
+func Test_translateFieldError_Items(t *testing.T) {
+	type order struct {
+		Lines []string `json:"lines" binding:"min=1"`
+	}
+	err := binding.Validator.ValidateStruct(&order{Lines: []string{}})
+	fieldError := err.(validator.ValidationErrors)[0]
+
+	assert.Equal(t, "must contain at least 1 items", validationMessage(fieldError, translatorForLanguages("en-GB")))
+}
+
+func Test_translatorForLanguages_QValues(t *testing.T) {
+	assert.Equal(t, "fr", translatorForLanguages("en;q=0.1, fr;q=0.9").Locale())
+	assert.Equal(t, "de", translatorForLanguages("fr;q=0.5, de").Locale())
+	assert.Equal(t, "es", translatorForLanguages("es-MX;q=0.8, fr;q=0").Locale())
+	assert.Equal(t, "en", translatorForLanguages("fr;q=0").Locale())
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// apiVersion is a version of the album API. A deprecated version has a deprecation date, its sunset date and the path prefix of its successor.
+type apiVersion struct {
+	name        string
+	deprecation time.Time
+	sunset      time.Time
+	successor   string
+}
+
+const apiVersionKey = "album-store.api.version"
+
+// apiV2 is the current version, v1 is deprecated from defaultV1Deprecation and removed at defaultV1Sunset unless
+// API_V1_DEPRECATION and API_V1_SUNSET set other dates.
+var apiV2 = apiVersion{name: "v2"}
+
+var (
+	defaultV1Deprecation = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	defaultV1Sunset      = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// apiVersions are the versions served, unversioned paths are answered as defaultAPIVersion so existing clients keep today's album model.
+var (
+	apiVersions = []apiVersion{
+		{name: "v1", deprecation: defaultV1Deprecation, sunset: defaultV1Sunset, successor: "/v2"},
+		apiV2,
+	}
+	defaultAPIVersion = apiVersions[0]
+)
+
+// setDeprecationDates sets the deprecation and sunset dates of the named version from YYYY-MM-DD dates, an empty date keeps the current one.
+func setDeprecationDates(name string, deprecation string, sunset string) error {
+	for index := range apiVersions {
+		version := &apiVersions[index]
+		if version.name != name {
+			continue
+		}
+		var err error
+		if deprecation != "" {
+			if version.deprecation, err = time.Parse(time.DateOnly, deprecation); err != nil {
+				return fmt.Errorf("%s deprecation date %s is not YYYY-MM-DD", name, deprecation)
+			}
+		}
+		if sunset != "" {
+			if version.sunset, err = time.Parse(time.DateOnly, sunset); err != nil {
+				return fmt.Errorf("%s sunset date %s is not YYYY-MM-DD", name, sunset)
+			}
+		}
+		if version.sunset.Before(version.deprecation) {
+			return fmt.Errorf("%s sunset %s is before its deprecation %s", name, version.sunset.Format(time.DateOnly), version.deprecation.Format(time.DateOnly))
+		}
+		if defaultAPIVersion.name == name {
+			defaultAPIVersion = *version
+		}
+		return nil
+	}
+	return fmt.Errorf("API version %s is not served", name)
+}
+
+// apiVersioning picks the API version of the request from the /v1 or /v2 path prefix, or on unversioned paths from a version parameter
+// on the Accept media type, e.g. application/json; version=2. The version is recorded on the span and kept in the gin context.
+// It is only used on the album routes, the rest of the API is not versioned.
+func apiVersioning() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		span := trace.SpanFromContext(c.Request.Context())
+		version, requested, found := requestedAPIVersion(c)
+		if !found {
+			buildErrorResponse(c, span, problemVersionNotSupported, fmt.Sprintf("API version [%s] not supported, use one of %s", requested, strings.Join(apiVersionNames(), ", ")))
+			return
+		}
+		c.Set(apiVersionKey, version)
+		span.SetAttributes(attribute.Key("album-store.api.version").String(version.name))
+		c.Next()
+	}
+}
+
+func requestedAPIVersion(c *gin.Context) (apiVersion, string, bool) {
+	for _, version := range apiVersions {
+		if strings.HasPrefix(c.Request.URL.Path, "/"+version.name+"/") {
+			return version, version.name, true
+		}
+	}
+	for _, mediaRange := range strings.Split(c.GetHeader("Accept"), ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil || params["version"] == "" {
+			continue
+		}
+		requested := "v" + strings.TrimPrefix(params["version"], "v")
+		for _, version := range apiVersions {
+			if version.name == requested {
+				return version, requested, true
+			}
+		}
+		return apiVersion{}, params["version"], false
+	}
+	return defaultAPIVersion, defaultAPIVersion.name, true
+}
+
+func apiVersionNames() []string {
+	var names []string
+	for _, version := range apiVersions {
+		names = append(names, version.name)
+	}
+	return names
+}
+
+// requestAPIVersion is the version apiVersioning picked, the default on routes without it.
+func requestAPIVersion(c *gin.Context) apiVersion {
+	if version, found := c.Get(apiVersionKey); found {
+		return version.(apiVersion)
+	}
+	return defaultAPIVersion
+}
+
+// versioned serves a route with the handler of the request's API version. Responses in a deprecated version get
+// Deprecation (RFC 9745), Sunset (RFC 8594) and a Link to the same resource in the successor version.
+func versioned(v1Handler gin.HandlerFunc, v2Handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		version := requestAPIVersion(c)
+		if !version.deprecation.IsZero() {
+			c.Header("Deprecation", fmt.Sprintf("@%d", version.deprecation.Unix()))
+			c.Header("Sunset", version.sunset.Format(http.TimeFormat))
+			c.Header("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, version.successor, strings.TrimPrefix(c.Request.URL.Path, "/"+version.name)))
+		}
+		if version.name == apiV2.name {
+			v2Handler(c)
+			return
+		}
+		v1Handler(c)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func Test_getAlbumById_V2(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/v2/albums/2", nil))
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, `{"id":2,"title":"Jeru","artist":"Gerry Mulligan","priceCents":1799,"currency":"USD"}`, testRecorder.Body.String())
+	assert.Empty(t, testRecorder.Header().Get("Deprecation"))
+	assert.Empty(t, testRecorder.Header().Get("Sunset"))
+	finishedSpans := requestSpans(spanRecorder)
+	assert.Equal(t, "/v2/albums/:id", finishedSpans[0].Name())
+	attributeMap := makeKeyMap(finishedSpans[0].Attributes())
+	assert.Equal(t, "v2", attributeMap["album-store.api.version"].Emit())
+}
+
+func Test_getAlbumById_V2_NotFound(t *testing.T) {
+	resetAlbums()
+	testRecorder, _, router := setupTestRouter(t)
+
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/v2/albums/1666", nil))
+
+	assert.Equal(t, http.StatusNotFound, testRecorder.Code)
+	assert.Equal(t, `{"errors":null,"message":"Album [1666] not found"}`, testRecorder.Body.String())
+}
+
+func Test_getAlbums_V1_Deprecated(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/v1/albums/2", nil))
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, `{"id":2,"title":"Jeru","artist":"Gerry Mulligan","price":17.99}`, testRecorder.Body.String())
+	assert.Equal(t, "@1792368000", testRecorder.Header().Get("Deprecation"))
+	assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", testRecorder.Header().Get("Sunset"))
+	assert.Equal(t, `</v2/albums/2>; rel="successor-version"`, testRecorder.Header().Get("Link"))
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	assert.Equal(t, "v1", attributeMap["album-store.api.version"].Emit())
+}
+
+func Test_getAlbums_Unversioned_Defaults_To_V1(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/albums", nil))
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Contains(t, testRecorder.Body.String(), `"price":56.99`)
+	assert.Equal(t, `</v2/albums>; rel="successor-version"`, testRecorder.Header().Get("Link"))
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	assert.Equal(t, "v1", attributeMap["album-store.api.version"].Emit())
+}
+
+func Test_getAlbums_Accept_Version(t *testing.T) {
+	resetAlbums()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+	req.Header.Set("Accept", "application/json; version=2")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Contains(t, testRecorder.Body.String(), `"priceCents":5699,"currency":"USD"`)
+	assert.Empty(t, testRecorder.Header().Get("Deprecation"))
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	assert.Equal(t, "v2", attributeMap["album-store.api.version"].Emit())
+}
+
+func Test_getAlbums_Accept_Version_NotSupported(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums", nil)
+	req.Header.Set("Accept", "application/json; version=3")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusNotAcceptable, testRecorder.Code)
+	assert.Equal(t, `{"errors":null,"message":"API version [3] not supported, use one of v1, v2"}`, testRecorder.Body.String())
+}
+
+func Test_getAlbums_V2_JsonOnly(t *testing.T) {
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/albums", nil)
+	req.Header.Set("Accept", "application/xml")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusNotAcceptable, testRecorder.Code)
+	assert.Equal(t, `{"errors":null,"message":"Accept [application/xml] not supported, use application/json"}`, testRecorder.Body.String())
+}
+
+func Test_postAlbum_V2(t *testing.T) {
+	resetAlbums()
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPost, "/v2/albums", strings.NewReader(`{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","priceCents":6660,"currency":"USD"}`))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusCreated, testRecorder.Code)
+	assert.Equal(t, `{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","priceCents":6660,"currency":"USD"}`, testRecorder.Body.String())
+	album, _ := albumByID(10)
+	assert.Equal(t, 66.6, album.Price)
+}
+
+func Test_postAlbum_V2_Validation(t *testing.T) {
+	resetAlbums()
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := newJsonRequest(http.MethodPost, "/v2/albums", strings.NewReader(`{"id":10,"title":"The Ozzman Cometh","artist":"Black Sabbath","priceCents":6660,"currency":"EUR"}`))
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, testRecorder.Code)
+	assert.Equal(t, `{"errors":[{"field":"currency","message":"must be one of [USD]"}],"message":""}`, testRecorder.Body.String())
+	assert.Len(t, listAlbums(), 3)
+}
+
+func Test_openAPIValidation_StrictResponse_Accept_Version(t *testing.T) {
+	strictResponseValidation = true
+	defer func() { strictResponseValidation = false }()
+	resetAlbums()
+	reader := setupTestMeter()
+	testRecorder, _, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/1", nil)
+	req.Header.Set("Accept", "application/json; version=2")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusOK, testRecorder.Code)
+	assert.Equal(t, int64(0), responseViolations(t, reader))
+}
+
+func Test_apiVersioning_Only_Album_Routes(t *testing.T) {
+	resetCarts()
+	testRecorder, spanRecorder, router := setupTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/carts", nil)
+	req.Header.Set("Accept", "application/json; version=9")
+	router.ServeHTTP(testRecorder, req)
+
+	assert.Equal(t, http.StatusCreated, testRecorder.Code)
+	assert.Empty(t, testRecorder.Header().Get("Deprecation"))
+	attributeMap := makeKeyMap(requestSpans(spanRecorder)[0].Attributes())
+	assert.NotContains(t, attributeMap, attribute.Key("album-store.api.version"))
+}
+
+func Test_setDeprecationDates(t *testing.T) {
+	versions, defaultVersion := append([]apiVersion{}, apiVersions...), defaultAPIVersion
+	t.Cleanup(func() { apiVersions, defaultAPIVersion = versions, defaultVersion })
+
+	assert.NoError(t, setDeprecationDates("v1", "2027-01-01", "2027-12-31"))
+	resetAlbums()
+	testRecorder, _, router := setupTestRouter(t)
+	router.ServeHTTP(testRecorder, httptest.NewRequest(http.MethodGet, "/albums/2", nil))
+
+	assert.Equal(t, "@1798761600", testRecorder.Header().Get("Deprecation"))
+	assert.Equal(t, "Fri, 31 Dec 2027 00:00:00 GMT", testRecorder.Header().Get("Sunset"))
+
+	assert.EqualError(t, setDeprecationDates("v1", "01/01/2027", ""), "v1 deprecation date 01/01/2027 is not YYYY-MM-DD")
+	assert.EqualError(t, setDeprecationDates("v1", "2028-01-01", "2027-12-31"), "v1 sunset 2027-12-31 is before its deprecation 2028-01-01")
+	assert.EqualError(t, setDeprecationDates("v3", "", ""), "API version v3 is not served")
+}